@@ -0,0 +1,412 @@
+package assets
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// TemplatesFS 包含所有 SVG 模板的文件系统
+// 每个模板由同名的 .svg 源文件和 .json 参数声明组成
+//
+//go:embed templates/*.svg templates/*.json
+var TemplatesFS embed.FS
+
+// TemplatesDir 是模板目录的路径
+const TemplatesDir = "templates"
+
+// 内置模板名称
+const (
+	TemplateProject    = "project"    // 项目组：剪贴板与任务勾选
+	TemplateDiscussion = "discussion" // 讨论组：对话气泡
+	TemplateInterest   = "interest"   // 兴趣小组：爱心与星光
+)
+
+// ErrTemplateNotFound 表示模板不存在，可用 errors.Is 判断
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrInvalidTemplateParam 表示模板参数无效，可用 errors.Is 判断
+var ErrInvalidTemplateParam = errors.New("invalid template param")
+
+// TemplateParamType 是模板参数的类型
+type TemplateParamType string
+
+// 支持的模板参数类型
+const (
+	ParamColor  TemplateParamType = "color"  // 十六进制颜色，替换为 #RRGGBB，不支持透明度
+	ParamNumber TemplateParamType = "number" // 数值，可声明 min 和 max
+	ParamText   TemplateParamType = "text"   // 文本，替换前做 XML 转义，可声明 max_length
+	ParamToggle TemplateParamType = "toggle" // 开关，关闭时移除 element 指定的元素
+)
+
+// TemplateParam 声明一个模板参数
+// 源文件中的 {{name}} 占位符替换为参数值；
+// 设置了 Element 的开关参数关闭时、文本参数为空时，移除 id 为 Element 的元素及其子元素
+type TemplateParam struct {
+	Name        string            `json:"name"`
+	Type        TemplateParamType `json:"type"`
+	Default     any               `json:"default"`
+	Description string            `json:"description,omitempty"`
+	Min         *float64          `json:"min,omitempty"`        // number: 最小值
+	Max         *float64          `json:"max,omitempty"`        // number: 最大值
+	MaxLength   int               `json:"max_length,omitempty"` // text: 最大字符数，0 表示不限制
+	Element     string            `json:"element,omitempty"`    // toggle、text: 受控元素的 id
+}
+
+// TemplateParams 是渲染模板时传入的参数值
+// 值可以是对应类型的 Go 值（string、float64、int、bool），也可以是字符串形式，
+// 便于直接使用查询参数或 JSON 中的值；未传入的参数使用默认值
+type TemplateParams map[string]any
+
+// Template 是一个参数化的 SVG 模板
+type Template struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Params      []TemplateParam `json:"params"`
+
+	source []byte
+}
+
+// templateManifest 是 .json 参数声明文件的结构
+type templateManifest struct {
+	Description string          `json:"description"`
+	Params      []TemplateParam `json:"params"`
+}
+
+// placeholderRe 匹配 {{name}} 占位符
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// loadTemplates 加载并校验所有内嵌模板，只执行一次
+var loadTemplates = sync.OnceValues(func() (map[string]*Template, error) {
+	entries, err := fs.ReadDir(TemplatesFS, TemplatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("列出模板失败: %w", err)
+	}
+
+	templates := make(map[string]*Template)
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".svg")
+		if !ok {
+			continue
+		}
+		t, err := loadTemplate(name)
+		if err != nil {
+			return nil, fmt.Errorf("加载模板 %s 失败: %w", name, err)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+})
+
+// loadTemplate 读取模板源文件和参数声明，并用默认参数试渲染一次
+func loadTemplate(name string) (*Template, error) {
+	source, err := TemplatesFS.ReadFile(path.Join(TemplatesDir, name+".svg"))
+	if err != nil {
+		return nil, err
+	}
+	manifestData, err := TemplatesFS.ReadFile(path.Join(TemplatesDir, name+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest templateManifest
+	dec := json.NewDecoder(bytes.NewReader(manifestData))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("参数声明无效: %w", err)
+	}
+
+	t := &Template{Name: name, Description: manifest.Description, Params: manifest.Params, source: source}
+	seen := make(map[string]bool)
+	for _, p := range t.Params {
+		if p.Name == "" || seen[p.Name] {
+			return nil, fmt.Errorf("参数名称 %q 为空或重复", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Type == ParamToggle && p.Element == "" {
+			return nil, fmt.Errorf("开关参数 %s 未声明 element", p.Name)
+		}
+	}
+	for _, m := range placeholderRe.FindAllSubmatch(source, -1) {
+		if !seen[string(m[1])] {
+			return nil, fmt.Errorf("占位符 %s 未声明", m[1])
+		}
+	}
+
+	// 默认值必须有效，受控元素必须存在
+	if _, err = t.Render(nil); err != nil {
+		return nil, fmt.Errorf("默认参数无效: %w", err)
+	}
+	ids := elementIDs(source)
+	for _, p := range t.Params {
+		if p.Element != "" && !ids[p.Element] {
+			return nil, fmt.Errorf("参数 %s 的元素 %q 不存在", p.Name, p.Element)
+		}
+	}
+	return t, nil
+}
+
+// ListTemplates 返回所有内嵌模板的名称，按名称排序
+func ListTemplates() ([]string, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetTemplate 返回指定名称的模板，返回值由所有调用方共享，不要修改
+func GetTemplate(name string) (*Template, error) {
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := templates[strings.TrimSuffix(name, ".svg")]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+// RenderTemplate 使用参数渲染指定模板，返回的 SVG 数据可以直接交给 SVGRenderer
+func RenderTemplate(name string, params TemplateParams) ([]byte, error) {
+	t, err := GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Render(params)
+}
+
+// Defaults 返回所有参数的默认值
+func (t *Template) Defaults() TemplateParams {
+	out := make(TemplateParams, len(t.Params))
+	for _, p := range t.Params {
+		out[p.Name] = p.Default
+	}
+	return out
+}
+
+// Render 校验参数并渲染模板，未知参数和无效值返回 ErrInvalidTemplateParam
+func (t *Template) Render(params TemplateParams) ([]byte, error) {
+	declared := make(map[string]*TemplateParam, len(t.Params))
+	for i := range t.Params {
+		declared[t.Params[i].Name] = &t.Params[i]
+	}
+	for name := range params {
+		if declared[name] == nil {
+			return nil, fmt.Errorf("%w: template %s has no param %q", ErrInvalidTemplateParam, t.Name, name)
+		}
+	}
+
+	values := make(map[string]string, len(t.Params))
+	remove := make(map[string]bool)
+	for _, p := range t.Params {
+		v, ok := params[p.Name]
+		if !ok || v == nil {
+			v = p.Default
+		}
+		value, removed, err := p.resolve(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: template %s param %s: %v", ErrInvalidTemplateParam, t.Name, p.Name, err)
+		}
+		values[p.Name] = value
+		if removed {
+			remove[p.Element] = true
+		}
+	}
+
+	out := placeholderRe.ReplaceAllFunc(t.source, func(m []byte) []byte {
+		return []byte(values[string(placeholderRe.FindSubmatch(m)[1])])
+	})
+	if len(remove) > 0 {
+		return removeElements(out, remove)
+	}
+	return out, nil
+}
+
+// resolve 校验参数值并返回替换文本，removed 表示受控元素需要移除
+func (p *TemplateParam) resolve(v any) (value string, removed bool, err error) {
+	switch p.Type {
+	case ParamColor:
+		s, ok := v.(string)
+		if !ok {
+			return "", false, fmt.Errorf("expected a color string, got %T", v)
+		}
+		c, err := utils.ParseHexColor(s)
+		if err != nil {
+			return "", false, err
+		}
+		if c.A != 255 {
+			return "", false, fmt.Errorf("color %q must be opaque", s)
+		}
+		return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B), false, nil
+
+	case ParamNumber:
+		n, err := paramNumber(v)
+		if err != nil {
+			return "", false, err
+		}
+		if p.Min != nil && n < *p.Min {
+			return "", false, fmt.Errorf("%v is less than %v", n, *p.Min)
+		}
+		if p.Max != nil && n > *p.Max {
+			return "", false, fmt.Errorf("%v is greater than %v", n, *p.Max)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), false, nil
+
+	case ParamText:
+		s, ok := v.(string)
+		if !ok {
+			return "", false, fmt.Errorf("expected a string, got %T", v)
+		}
+		if !utf8.ValidString(s) || strings.IndexFunc(s, unicode.IsControl) >= 0 {
+			return "", false, fmt.Errorf("text contains invalid characters")
+		}
+		if p.MaxLength > 0 && utf8.RuneCountInString(s) > p.MaxLength {
+			return "", false, fmt.Errorf("text is longer than %d characters", p.MaxLength)
+		}
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String(), s == "" && p.Element != "", nil
+
+	case ParamToggle:
+		on, err := paramBool(v)
+		if err != nil {
+			return "", false, err
+		}
+		return strconv.FormatBool(on), !on, nil
+
+	default:
+		return "", false, fmt.Errorf("unknown param type %q", p.Type)
+	}
+}
+
+// paramNumber 将数值或数值字符串转换为 float64
+func paramNumber(v any) (float64, error) {
+	var n float64
+	switch x := v.(type) {
+	case float64:
+		n = x
+	case float32:
+		n = float64(x)
+	case int:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case json.Number:
+		var err error
+		if n, err = x.Float64(); err != nil {
+			return 0, err
+		}
+	case string:
+		var err error
+		if n, err = strconv.ParseFloat(strings.TrimSpace(x), 64); err != nil {
+			return 0, fmt.Errorf("%q is not a number", x)
+		}
+	default:
+		return 0, fmt.Errorf("expected a number, got %T", v)
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%v is not a finite number", n)
+	}
+	return n, nil
+}
+
+// paramBool 将布尔值或布尔字符串转换为 bool
+func paramBool(v any) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(x))
+		if err != nil {
+			return false, fmt.Errorf("%q is not a boolean", x)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("expected a boolean, got %T", v)
+	}
+}
+
+// elementIDs 返回 SVG 中所有元素的 id
+func elementIDs(data []byte) map[string]bool {
+	ids := make(map[string]bool)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return ids
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			for _, attr := range se.Attr {
+				if attr.Name.Local == "id" {
+					ids[attr.Value] = true
+				}
+			}
+		}
+	}
+}
+
+// removeElements 移除 id 在 ids 中的元素及其子元素，其余内容按字节保留
+func removeElements(data []byte, ids map[string]bool) ([]byte, error) {
+	var out bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	copied := int64(0) // data 中已处理到的位置
+	skipFrom := int64(-1)
+	depth := 0
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template SVG: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+				continue
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "id" && ids[attr.Value] {
+					skipFrom, depth = start, 1
+					break
+				}
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth == 0 {
+				out.Write(data[copied:skipFrom])
+				copied = dec.InputOffset()
+			}
+		}
+	}
+	out.Write(data[copied:])
+	return out.Bytes(), nil
+}
@@ -0,0 +1,299 @@
+package background
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// rowBand is the number of rows generated between two ctx checks
+const rowBand = 32
+
+// lutSize is the number of precomputed gradient colors
+const lutSize = 1024
+
+// supersample is the per-axis sample count used to anti-alias pattern edges
+const supersample = 4
+
+// Generate renders a spec into a new image of the spec's size
+func Generate(s Spec) (*image.RGBA, error) {
+	return GenerateCtx(context.Background(), s)
+}
+
+// GenerateCtx renders a spec, checking ctx between row bands
+func GenerateCtx(ctx context.Context, s Spec) (*image.RGBA, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, s.Width, s.Height))
+	shade, err := s.shader()
+	if err != nil {
+		return nil, err
+	}
+
+	for y := 0; y < s.Height; y++ {
+		if y%rowBand == 0 {
+			if err = ctx.Err(); err != nil {
+				return nil, fmt.Errorf("background generation canceled at row %d of %d: %w", y, s.Height, err)
+			}
+		}
+		for x := 0; x < s.Width; x++ {
+			c := shade(float64(x)+0.5, float64(y)+0.5)
+			i := img.PixOffset(x, y)
+			img.Pix[i+0] = c.R
+			img.Pix[i+1] = c.G
+			img.Pix[i+2] = c.B
+			img.Pix[i+3] = c.A
+		}
+	}
+	return img, nil
+}
+
+// shader returns the color of the pixel centered at (x, y)
+type shader func(x, y float64) color.RGBA
+
+// shader builds the shading function of the spec's kind
+func (s Spec) shader() (shader, error) {
+	w, h := float64(s.Width), float64(s.Height)
+	cx, cy := s.center()
+
+	switch s.Kind {
+	case KindSolid:
+		c := toRGBA(s.Color)
+		return func(_, _ float64) color.RGBA { return c }, nil
+
+	case KindLinear:
+		lut := newGradientLUT(s.Stops)
+		dx, dy := direction(s.Angle)
+		// The gradient spans the projection of the whole image onto the direction
+		half := (math.Abs(w*dx) + math.Abs(h*dy)) / 2
+		return func(x, y float64) color.RGBA {
+			return lut.at(((x-w/2)*dx+(y-h/2)*dy)/(2*half) + 0.5)
+		}, nil
+
+	case KindRadial:
+		lut := newGradientLUT(s.Stops)
+		radius := s.Radius
+		if radius == 0 {
+			radius = 1
+		}
+		farthest := math.Hypot(math.Max(cx, w-cx), math.Max(cy, h-cy)) * radius
+		return func(x, y float64) color.RGBA {
+			return lut.at(math.Hypot(x-cx, y-cy) / farthest)
+		}, nil
+
+	case KindConic:
+		lut := newGradientLUT(s.Stops)
+		start := s.Angle * math.Pi / 180
+		return func(x, y float64) color.RGBA {
+			t := (math.Atan2(y-cy, x-cx) - start) / (2 * math.Pi)
+			return lut.at(t - math.Floor(t))
+		}, nil
+
+	case KindStripes:
+		cell := s.cellSize()
+		dx, dy := direction(s.Angle)
+		return s.pattern(func(x, y float64) bool {
+			// Stripes run perpendicular to the direction
+			pos := (x*dx + y*dy) / cell
+			return int(math.Floor(pos))%2 != 0
+		}), nil
+
+	case KindDots:
+		cell := s.cellSize()
+		radius := s.Radius
+		if radius == 0 {
+			radius = 0.25
+		}
+		r := radius * cell
+		return s.pattern(func(x, y float64) bool {
+			fx := x - (math.Floor(x/cell)+0.5)*cell
+			fy := y - (math.Floor(y/cell)+0.5)*cell
+			return fx*fx+fy*fy <= r*r
+		}), nil
+
+	case KindCheckerboard:
+		cell := s.cellSize()
+		return s.pattern(func(x, y float64) bool {
+			return (int(math.Floor(x/cell))+int(math.Floor(y/cell)))%2 != 0
+		}), nil
+
+	case KindNoise:
+		return s.noise(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown background kind: %q", s.Kind)
+	}
+}
+
+// pattern returns a shader that blends Color and Foreground by the supersampled
+// coverage of inside, giving anti-aliased pattern edges
+func (s Spec) pattern(inside func(x, y float64) bool) shader {
+	bg, fg := s.patternColors()
+	return func(x, y float64) color.RGBA {
+		hits := 0
+		for j := 0; j < supersample; j++ {
+			for i := 0; i < supersample; i++ {
+				sx := x - 0.5 + (float64(i)+0.5)/supersample
+				sy := y - 0.5 + (float64(j)+0.5)/supersample
+				if inside(sx, sy) {
+					hits++
+				}
+			}
+		}
+		return lerp(bg, fg, float64(hits)/(supersample*supersample))
+	}
+}
+
+// noise returns a shader of cells split along a random diagonal into two
+// triangles, each filled with a random color from the palette
+func (s Spec) noise() shader {
+	cell := s.cellSize()
+	cols, cells := s.noiseCells()
+
+	return func(x, y float64) color.RGBA {
+		col, row := int(x/cell), int(y/cell)
+		c := cells[row*cols+col]
+		fx, fy := x/cell-float64(col), y/cell-float64(row)
+		upper := fy < fx
+		if c.flip {
+			upper = fx+fy < 1
+		}
+		if upper {
+			return c.upper
+		}
+		return c.lower
+	}
+}
+
+// noiseCell is a cell of a noise pattern. Without flip the diagonal runs from
+// the top-left to the bottom-right corner and upper is the top-right triangle,
+// with flip it runs from the top-right to the bottom-left corner and upper is
+// the top-left triangle
+type noiseCell struct {
+	flip         bool
+	upper, lower color.RGBA
+}
+
+// noiseCells returns the column count and the cells of a noise pattern in
+// row-major order, covering the image with one spare row and column
+func (s Spec) noiseCells() (int, []noiseCell) {
+	cell := s.cellSize()
+	cols := int(math.Ceil(float64(s.Width)/cell)) + 1
+	rows := int(math.Ceil(float64(s.Height)/cell)) + 1
+
+	var palette func(t float64) color.RGBA
+	if len(s.Stops) > 0 {
+		palette = newGradientLUT(s.Stops).at
+	} else {
+		bg, fg := s.patternColors()
+		palette = func(t float64) color.RGBA { return lerp(bg, fg, t) }
+	}
+
+	// Each cell gets a diagonal and two triangle colors, drawn in a fixed order
+	// so that the same seed always produces the same image
+	rng := rand.New(rand.NewSource(s.Seed))
+	cells := make([]noiseCell, cols*rows)
+	for i := range cells {
+		cells[i] = noiseCell{
+			flip:  rng.Intn(2) == 1,
+			upper: palette(rng.Float64()),
+			lower: palette(rng.Float64()),
+		}
+	}
+	return cols, cells
+}
+
+// center returns the gradient center in pixels
+func (s Spec) center() (float64, float64) {
+	p := Point{X: 0.5, Y: 0.5}
+	if s.Center != nil {
+		p = *s.Center
+	}
+	return p.X * float64(s.Width), p.Y * float64(s.Height)
+}
+
+// cellSize returns the pattern period in pixels
+func (s Spec) cellSize() float64 {
+	if s.CellSize > 0 {
+		return s.CellSize
+	}
+	return math.Max(float64(max(s.Width, s.Height))/8, 1)
+}
+
+// patternColors returns the base and foreground colors of patterns
+func (s Spec) patternColors() (bg, fg color.RGBA) {
+	if s.Color != nil {
+		bg = toRGBA(s.Color)
+	}
+	fg = color.RGBA{A: 255}
+	if s.Foreground != nil {
+		fg = toRGBA(s.Foreground)
+	}
+	return bg, fg
+}
+
+// gradientLUT holds precomputed colors along a gradient
+type gradientLUT [lutSize]color.RGBA
+
+// newGradientLUT interpolates the stops in premultiplied space, which avoids
+// dark fringes when fading to transparent
+func newGradientLUT(stops []Stop) *gradientLUT {
+	sorted := append([]Stop(nil), stops...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	lut := new(gradientLUT)
+	k := 0
+	for i := range lut {
+		t := float64(i) / (lutSize - 1)
+		for k < len(sorted)-1 && sorted[k+1].Offset < t {
+			k++
+		}
+
+		first, last := sorted[0], sorted[len(sorted)-1]
+		switch {
+		case t <= first.Offset:
+			lut[i] = toRGBA(first.Color)
+		case t >= last.Offset:
+			lut[i] = toRGBA(last.Color)
+		default:
+			a, b := sorted[k], sorted[k+1]
+			f := 0.0
+			if span := b.Offset - a.Offset; span > 0 {
+				f = (t - a.Offset) / span
+			}
+			lut[i] = lerp(toRGBA(a.Color), toRGBA(b.Color), f)
+		}
+	}
+	return lut
+}
+
+// at returns the gradient color at t, clamped to [0, 1]
+func (l *gradientLUT) at(t float64) color.RGBA {
+	i := int(math.Round(t * (lutSize - 1)))
+	return l[min(max(i, 0), lutSize-1)]
+}
+
+// direction returns the unit vector of an angle in degrees
+func direction(angle float64) (float64, float64) {
+	rad := angle * math.Pi / 180
+	return math.Cos(rad), math.Sin(rad)
+}
+
+// toRGBA converts any color to premultiplied RGBA
+func toRGBA(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+// lerp interpolates two premultiplied colors
+func lerp(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
@@ -0,0 +1,244 @@
+// Package background generates procedural icon backgrounds from a spec
+//
+// A Spec describes a solid color, a gradient or a pattern together with the
+// output size. Specs serialize to compact bytes (see Marshal) that can be
+// passed anywhere a background image is accepted, so icons no longer need a
+// stored JPEG per style.
+package background
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// Kind identifies the kind of generated background
+type Kind string
+
+// Background kinds
+const (
+	KindSolid        Kind = "solid"        // Color everywhere
+	KindLinear       Kind = "linear"       // Stops along Angle
+	KindRadial       Kind = "radial"       // Stops from Center outwards
+	KindConic        Kind = "conic"        // Stops around Center, starting at Angle
+	KindStripes      Kind = "stripes"      // Alternating Color and Foreground bands
+	KindDots         Kind = "dots"         // Foreground dots on a Color grid
+	KindCheckerboard Kind = "checkerboard" // Alternating Color and Foreground cells
+	KindNoise        Kind = "noise"        // Seeded random triangles
+)
+
+// magic prefixes serialized specs so they can be told apart from image data
+var magic = []byte("ICMKBG:")
+
+// Stop is a color stop of a gradient, Offset ranges from 0 to 1
+type Stop struct {
+	Offset float64
+	Color  color.Color
+}
+
+// Point is a position normalized to the image size, (0.5, 0.5) is the center
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Spec describes a generated background
+//
+// Fields that do not apply to Kind are ignored. Angles are in degrees with
+// 0 pointing right and 90 pointing down.
+type Spec struct {
+	Kind          Kind
+	Width, Height int // Size in pixels, at most MaxSize
+
+	Color      color.Color // Solid color, or the base color of patterns
+	Foreground color.Color // Pattern color, defaults to black
+	Stops      []Stop      // Gradient stops, also the palette of noise
+
+	Angle    float64 // Direction of linear gradients and stripes, start of conic gradients
+	Center   *Point  // Center of radial and conic gradients, defaults to the image center
+	Radius   float64 // Radial: fraction of the distance to the farthest corner (default 1); dots: fraction of CellSize (default 0.25)
+	CellSize float64 // Pattern period in pixels, at least MinCellSize, defaults to 1/8 of the longer side
+	Seed     int64   // Seed of noise patterns
+}
+
+// MaxSize is the largest width or height of a generated background in
+// pixels. It applies even when no resource limits are configured, as specs
+// arrive in place of image data and are generated without a decode step
+const MaxSize = 1 << 14
+
+// MinCellSize is the smallest pattern period in pixels, smaller cells would
+// only alias and noise patterns allocate one entry per cell
+const MinCellSize = 1
+
+// Validate checks that the spec can be generated
+func (s Spec) Validate() error {
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("invalid background size: width=%d, height=%d", s.Width, s.Height)
+	}
+	if s.Width > MaxSize || s.Height > MaxSize {
+		return fmt.Errorf("background size %dx%d exceeds the maximum of %d", s.Width, s.Height, MaxSize)
+	}
+	if s.Radius < 0 || math.IsNaN(s.Radius) {
+		return fmt.Errorf("radius must not be negative")
+	}
+	if s.CellSize != 0 && (s.CellSize < MinCellSize || math.IsNaN(s.CellSize) || math.IsInf(s.CellSize, 0)) {
+		return fmt.Errorf("invalid cell size %g, must be 0 or at least %d", s.CellSize, MinCellSize)
+	}
+
+	switch s.Kind {
+	case KindSolid:
+		if s.Color == nil {
+			return fmt.Errorf("solid background requires a color")
+		}
+	case KindLinear, KindRadial, KindConic:
+		if len(s.Stops) == 0 {
+			return fmt.Errorf("%s gradient requires at least one stop", s.Kind)
+		}
+	case KindStripes, KindDots, KindCheckerboard, KindNoise:
+	default:
+		return fmt.Errorf("unknown background kind: %q", s.Kind)
+	}
+
+	for i, stop := range s.Stops {
+		if stop.Color == nil {
+			return fmt.Errorf("stop %d has no color", i)
+		}
+		if stop.Offset < 0 || stop.Offset > 1 {
+			return fmt.Errorf("stop %d offset %g out of range [0, 1]", i, stop.Offset)
+		}
+	}
+	return nil
+}
+
+// Marshal serializes a spec into bytes accepted in place of background
+// image data, see IsSpec and Unmarshal
+func Marshal(s Spec) ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, magic...), data...), nil
+}
+
+// IsSpec reports whether data is a spec serialized by Marshal
+func IsSpec(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Unmarshal parses and validates a spec serialized by Marshal
+func Unmarshal(data []byte) (Spec, error) {
+	if !IsSpec(data) {
+		return Spec{}, fmt.Errorf("data is not a background spec")
+	}
+
+	var s Spec
+	if err := json.Unmarshal(data[len(magic):], &s); err != nil {
+		return Spec{}, fmt.Errorf("error parsing background spec: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return Spec{}, err
+	}
+	return s, nil
+}
+
+// specJSON is the serialized form of Spec, colors are written as hex strings
+type specJSON struct {
+	Kind       Kind       `json:"kind"`
+	Width      int        `json:"width"`
+	Height     int        `json:"height"`
+	Color      string     `json:"color,omitempty"`
+	Foreground string     `json:"foreground,omitempty"`
+	Stops      []stopJSON `json:"stops,omitempty"`
+	Angle      float64    `json:"angle,omitempty"`
+	Center     *Point     `json:"center,omitempty"`
+	Radius     float64    `json:"radius,omitempty"`
+	CellSize   float64    `json:"cell_size,omitempty"`
+	Seed       int64      `json:"seed,omitempty"`
+}
+
+// stopJSON is the serialized form of Stop
+type stopJSON struct {
+	Offset float64 `json:"offset"`
+	Color  string  `json:"color"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Spec) MarshalJSON() ([]byte, error) {
+	out := specJSON{
+		Kind:       s.Kind,
+		Width:      s.Width,
+		Height:     s.Height,
+		Color:      formatColor(s.Color),
+		Foreground: formatColor(s.Foreground),
+		Angle:      s.Angle,
+		Center:     s.Center,
+		Radius:     s.Radius,
+		CellSize:   s.CellSize,
+		Seed:       s.Seed,
+	}
+	for _, stop := range s.Stops {
+		out.Stops = append(out.Stops, stopJSON{Offset: stop.Offset, Color: formatColor(stop.Color)})
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rejecting unknown fields
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	var in specJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return err
+	}
+
+	out := Spec{
+		Kind:     in.Kind,
+		Width:    in.Width,
+		Height:   in.Height,
+		Angle:    in.Angle,
+		Center:   in.Center,
+		Radius:   in.Radius,
+		CellSize: in.CellSize,
+		Seed:     in.Seed,
+	}
+	var err error
+	if out.Color, err = parseColor(in.Color); err != nil {
+		return fmt.Errorf("color: %w", err)
+	}
+	if out.Foreground, err = parseColor(in.Foreground); err != nil {
+		return fmt.Errorf("foreground: %w", err)
+	}
+	for i, stop := range in.Stops {
+		c, err := parseColor(stop.Color)
+		if err != nil {
+			return fmt.Errorf("stops[%d].color: %w", i, err)
+		}
+		out.Stops = append(out.Stops, Stop{Offset: stop.Offset, Color: c})
+	}
+
+	*s = out
+	return nil
+}
+
+// formatColor formats an optional color, nil becomes an empty string
+func formatColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	return utils.FormatColor(c)
+}
+
+// parseColor parses an optional color, an empty string becomes nil
+func parseColor(s string) (color.Color, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return utils.ParseColor(s)
+}
@@ -0,0 +1,82 @@
+package background
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestValidateCellSize(t *testing.T) {
+	base := Spec{Kind: KindNoise, Width: 64, Height: 64, Color: color.White}
+	tests := []struct {
+		cellSize float64
+		valid    bool
+	}{
+		{0, true},
+		{MinCellSize, true},
+		{8, true},
+		{0.0001, false},
+		{0.5, false},
+		{-1, false},
+		{math.NaN(), false},
+		{math.Inf(1), false},
+	}
+	for _, tt := range tests {
+		s := base
+		s.CellSize = tt.cellSize
+		if err := s.Validate(); (err == nil) != tt.valid {
+			t.Errorf("CellSize %g: Validate() = %v, want valid %v", tt.cellSize, err, tt.valid)
+		}
+	}
+}
+
+func TestGenerateRejectsTinyNoiseCells(t *testing.T) {
+	// Would allocate about 3.6 TB of noise cells if accepted
+	s := Spec{Kind: KindNoise, Width: 64, Height: 64, CellSize: 0.0001}
+	if _, err := Generate(s); err == nil {
+		t.Fatal("Generate accepted a cell size below MinCellSize")
+	}
+	if _, err := Marshal(s); err == nil {
+		t.Fatal("Marshal accepted a cell size below MinCellSize")
+	}
+
+	s.CellSize = MinCellSize
+	img, err := Generate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 {
+		t.Fatalf("unexpected size %v", img.Bounds())
+	}
+}
+
+func TestValidateSize(t *testing.T) {
+	tests := []struct {
+		width, height int
+		valid         bool
+	}{
+		{1, 1, true},
+		{MaxSize, MaxSize, true},
+		{0, 64, false},
+		{64, -1, false},
+		{MaxSize + 1, 64, false},
+		{64, 1 << 30, false},
+	}
+	for _, tt := range tests {
+		s := Spec{Kind: KindSolid, Width: tt.width, Height: tt.height, Color: color.White}
+		if err := s.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%dx%d: Validate() = %v, want valid %v", tt.width, tt.height, err, tt.valid)
+		}
+	}
+}
+
+func TestUnmarshalRejectsOversizedSpecs(t *testing.T) {
+	// Written by hand, Marshal refuses to produce it
+	data := []byte(string(magic) + `{"kind":"solid","width":1000000,"height":1000000,"color":"#ffffff"}`)
+	if _, err := Unmarshal(data); err == nil {
+		t.Fatal("Unmarshal accepted a 1000000x1000000 background")
+	}
+	if _, err := Generate(Spec{Kind: KindSolid, Width: 1 << 20, Height: 1 << 20, Color: color.White}); err == nil {
+		t.Fatal("Generate accepted a background larger than MaxSize")
+	}
+}
@@ -0,0 +1,166 @@
+package background
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// ErrSVGUnsupported is returned by Spec.SVG for kinds SVG cannot express
+var ErrSVGUnsupported = errors.New("background kind has no SVG representation")
+
+// SVG returns the spec as SVG markup covering (0, 0) to (Width, Height)
+//
+// defs holds gradients and patterns to place in a <defs> element, their ids
+// start with id; content holds the shapes painting the background. Conic
+// gradients have no SVG equivalent and return ErrSVGUnsupported.
+func (s Spec) SVG(id string) (defs, content string, err error) {
+	if err = s.Validate(); err != nil {
+		return "", "", err
+	}
+
+	w, h := float64(s.Width), float64(s.Height)
+	cx, cy := s.center()
+	var d, c strings.Builder
+	fill := func(paint string) {
+		fmt.Fprintf(&c, `<rect width="%s" height="%s"%s/>`, num(w), num(h), paint)
+	}
+
+	switch s.Kind {
+	case KindSolid:
+		fill(paintAttrs("fill", s.Color))
+
+	case KindLinear:
+		// Same geometry as the raster shader: the gradient spans the projection
+		// of the image onto the direction
+		dx, dy := direction(s.Angle)
+		half := (math.Abs(w*dx) + math.Abs(h*dy)) / 2
+		fmt.Fprintf(&d, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s">`,
+			id, num(w/2-dx*half), num(h/2-dy*half), num(w/2+dx*half), num(h/2+dy*half))
+		writeStops(&d, s.Stops)
+		d.WriteString(`</linearGradient>`)
+		fill(fmt.Sprintf(` fill="url(#%s)"`, id))
+
+	case KindRadial:
+		radius := s.Radius
+		if radius == 0 {
+			radius = 1
+		}
+		farthest := math.Hypot(math.Max(cx, w-cx), math.Max(cy, h-cy)) * radius
+		fmt.Fprintf(&d, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">`,
+			id, num(cx), num(cy), num(farthest))
+		writeStops(&d, s.Stops)
+		d.WriteString(`</radialGradient>`)
+		fill(fmt.Sprintf(` fill="url(#%s)"`, id))
+
+	case KindConic:
+		return "", "", fmt.Errorf("%w: %s", ErrSVGUnsupported, s.Kind)
+
+	case KindStripes:
+		// One band of each color along the pattern's x axis, rotated onto the direction
+		cell := s.cellSize()
+		bg, fg := s.patternColors()
+		writePattern(&d, id, 2*cell, 2*cell, fmt.Sprintf(` patternTransform="rotate(%s)"`, num(s.Angle)), bg)
+		fmt.Fprintf(&d, `<rect x="%s" width="%s" height="%s"%s/></pattern>`,
+			num(cell), num(cell), num(2*cell), paintAttrs("fill", fg))
+		fill(fmt.Sprintf(` fill="url(#%s)"`, id))
+
+	case KindDots:
+		cell := s.cellSize()
+		radius := s.Radius
+		if radius == 0 {
+			radius = 0.25
+		}
+		bg, fg := s.patternColors()
+		writePattern(&d, id, cell, cell, "", bg)
+		fmt.Fprintf(&d, `<circle cx="%s" cy="%s" r="%s"%s/></pattern>`,
+			num(cell/2), num(cell/2), num(radius*cell), paintAttrs("fill", fg))
+		fill(fmt.Sprintf(` fill="url(#%s)"`, id))
+
+	case KindCheckerboard:
+		cell := s.cellSize()
+		bg, fg := s.patternColors()
+		writePattern(&d, id, 2*cell, 2*cell, "", bg)
+		paint := paintAttrs("fill", fg)
+		fmt.Fprintf(&d, `<rect x="%s" width="%s" height="%s"%s/>`, num(cell), num(cell), num(cell), paint)
+		fmt.Fprintf(&d, `<rect y="%s" width="%s" height="%s"%s/></pattern>`, num(cell), num(cell), num(cell), paint)
+		fill(fmt.Sprintf(` fill="url(#%s)"`, id))
+
+	case KindNoise:
+		// Triangles are written out one by one so the output matches the raster
+		// cell for cell; crisp edges avoid seams between neighbours
+		cell := s.cellSize()
+		cols, cells := s.noiseCells()
+		c.WriteString(`<g shape-rendering="crispEdges">`)
+		for i, nc := range cells {
+			x0, y0 := float64(i%cols)*cell, float64(i/cols)*cell
+			if x0 >= w || y0 >= h {
+				continue
+			}
+			x1, y1 := x0+cell, y0+cell
+			upper := [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}}
+			lower := [][2]float64{{x0, y0}, {x1, y1}, {x0, y1}}
+			if nc.flip {
+				upper = [][2]float64{{x0, y0}, {x1, y0}, {x0, y1}}
+				lower = [][2]float64{{x1, y0}, {x1, y1}, {x0, y1}}
+			}
+			writePolygon(&c, upper, nc.upper)
+			writePolygon(&c, lower, nc.lower)
+		}
+		c.WriteString(`</g>`)
+
+	default:
+		return "", "", fmt.Errorf("unknown background kind: %q", s.Kind)
+	}
+	return d.String(), c.String(), nil
+}
+
+// writeStops writes the gradient stops sorted by offset
+func writeStops(sb *strings.Builder, stops []Stop) {
+	sorted := append([]Stop(nil), stops...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	for _, stop := range sorted {
+		fmt.Fprintf(sb, `<stop offset="%s"%s/>`, num(stop.Offset), paintAttrs("stop-color", stop.Color))
+	}
+}
+
+// writePattern opens a pattern element tiling from the origin and fills the
+// tile with the base color, the caller writes the foreground and closes it
+func writePattern(sb *strings.Builder, id string, w, h float64, extra string, bg color.RGBA) {
+	fmt.Fprintf(sb, `<pattern id="%s" patternUnits="userSpaceOnUse" width="%s" height="%s"%s>`,
+		id, num(w), num(h), extra)
+	if bg.A > 0 {
+		fmt.Fprintf(sb, `<rect width="%s" height="%s"%s/>`, num(w), num(h), paintAttrs("fill", bg))
+	}
+}
+
+// writePolygon writes a filled polygon
+func writePolygon(sb *strings.Builder, points [][2]float64, c color.RGBA) {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = num(p[0]) + "," + num(p[1])
+	}
+	fmt.Fprintf(sb, `<polygon points="%s"%s/>`, strings.Join(coords, " "), paintAttrs("fill", c))
+}
+
+// paintAttrs returns a color attribute such as fill or stop-color, followed by
+// the matching opacity attribute when the color is translucent
+func paintAttrs(attr string, c color.Color) string {
+	hex, opacity := utils.SVGPaint(c)
+	out := fmt.Sprintf(` %s="%s"`, attr, hex)
+	if opacity < 1 {
+		out += fmt.Sprintf(` %s-opacity="%s"`, strings.TrimSuffix(attr, "-color"), num(opacity))
+	}
+	return out
+}
+
+// num formats a coordinate with at most three decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
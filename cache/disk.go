@@ -0,0 +1,546 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskMagic identifies files written by DiskCache
+var diskMagic = []byte("ICMK")
+
+// diskHeaderSize is the size of magic + crc32 + key length
+const diskHeaderSize = 4 + 4 + 4
+
+// diskTempPrefix marks files that are still being written
+const diskTempPrefix = ".tmp-"
+
+// ErrCorruptedEntry is returned when a cache file fails validation
+var ErrCorruptedEntry = errors.New("corrupted cache entry")
+
+// Codec converts cache items to and from bytes for persistent caches
+type Codec interface {
+	// Encode serializes an item
+	Encode(item CacheItem) ([]byte, error)
+
+	// Decode deserializes an item produced by Encode
+	Decode(data []byte) (CacheItem, error)
+}
+
+// EncodedImageCodec is the Codec for *EncodedImage items
+type EncodedImageCodec struct{}
+
+// Encode implements Codec, the format name is stored in front of the data
+func (EncodedImageCodec) Encode(item CacheItem) ([]byte, error) {
+	img, ok := item.(*EncodedImage)
+	if !ok {
+		return nil, fmt.Errorf("item is not *EncodedImage: %T", item)
+	}
+	if len(img.format) > 255 {
+		return nil, fmt.Errorf("format name too long: %q", img.format)
+	}
+
+	data := make([]byte, 0, 1+len(img.format)+len(img.data))
+	data = append(data, byte(len(img.format)))
+	data = append(data, img.format...)
+	data = append(data, img.data...)
+	return data, nil
+}
+
+// Decode implements Codec
+func (EncodedImageCodec) Decode(data []byte) (CacheItem, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, ErrCorruptedEntry
+	}
+	formatLen := int(data[0])
+	return NewEncodedImage(data[1+formatLen:], string(data[1:1+formatLen])), nil
+}
+
+// diskEntry is the in-memory index record of a cache file
+type diskEntry struct {
+	name string // content-addressed file name
+	key  string // cache key, read from the file header on load
+	size int64  // file size in bytes
+}
+
+// diskEvicted is an eviction waiting to be notified, the file was moved
+// aside to path so the item can be decoded once the lock is released
+type diskEvicted struct {
+	key    string
+	path   string
+	reason EvictReason
+}
+
+// diskPending are evictions collected under the cache lock, notified after
+// it is released
+type diskPending struct {
+	entries   []diskEvicted
+	callbacks []EvictCallback
+}
+
+// DiskCache implements Cache backed by a local directory
+//
+// File names are the SHA-256 of the cache key, files are written to a temp
+// file and renamed into place so readers never observe partial writes, and
+// the total size is capped by evicting the least recently accessed files.
+// Files that fail validation are deleted and treated as a cache miss.
+//
+// DiskCache implements EvictNotifier: size-cap evictions are reported with
+// EvictCapacity, Remove with EvictRemoved and Clear with EvictCleared. The
+// item is decoded from the evicted file outside the lock, and is nil if the
+// file can no longer be decoded. Dropping a corrupted file is not reported,
+// and files have no TTL.
+type DiskCache struct {
+	dir      string
+	maxBytes int64 // Maximum total file size in bytes, 0 means unlimited
+	codec    Codec
+
+	bytes   int64                    // Current total file size in bytes
+	entries map[string]*list.Element // File name to LRU list element
+	lru     *list.List               // Front is the most recently accessed
+	mu      sync.Mutex
+
+	onEvict  []EvictCallback // Eviction callbacks
+	pending  []diskEvicted   // Evictions to notify once the lock is released
+	evictSeq uint64          // Makes the names of moved-aside files unique
+}
+
+// NewDiskCache creates a disk cache rooted at dir, creating it if needed
+// Existing files in dir are indexed so a restarted process serves them
+// immediately, ordered by their last access time
+func NewDiskCache(dir string, maxBytes int64, codec Codec) (*DiskCache, error) {
+	if codec == nil {
+		codec = EncodedImageCodec{}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache dir: %w", err)
+	}
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		codec:    codec,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load indexes existing cache files and removes leftover temp files and
+// files whose header cannot be read
+func (c *DiskCache) load() error {
+	type found struct {
+		entry *diskEntry
+		atime time.Time
+	}
+	var files []found
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), diskTempPrefix) {
+			_ = os.Remove(path)
+			return nil
+		}
+		if !isCacheFileName(d.Name()) || filepath.Base(filepath.Dir(path)) != d.Name()[:2] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		key, err := readDiskKey(path)
+		if err != nil {
+			_ = os.Remove(path)
+			return nil
+		}
+		files = append(files, found{
+			entry: &diskEntry{name: d.Name(), key: key, size: info.Size()},
+			atime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error loading cache dir: %w", err)
+	}
+
+	// Oldest first, so the most recently accessed file ends up in front
+	sort.Slice(files, func(i, j int) bool { return files[i].atime.Before(files[j].atime) })
+	for _, f := range files {
+		c.entries[f.entry.name] = c.lru.PushFront(f.entry)
+		c.bytes += f.entry.size
+	}
+	c.evictOverflow()
+	c.notify(c.takePending())
+	return nil
+}
+
+// Get retrieves an item from cache
+func (c *DiskCache) Get(key string) (CacheItem, bool) {
+	name := c.fileName(key)
+
+	c.mu.Lock()
+	elem, found := c.entries[name]
+	var entry *diskEntry
+	if found {
+		entry = elem.Value.(*diskEntry)
+	}
+	c.mu.Unlock()
+	if !found {
+		return nil, false
+	}
+
+	path := c.filePath(name)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		c.dropStale(entry)
+		return nil, false
+	}
+
+	payload, err := c.unwrap(key, raw)
+	if err != nil {
+		c.dropStale(entry)
+		return nil, false
+	}
+	item, err := c.codec.Decode(payload)
+	if err != nil {
+		c.dropStale(entry)
+		return nil, false
+	}
+
+	// Record the access, the modification time doubles as access time
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[name]; ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	return item, true
+}
+
+// Put adds or updates an item in cache
+func (c *DiskCache) Put(key string, item CacheItem) bool {
+	payload, err := c.codec.Encode(item)
+	if err != nil {
+		return false
+	}
+	raw := c.wrap(key, payload)
+
+	c.mu.Lock()
+	maxBytes := c.maxBytes
+	c.mu.Unlock()
+	if maxBytes > 0 && int64(len(raw)) > maxBytes {
+		return false
+	}
+
+	name := c.fileName(key)
+	path := c.filePath(name)
+	tmpName, err := writeTempFile(filepath.Dir(path), raw)
+	if err != nil {
+		return false
+	}
+
+	// Rename under the lock, so a Get that fails on the previous file
+	// cannot drop the new one
+	c.mu.Lock()
+	if err = os.Rename(tmpName, path); err != nil {
+		c.mu.Unlock()
+		_ = os.Remove(tmpName)
+		return false
+	}
+
+	// Every write gets a fresh entry, which tells dropStale apart from
+	// the entry a failed read observed
+	entry := &diskEntry{name: name, key: key, size: int64(len(raw))}
+	if elem, ok := c.entries[name]; ok {
+		c.bytes += entry.size - elem.Value.(*diskEntry).size
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[name] = c.lru.PushFront(entry)
+		c.bytes += entry.size
+	}
+	c.evictOverflow()
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return true
+}
+
+// Remove removes an item from cache
+func (c *DiskCache) Remove(key string) {
+	c.mu.Lock()
+	c.removeEntry(c.fileName(key), EvictRemoved)
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Clear removes all items from cache
+func (c *DiskCache) Clear() {
+	c.mu.Lock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		c.discard(elem.Value.(*diskEntry), EvictCleared)
+	}
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Size returns the number of items in cache
+func (c *DiskCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// SetMaxBytes implements ByteLimiter
+func (c *DiskCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	c.maxBytes = maxBytes
+	c.evictOverflow()
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Bytes implements ByteLimiter, returning the total size of cache files
+func (c *DiskCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// OnEvict implements EvictNotifier
+// Callbacks run after the cache lock is released, so they may safely call
+// back into the cache
+func (c *DiskCache) OnEvict(callback EvictCallback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = append(c.onEvict, callback)
+}
+
+// dropStale removes a corrupted file and its index entry after a failed
+// read, unless the entry was rewritten or removed since the read observed it
+func (c *DiskCache) dropStale(entry *diskEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[entry.name]; ok && elem.Value == entry {
+		c.lru.Remove(elem)
+		delete(c.entries, entry.name)
+		c.bytes -= entry.size
+		_ = os.Remove(c.filePath(entry.name))
+	}
+}
+
+// removeEntry removes a file and its index entry and queues its
+// notification, caller must hold c.mu
+func (c *DiskCache) removeEntry(name string, reason EvictReason) {
+	elem, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	entry := elem.Value.(*diskEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, name)
+	c.bytes -= entry.size
+	c.discard(entry, reason)
+}
+
+// discard deletes the file of an entry, or moves it aside for notification
+// when callbacks are registered, caller must hold c.mu
+func (c *DiskCache) discard(entry *diskEntry, reason EvictReason) {
+	path := c.filePath(entry.name)
+	if len(c.onEvict) == 0 {
+		_ = os.Remove(path)
+		return
+	}
+
+	c.evictSeq++
+	aside := filepath.Join(filepath.Dir(path), fmt.Sprintf("%sevict-%d-%s", diskTempPrefix, c.evictSeq, entry.name))
+	if err := os.Rename(path, aside); err != nil {
+		_ = os.Remove(path)
+		aside = ""
+	}
+	c.pending = append(c.pending, diskEvicted{key: entry.key, path: aside, reason: reason})
+}
+
+// evictOverflow evicts least recently accessed files until the byte budget
+// is respected, caller must hold c.mu
+func (c *DiskCache) evictOverflow() {
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		c.removeEntry(back.Value.(*diskEntry).name, EvictCapacity)
+	}
+}
+
+// takePending returns the queued evictions together with the callbacks to
+// notify, caller must hold c.mu
+func (c *DiskCache) takePending() diskPending {
+	if len(c.pending) == 0 {
+		return diskPending{}
+	}
+	p := diskPending{entries: c.pending, callbacks: c.onEvict}
+	c.pending = nil
+	return p
+}
+
+// notify decodes the moved-aside files, invokes the callbacks and deletes
+// the files, must be called without holding c.mu
+func (c *DiskCache) notify(p diskPending) {
+	if len(p.entries) == 0 {
+		return
+	}
+	fired := pendingEvictions{callbacks: p.callbacks}
+	for _, e := range p.entries {
+		fired.entries = append(fired.entries, evictedEntry{e.key, c.readAside(e), e.reason})
+	}
+	fired.fire()
+}
+
+// readAside decodes and deletes a moved-aside file, returning nil if it
+// cannot be decoded
+func (c *DiskCache) readAside(e diskEvicted) CacheItem {
+	if e.path == "" {
+		return nil
+	}
+	defer os.Remove(e.path)
+
+	raw, err := os.ReadFile(e.path)
+	if err != nil {
+		return nil
+	}
+	payload, err := c.unwrap(e.key, raw)
+	if err != nil {
+		return nil
+	}
+	item, err := c.codec.Decode(payload)
+	if err != nil {
+		return nil
+	}
+	return item
+}
+
+// fileName returns the content-addressed file name of a key
+func (c *DiskCache) fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// filePath shards files into sub directories by the first two hex digits
+func (c *DiskCache) filePath(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// isCacheFileName reports whether name looks like a file written by DiskCache
+func isCacheFileName(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// wrap prepends the header and key to the payload
+// layout: magic | crc32(keyLen|key|payload) | keyLen | key | payload
+func (c *DiskCache) wrap(key string, payload []byte) []byte {
+	raw := make([]byte, diskHeaderSize, diskHeaderSize+len(key)+len(payload))
+	copy(raw, diskMagic)
+	binary.BigEndian.PutUint32(raw[8:12], uint32(len(key)))
+	raw = append(raw, key...)
+	raw = append(raw, payload...)
+	binary.BigEndian.PutUint32(raw[4:8], crc32.ChecksumIEEE(raw[8:]))
+	return raw
+}
+
+// unwrap validates the header and returns the payload
+func (c *DiskCache) unwrap(key string, raw []byte) ([]byte, error) {
+	if len(raw) < diskHeaderSize || !bytes.Equal(raw[:4], diskMagic) {
+		return nil, ErrCorruptedEntry
+	}
+	if binary.BigEndian.Uint32(raw[4:8]) != crc32.ChecksumIEEE(raw[8:]) {
+		return nil, ErrCorruptedEntry
+	}
+	keyLen := int(binary.BigEndian.Uint32(raw[8:12]))
+	if len(raw) < diskHeaderSize+keyLen || string(raw[diskHeaderSize:diskHeaderSize+keyLen]) != key {
+		return nil, ErrCorruptedEntry
+	}
+	return raw[diskHeaderSize+keyLen:], nil
+}
+
+// readDiskKey reads the cache key from the header of a cache file
+func readDiskKey(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, diskHeaderSize)
+	if _, err = io.ReadFull(f, header); err != nil || !bytes.Equal(header[:4], diskMagic) {
+		return "", ErrCorruptedEntry
+	}
+	key := make([]byte, binary.BigEndian.Uint32(header[8:12]))
+	if _, err = io.ReadFull(f, key); err != nil {
+		return "", ErrCorruptedEntry
+	}
+	return string(key), nil
+}
+
+// writeTempFile writes data to a temp file in dir, the caller renames it
+// into place so readers never observe partial writes
+func writeTempFile(dir string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, diskTempPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	tmpName := tmp.Name()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return "", err
+	}
+	return tmpName, nil
+}
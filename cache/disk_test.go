@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestDiskCache(t *testing.T, dir string, maxBytes int64) *DiskCache {
+	t.Helper()
+	c, err := NewDiskCache(dir, maxBytes, EncodedImageCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testImage(data string) *EncodedImage {
+	return NewEncodedImage([]byte(data), "png")
+}
+
+// tempFiles returns the files in dir that are still being written or were
+// moved aside for notification
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && strings.HasPrefix(d.Name(), diskTempPrefix) {
+			found = append(found, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+// evictRecorder collects eviction notifications
+type evictRecorder struct {
+	mu     sync.Mutex
+	events []string
+	items  map[string]CacheItem
+}
+
+func (r *evictRecorder) record(key string, item CacheItem, reason EvictReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, key+":"+reason.String())
+	if r.items == nil {
+		r.items = make(map[string]CacheItem)
+	}
+	r.items[key] = item
+}
+
+func (r *evictRecorder) got() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, ",")
+}
+
+func TestDiskCacheCorruptionRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(raw []byte) []byte
+	}{
+		{"empty file", func([]byte) []byte { return nil }},
+		{"truncated header", func(raw []byte) []byte { return raw[:diskHeaderSize-1] }},
+		{"truncated payload", func(raw []byte) []byte { return raw[:len(raw)-1] }},
+		{"bad magic", func(raw []byte) []byte { raw[0] = 'X'; return raw }},
+		{"flipped payload byte", func(raw []byte) []byte { raw[len(raw)-1] ^= 0xff; return raw }},
+		{"other key", func([]byte) []byte { return (&DiskCache{}).wrap("other", []byte{0}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestDiskCache(t, t.TempDir(), 0)
+			if !c.Put("icon", testImage("data")) {
+				t.Fatal("Put failed")
+			}
+			path := c.filePath(c.fileName("icon"))
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(path, tt.corrupt(raw), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := c.Get("icon"); ok {
+				t.Fatal("corrupted entry was served")
+			}
+			if _, err = os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("corrupted file was not deleted: %v", err)
+			}
+			if c.Size() != 0 || c.Bytes() != 0 {
+				t.Errorf("index still holds %d items, %d bytes", c.Size(), c.Bytes())
+			}
+
+			if !c.Put("icon", testImage("fresh")) {
+				t.Fatal("Put after corruption failed")
+			}
+			item, ok := c.Get("icon")
+			if !ok || string(item.(*EncodedImage).Bytes()) != "fresh" {
+				t.Errorf("Get after rewrite = %v, %v", item, ok)
+			}
+		})
+	}
+}
+
+func TestDiskCacheWriteThenRename(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 0)
+	if !c.Put("icon", testImage("data")) {
+		t.Fatal("Put failed")
+	}
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("temp files left after Put: %v", files)
+	}
+
+	// A crash between writing and renaming leaves a temp file behind, which
+	// the next process removes without indexing it
+	leftover := filepath.Join(filepath.Dir(c.filePath(c.fileName("icon"))), diskTempPrefix+"crashed")
+	if err := os.WriteFile(leftover, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newTestDiskCache(t, dir, 0)
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("temp files left after restart: %v", files)
+	}
+	if restarted.Size() != 1 {
+		t.Errorf("restarted cache indexed %d files, want 1", restarted.Size())
+	}
+	item, ok := restarted.Get("icon")
+	if !ok || !bytes.Equal(item.(*EncodedImage).Bytes(), []byte("data")) {
+		t.Errorf("restarted cache Get = %v, %v", item, ok)
+	}
+}
+
+func TestDiskCacheEvictsByAccessTime(t *testing.T) {
+	c := newTestDiskCache(t, t.TempDir(), 0)
+	c.Put("a", testImage("1111"))
+	entrySize := c.Bytes()
+	c.Remove("a")
+
+	var rec evictRecorder
+	c.SetMaxBytes(entrySize * 2)
+	c.OnEvict(rec.record)
+
+	c.Put("a", testImage("1111"))
+	c.Put("b", testImage("2222"))
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing before eviction")
+	}
+	c.Put("c", testImage("3333"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("least recently accessed entry survived")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if got := rec.got(); got != "b:capacity" {
+		t.Errorf("evictions = %q, want b:capacity", got)
+	}
+	if item, _ := rec.items["b"].(*EncodedImage); item == nil || string(item.Bytes()) != "2222" {
+		t.Errorf("evicted item = %v, want the decoded entry", rec.items["b"])
+	}
+	if c.Bytes() > entrySize*2 {
+		t.Errorf("cache holds %d bytes, budget %d", c.Bytes(), entrySize*2)
+	}
+}
+
+func TestDiskCacheLoadKeepsAccessOrder(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 0)
+	c.Put("old", testImage("1111"))
+	c.Put("new", testImage("2222"))
+	entrySize := c.Bytes() / 2
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(c.filePath(c.fileName("old")), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newTestDiskCache(t, dir, entrySize)
+	if _, ok := restarted.Get("old"); ok {
+		t.Errorf("oldest file survived a smaller budget on load")
+	}
+	if _, ok := restarted.Get("new"); !ok {
+		t.Errorf("newest file was evicted on load")
+	}
+}
+
+func TestDiskCacheOnEvict(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestDiskCache(t, dir, 0)
+	var rec evictRecorder
+	c.OnEvict(rec.record)
+
+	c.Put("a", testImage("1"))
+	c.Put("b", testImage("2"))
+	c.Put("c", testImage("3"))
+	c.Remove("a")
+	c.Remove("missing")
+	c.Clear()
+
+	got := rec.got()
+	if !strings.HasPrefix(got, "a:removed,") || !strings.Contains(got, "b:cleared") ||
+		!strings.Contains(got, "c:cleared") || strings.Count(got, ",") != 2 {
+		t.Errorf("evictions = %q", got)
+	}
+	if item, _ := rec.items["c"].(*EncodedImage); item == nil || string(item.Bytes()) != "3" {
+		t.Errorf("cleared item = %v, want the decoded entry", rec.items["c"])
+	}
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("evicted files left behind: %v", files)
+	}
+	if c.Size() != 0 || c.Bytes() != 0 {
+		t.Errorf("cleared cache holds %d items, %d bytes", c.Size(), c.Bytes())
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+// EncodedImage represents a cacheable encoded image, e.g. the PNG or JPEG
+// bytes of a rendered icon
+type EncodedImage struct {
+	data   []byte
+	format string
+}
+
+// NewEncodedImage creates a new encoded image resource
+func NewEncodedImage(data []byte, format string) *EncodedImage {
+	return &EncodedImage{
+		data:   data,
+		format: format,
+	}
+}
+
+// Bytes returns the encoded image data
+func (e *EncodedImage) Bytes() []byte {
+	return e.data
+}
+
+// Format returns the encoding format of the image, e.g. "png"
+func (e *EncodedImage) Format() string {
+	return e.format
+}
+
+// Size implements cache.CacheItem
+func (e *EncodedImage) Size() int {
+	return len(e.data)
+}
+
+// Clone implements cache.Resource
+func (e *EncodedImage) Clone() Resource {
+	dataCopy := make([]byte, len(e.data))
+	copy(dataCopy, e.data)
+
+	return &EncodedImage{
+		data:   dataCopy,
+		format: e.format,
+	}
+}
@@ -0,0 +1,59 @@
+package cache
+
+// EvictReason describes why an item left a cache
+type EvictReason int
+
+// Eviction reasons
+const (
+	// EvictCapacity means the item was evicted to respect the item capacity
+	// or the byte budget
+	EvictCapacity EvictReason = iota
+	// EvictTTL means the item outlived the cache TTL
+	EvictTTL
+	// EvictRemoved means the item was removed explicitly by Remove
+	EvictRemoved
+	// EvictCleared means the item was removed by Clear
+	EvictCleared
+)
+
+// String implements fmt.Stringer
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictTTL:
+		return "ttl"
+	case EvictRemoved:
+		return "removed"
+	case EvictCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictCallback is invoked when an item leaves a cache
+type EvictCallback func(key string, item CacheItem, reason EvictReason)
+
+// evictedEntry is an eviction waiting to be notified
+type evictedEntry struct {
+	key    string
+	item   CacheItem
+	reason EvictReason
+}
+
+// pendingEvictions are collected under the cache lock and fired after it
+// is released
+type pendingEvictions struct {
+	entries   []evictedEntry
+	callbacks []EvictCallback
+}
+
+// fire invokes every callback for every entry, in eviction order
+func (p pendingEvictions) fire() {
+	for _, e := range p.entries {
+		for _, cb := range p.callbacks {
+			cb(e.key, e.item, e.reason)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 // Package cache provides unified caching infrastructure for Icon Marker
 package cache
 
+import "time"
+
 // CacheItem represents an item that can be stored in cache
 type CacheItem interface {
 	// Size returns estimated memory size of the item in bytes
@@ -31,3 +33,26 @@ type Cache interface {
 	// Size returns the total number of items in cache
 	Size() int
 }
+
+// ByteLimiter is implemented by caches that can bound their total memory
+// usage, measured as the sum of CacheItem.Size of all items
+type ByteLimiter interface {
+	// SetMaxBytes sets the byte budget of the cache, 0 disables the limit
+	SetMaxBytes(maxBytes int64)
+
+	// Bytes returns the total size in bytes of all items in cache
+	Bytes() int64
+}
+
+// EvictNotifier is implemented by caches that report evicted items
+type EvictNotifier interface {
+	// OnEvict registers a callback invoked, outside the cache lock,
+	// whenever an item leaves the cache
+	OnEvict(callback EvictCallback)
+}
+
+// Expirer is implemented by caches whose items can expire
+type Expirer interface {
+	// SetTTL sets the time-to-live of items, 0 disables expiry
+	SetTTL(ttl time.Duration)
+}
@@ -2,24 +2,34 @@ package cache
 
 import (
 	"sync"
+	"time"
 )
 
 // lruItem represents an item in the LRU cache
 type lruItem struct {
-	key   string
-	value CacheItem
-	prev  *lruItem
-	next  *lruItem
+	key      string
+	value    CacheItem
+	storedAt time.Time // When the value was last put, used for TTL expiry
+	tick     uint64    // Value of LRUCache.tick when the item was last moved to front
+	prev     *lruItem
+	next     *lruItem
 }
 
 // LRUCache implements an LRU (Least Recently Used) cache
 type LRUCache struct {
 	capacity int                 // Maximum number of items
 	size     int                 // Current number of items
+	maxBytes int64               // Maximum total item size in bytes, 0 means unlimited
+	bytes    int64               // Current total item size in bytes
+	ttl      time.Duration       // Time-to-live of items, 0 means no expiry
 	items    map[string]*lruItem // Map for O(1) lookup
 	head     *lruItem            // Most recently used item
 	tail     *lruItem            // Least recently used item
+	tick     uint64              // Counts moves to front, see Get
 	mu       sync.RWMutex        // For thread safety
+
+	onEvict []EvictCallback // Eviction callbacks
+	pending []evictedEntry  // Evictions to notify once the lock is released
 }
 
 // NewLRUCache creates a new LRU cache with the given capacity
@@ -31,39 +41,89 @@ func NewLRUCache(capacity int) *LRUCache {
 }
 
 // Get retrieves an item from cache
+// An expired item is evicted with EvictTTL and reported as a miss
+//
+// A hit only takes the write lock to move the item to the front when the
+// item is outside the most recent quarter of the list, so hits on hot items
+// share the read lock. Items in that quarter are at most size/4 moves away
+// from the front, which keeps eviction approximately LRU.
 func (c *LRUCache) Get(key string) (CacheItem, bool) {
 	c.mu.RLock()
 	item, found := c.items[key]
+	var value CacheItem
+	var expired, recent bool
+	if found {
+		value = item.value
+		expired = c.expired(item, time.Now())
+		recent = c.tick-item.tick < uint64(c.size/4)
+	}
 	c.mu.RUnlock()
 
 	if !found {
 		return nil, false
 	}
+	if recent && !expired {
+		return value, true
+	}
 
-	// Move item to front (most recently used)
+	// Move item to front (most recently used), unless it was removed or
+	// replaced between releasing the read lock and taking the write lock
 	c.mu.Lock()
-	c.moveToFront(item)
+	if c.items[key] == item {
+		if expired {
+			c.evict(item, EvictTTL)
+		} else {
+			c.moveToFront(item)
+		}
+	}
+	evicted := c.takePending()
 	c.mu.Unlock()
 
-	return item.value, true
+	c.notify(evicted)
+	if expired {
+		return nil, false
+	}
+	return value, true
 }
 
 // Put adds or updates an item in cache
 func (c *LRUCache) Put(key string, value CacheItem) bool {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	stored := c.put(key, value)
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return stored
+}
+
+// put adds or updates an item, caller must hold c.mu
+func (c *LRUCache) put(key string, value CacheItem) bool {
+	// Items larger than the whole byte budget can never be stored
+	if c.maxBytes > 0 && int64(value.Size()) > c.maxBytes {
+		if item, found := c.items[key]; found {
+			c.evict(item, EvictCapacity)
+		}
+		return false
+	}
 
 	// Check if item already exists
 	if item, found := c.items[key]; found {
+		c.bytes += int64(value.Size()) - int64(item.value.Size())
 		item.value = value
+		item.storedAt = time.Now()
 		c.moveToFront(item)
+		c.evictOverflow()
 		return true
 	}
 
 	// Create new item
+	c.tick++
 	item := &lruItem{
-		key:   key,
-		value: value,
+		key:      key,
+		value:    value,
+		storedAt: time.Now(),
+		tick:     c.tick,
 	}
 
 	// Add to cache
@@ -81,11 +141,10 @@ func (c *LRUCache) Put(key string, value CacheItem) bool {
 	}
 
 	c.size++
+	c.bytes += int64(value.Size())
 
-	// Evict if over capacity
-	if c.size > c.capacity {
-		c.evictLRU()
-	}
+	// Evict if over capacity or byte budget
+	c.evictOverflow()
 
 	return true
 }
@@ -93,24 +152,32 @@ func (c *LRUCache) Put(key string, value CacheItem) bool {
 // Remove removes an item from cache
 func (c *LRUCache) Remove(key string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if item, found := c.items[key]; found {
-		c.removeItem(item)
-		delete(c.items, key)
-		c.size--
+		c.evict(item, EvictRemoved)
 	}
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
 }
 
 // Clear removes all items from cache
 func (c *LRUCache) Clear() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
+	if len(c.onEvict) > 0 {
+		for item := c.head; item != nil; item = item.next {
+			c.pending = append(c.pending, evictedEntry{item.key, item.value, EvictCleared})
+		}
+	}
 	c.items = make(map[string]*lruItem)
 	c.head = nil
 	c.tail = nil
 	c.size = 0
+	c.bytes = 0
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
 }
 
 // Size returns the number of items in cache
@@ -120,8 +187,73 @@ func (c *LRUCache) Size() int {
 	return c.size
 }
 
+// SetMaxBytes sets the byte budget of the cache, 0 disables the limit.
+// Items are evicted immediately if the cache is already over the new budget
+func (c *LRUCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	c.maxBytes = maxBytes
+	c.evictOverflow()
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// Bytes returns the total size in bytes of all items in cache
+func (c *LRUCache) Bytes() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.bytes
+}
+
+// SetTTL sets the time-to-live of items, 0 disables expiry
+// Items expire ttl after they were last put, expired items are evicted
+// lazily on access or by RemoveExpired
+func (c *LRUCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// RemoveExpired evicts all expired items and returns how many were evicted
+func (c *LRUCache) RemoveExpired() int {
+	c.mu.Lock()
+	now := time.Now()
+	count := 0
+	for item := c.tail; item != nil; {
+		prev := item.prev
+		if c.expired(item, now) {
+			c.evict(item, EvictTTL)
+			count++
+		}
+		item = prev
+	}
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return count
+}
+
+// OnEvict registers a callback invoked whenever an item leaves the cache
+// Callbacks run after the cache lock is released, so they may safely call
+// back into the cache
+func (c *LRUCache) OnEvict(callback EvictCallback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = append(c.onEvict, callback)
+}
+
+// expired reports whether an item has outlived the TTL, caller must hold c.mu
+func (c *LRUCache) expired(item *lruItem, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(item.storedAt) > c.ttl
+}
+
 // moveToFront moves an item to the front of the list (most recently used)
 func (c *LRUCache) moveToFront(item *lruItem) {
+	c.tick++
+	item.tick = c.tick
+
 	// Already at front
 	if item == c.head {
 		return
@@ -145,18 +277,67 @@ func (c *LRUCache) moveToFront(item *lruItem) {
 	c.head = item
 }
 
+// evictOldest evicts the least recently used item with EvictCapacity and
+// reports whether there was one, used by ShardedLRUCache to enforce its
+// total capacity across shards
+func (c *LRUCache) evictOldest() bool {
+	c.mu.Lock()
+	found := c.tail != nil
+	c.evictLRU()
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+	return found
+}
+
+// evictKey evicts an item with EvictCapacity, used by ShardedLRUCache for
+// items that no longer fit its total byte budget
+func (c *LRUCache) evictKey(key string) {
+	c.mu.Lock()
+	if item, found := c.items[key]; found {
+		c.evict(item, EvictCapacity)
+	}
+	evicted := c.takePending()
+	c.mu.Unlock()
+
+	c.notify(evicted)
+}
+
+// stats returns the number of items and their total size in bytes
+func (c *LRUCache) stats() (int, int64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.size, c.bytes
+}
+
 // evictLRU removes the least recently used item
 func (c *LRUCache) evictLRU() {
 	if c.tail == nil {
 		return
 	}
+	c.evict(c.tail, EvictCapacity)
+}
 
-	// Remove from map
-	delete(c.items, c.tail.key)
+// evictOverflow evicts least recently used items until both the item
+// capacity and the byte budget are respected
+func (c *LRUCache) evictOverflow() {
+	for c.tail != nil && (c.size > c.capacity || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.evictLRU()
+	}
+}
 
-	// Remove from list
-	c.removeItem(c.tail)
+// evict removes an item from map and list and queues its notification,
+// caller must hold c.mu
+func (c *LRUCache) evict(item *lruItem, reason EvictReason) {
+	delete(c.items, item.key)
+	c.removeItem(item)
 	c.size--
+	c.bytes -= int64(item.value.Size())
+
+	if len(c.onEvict) > 0 {
+		c.pending = append(c.pending, evictedEntry{item.key, item.value, reason})
+	}
 }
 
 // removeItem removes an item from the linked list
@@ -177,3 +358,19 @@ func (c *LRUCache) removeItem(item *lruItem) {
 		c.tail = item.prev
 	}
 }
+
+// takePending returns the queued evictions together with the callbacks to
+// notify, caller must hold c.mu
+func (c *LRUCache) takePending() pendingEvictions {
+	if len(c.pending) == 0 {
+		return pendingEvictions{}
+	}
+	p := pendingEvictions{entries: c.pending, callbacks: c.onEvict}
+	c.pending = nil
+	return p
+}
+
+// notify invokes eviction callbacks, must be called without holding c.mu
+func (c *LRUCache) notify(p pendingEvictions) {
+	p.fire()
+}
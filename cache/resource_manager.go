@@ -13,24 +13,48 @@ type ResourceManager struct {
 	fontCache   Cache
 	imageCache  Cache
 	ttlDuration time.Duration
+	onEvict     []EvictCallback
 	mu          sync.RWMutex
 }
 
 // NewResourceManager creates a new resource manager with specified cache sizes
+// The image cache is reserved for encoded render outputs, see Images
 func NewResourceManager(svgCacheSize, fontCacheSize, imageCacheSize int) *ResourceManager {
-	return &ResourceManager{
+	rm := &ResourceManager{
 		svgCache:    NewLRUCache(svgCacheSize),
 		fontCache:   NewLRUCache(fontCacheSize),
 		imageCache:  NewLRUCache(imageCacheSize),
 		ttlDuration: 30 * time.Minute, // Default TTL
 	}
+	for _, c := range rm.caches() {
+		applyTTL(c, rm.ttlDuration)
+	}
+	return rm
 }
 
 // SetTTL sets the time-to-live duration for cached resources
+// The TTL applies to every managed cache that implements Expirer
 func (rm *ResourceManager) SetTTL(duration time.Duration) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	rm.ttlDuration = duration
+	for _, c := range rm.caches() {
+		applyTTL(c, duration)
+	}
+}
+
+// OnEvict registers a callback invoked whenever a resource leaves one of the
+// managed caches. Keys carry the cache type prefix, e.g. "svg:<hash>".
+// Callbacks run outside the cache locks and may safely re-enter the caches
+func (rm *ResourceManager) OnEvict(callback EvictCallback) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.onEvict = append(rm.onEvict, callback)
+	for _, c := range rm.caches() {
+		if notifier, ok := c.(EvictNotifier); ok {
+			notifier.OnEvict(callback)
+		}
+	}
 }
 
 // GetResource is a generic method to get a resource from the specified cache
@@ -52,6 +76,28 @@ func (rm *ResourceManager) PutResource(cacheType string, key string, cache Cache
 	cache.Put(cacheKey, resource)
 }
 
+// RemoveResource removes a resource from the specified cache
+func (rm *ResourceManager) RemoveResource(cacheType string, key string, cache Cache) {
+	cacheKey := fmt.Sprintf("%s:%s", cacheType, key)
+	cache.Remove(cacheKey)
+}
+
+// SVGs returns a typed view of the SVG cache
+func (rm *ResourceManager) SVGs() *Typed[string, *SVGResource] {
+	return NewTyped[string, *SVGResource](rm.GetSVGCache(), "svg")
+}
+
+// Fonts returns a typed view of the font cache
+func (rm *ResourceManager) Fonts() *Typed[string, *FontResource] {
+	return NewTyped[string, *FontResource](rm.GetFontCache(), "font")
+}
+
+// Images returns a typed view of the image cache, which holds encoded
+// render outputs only, so byte budgets and clearing apply to outputs alone
+func (rm *ResourceManager) Images() *Typed[string, *EncodedImage] {
+	return NewTyped[string, *EncodedImage](rm.GetImageCache(), "image")
+}
+
 // GenerateKeyFromData generates a cache key from binary data using MD5 hash
 func (rm *ResourceManager) GenerateKeyFromData(data []byte) string {
 	return fmt.Sprintf("%x", md5.Sum(data))
@@ -61,7 +107,7 @@ func (rm *ResourceManager) GenerateKeyFromData(data []byte) string {
 func (rm *ResourceManager) ClearAll() {
 	rm.svgCache.Clear()
 	rm.fontCache.Clear()
-	rm.imageCache.Clear()
+	rm.GetImageCache().Clear()
 }
 
 // GetSVGCache returns the SVG cache
@@ -76,5 +122,39 @@ func (rm *ResourceManager) GetFontCache() Cache {
 
 // GetImageCache returns the image cache
 func (rm *ResourceManager) GetImageCache() Cache {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
 	return rm.imageCache
 }
+
+// SetImageCache replaces the image cache, e.g. with a TieredCache that keeps
+// rendered icons on disk across restarts
+func (rm *ResourceManager) SetImageCache(cache Cache) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.imageCache = cache
+	rm.adopt(cache)
+}
+
+// caches returns all managed caches, caller must hold rm.mu
+func (rm *ResourceManager) caches() []Cache {
+	return []Cache{rm.svgCache, rm.fontCache, rm.imageCache}
+}
+
+// adopt applies the TTL and eviction callbacks to a replacement cache,
+// caller must hold rm.mu
+func (rm *ResourceManager) adopt(cache Cache) {
+	applyTTL(cache, rm.ttlDuration)
+	if notifier, ok := cache.(EvictNotifier); ok {
+		for _, cb := range rm.onEvict {
+			notifier.OnEvict(cb)
+		}
+	}
+}
+
+// applyTTL sets the TTL on caches that support expiry
+func applyTTL(cache Cache, ttl time.Duration) {
+	if expirer, ok := cache.(Expirer); ok {
+		expirer.SetTTL(ttl)
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"github.com/golang/freetype/truetype"
+)
+
+// SVGResource represents a cacheable SVG resource
+// 存储原始 SVG 数据而不是已解析的图标，这样我们可以避免并发问题
+type SVGResource struct {
+	svgData []byte // 存储原始SVG数据，而不是解析后的图标
+}
+
+// NewSVGResource creates a new SVG resource from raw SVG data
+func NewSVGResource(svgData []byte) *SVGResource {
+	return &SVGResource{
+		svgData: svgData,
+	}
+}
+
+// Data returns the raw SVG data
+func (r *SVGResource) Data() []byte {
+	return r.svgData
+}
+
+// Size implements cache.CacheItem
+// 返回 SVG 数据的实际大小
+func (r *SVGResource) Size() int {
+	// 返回实际SVG数据的大小
+	return len(r.svgData)
+}
+
+// Clone implements cache.Resource
+// 深度复制 SVG 数据以避免并发修改问题
+func (r *SVGResource) Clone() Resource {
+	if len(r.svgData) == 0 {
+		return &SVGResource{}
+	}
+
+	// 深度复制数据
+	dataCopy := make([]byte, len(r.svgData))
+	copy(dataCopy, r.svgData)
+
+	return &SVGResource{
+		svgData: dataCopy,
+	}
+}
+
+// FontResource represents a cacheable font resource
+type FontResource struct {
+	font *truetype.Font
+}
+
+// NewFontResource creates a new font resource from a parsed font
+func NewFontResource(font *truetype.Font) *FontResource {
+	return &FontResource{
+		font: font,
+	}
+}
+
+// Font returns the parsed font
+func (r *FontResource) Font() *truetype.Font {
+	return r.font
+}
+
+// Size implements cache.CacheItem
+func (r *FontResource) Size() int {
+	// Approximation of font size in memory
+	return 100 * 1024 // 100KB is a reasonable approximation
+}
+
+// Clone implements cache.Resource
+func (r *FontResource) Clone() Resource {
+	// Fonts are immutable, so we can return the same instance
+	return r
+}
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultShardCount is used when NewShardedLRUCache is given no shard count
+const defaultShardCount = 16
+
+// ShardedLRUCache implements Cache by hash-partitioning keys over several
+// independent LRUCache shards, so that concurrent Get calls on different
+// keys rarely contend for the same lock
+//
+// The capacity and the byte budget apply to the whole cache, not to each
+// shard, so keys that hash unevenly still fit. When a Put overflows them,
+// the least recently used item of the fullest shard is evicted. Recency is
+// tracked per shard, which makes eviction approximately LRU across the
+// whole cache.
+type ShardedLRUCache struct {
+	shards   []*LRUCache
+	capacity int          // Maximum number of items in all shards
+	maxBytes atomic.Int64 // Maximum total item size in bytes, 0 means unlimited
+	evictMu  sync.Mutex   // Serializes evictions across shards
+}
+
+// NewShardedLRUCache creates a sharded LRU cache holding up to capacity
+// items in total, spread over shardCount shards (16 if shardCount <= 0)
+func NewShardedLRUCache(shardCount, capacity int) *ShardedLRUCache {
+	if shardCount <= 0 {
+		shardCount = defaultShardCount
+	}
+
+	// Every shard may grow up to the total capacity, evictOverflow keeps
+	// the sum within it
+	shards := make([]*LRUCache, shardCount)
+	for i := range shards {
+		shards[i] = NewLRUCache(capacity)
+	}
+
+	return &ShardedLRUCache{
+		shards:   shards,
+		capacity: capacity,
+	}
+}
+
+// Get retrieves an item from cache
+func (c *ShardedLRUCache) Get(key string) (CacheItem, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Put adds or updates an item in cache
+func (c *ShardedLRUCache) Put(key string, item CacheItem) bool {
+	s := c.shard(key)
+
+	// Items larger than the whole byte budget can never be stored
+	if maxBytes := c.maxBytes.Load(); maxBytes > 0 && int64(item.Size()) > maxBytes {
+		s.evictKey(key)
+		return false
+	}
+
+	if !s.Put(key, item) {
+		return false
+	}
+	c.evictOverflow(s, item)
+	return true
+}
+
+// Remove removes an item from cache
+func (c *ShardedLRUCache) Remove(key string) {
+	c.shard(key).Remove(key)
+}
+
+// Clear removes all items from cache
+func (c *ShardedLRUCache) Clear() {
+	for _, s := range c.shards {
+		s.Clear()
+	}
+}
+
+// Size returns the number of items in cache
+func (c *ShardedLRUCache) Size() int {
+	size := 0
+	for _, s := range c.shards {
+		size += s.Size()
+	}
+	return size
+}
+
+// SetMaxBytes implements ByteLimiter, the budget applies to all shards
+// together
+func (c *ShardedLRUCache) SetMaxBytes(maxBytes int64) {
+	c.maxBytes.Store(maxBytes)
+	c.evictOverflow(nil, nil)
+}
+
+// Bytes implements ByteLimiter
+func (c *ShardedLRUCache) Bytes() int64 {
+	var total int64
+	for _, s := range c.shards {
+		total += s.Bytes()
+	}
+	return total
+}
+
+// SetTTL implements Expirer
+func (c *ShardedLRUCache) SetTTL(ttl time.Duration) {
+	for _, s := range c.shards {
+		s.SetTTL(ttl)
+	}
+}
+
+// RemoveExpired evicts all expired items and returns how many were evicted
+func (c *ShardedLRUCache) RemoveExpired() int {
+	count := 0
+	for _, s := range c.shards {
+		count += s.RemoveExpired()
+	}
+	return count
+}
+
+// OnEvict implements EvictNotifier
+func (c *ShardedLRUCache) OnEvict(callback EvictCallback) {
+	for _, s := range c.shards {
+		s.OnEvict(callback)
+	}
+}
+
+// evictOverflow evicts from the fullest shard until the total capacity and
+// byte budget are respected, by item count or by bytes, whichever overflows
+// The item just put into shard written is not counted, so it is never the
+// one evicted to make room for itself
+func (c *ShardedLRUCache) evictOverflow(written *LRUCache, item CacheItem) {
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+
+	maxBytes := c.maxBytes.Load()
+	for {
+		var size int
+		var bytes int64
+		var mostItems, mostBytes *LRUCache
+		var shardMostItems int
+		var shardMostBytes int64
+		for _, s := range c.shards {
+			n, b := s.stats()
+			size += n
+			bytes += b
+			if s == written {
+				n--
+				b -= int64(item.Size())
+			}
+			if mostItems == nil || n > shardMostItems {
+				mostItems, shardMostItems = s, n
+			}
+			if mostBytes == nil || b > shardMostBytes {
+				mostBytes, shardMostBytes = s, b
+			}
+		}
+
+		var victim *LRUCache
+		switch {
+		case maxBytes > 0 && bytes > maxBytes:
+			victim = mostBytes
+		case size > c.capacity:
+			victim = mostItems
+		default:
+			return
+		}
+		if !victim.evictOldest() {
+			return
+		}
+	}
+}
+
+// shard returns the shard responsible for key, using FNV-1a
+func (c *ShardedLRUCache) shard(key string) *LRUCache {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime32
+	}
+	return c.shards[h%uint32(len(c.shards))]
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type benchItem struct{}
+
+func (benchItem) Size() int { return 1 }
+
+const benchKeyCount = 1024
+
+// benchmarkGet runs b.N Get calls spread over the given number of goroutines
+// Both caches have room for exactly benchKeyCount items, so they are
+// measured on hits only, the miss rate is reported to make that visible
+func benchmarkGet(b *testing.B, c Cache, goroutines int) {
+	keys := make([]string, benchKeyCount)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("svg:%d", i)
+		c.Put(keys[i], benchItem{})
+	}
+	if c.Size() != benchKeyCount {
+		b.Fatalf("cache holds %d of %d benchmark keys", c.Size(), benchKeyCount)
+	}
+
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	var misses atomic.Int64
+	perGoroutine := (b.N + goroutines - 1) / goroutines
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				if _, ok := c.Get(keys[(offset+i)%benchKeyCount]); !ok {
+					misses.Add(1)
+				}
+			}
+		}(g * 97)
+	}
+	wg.Wait()
+
+	b.ReportMetric(float64(misses.Load())/float64(perGoroutine*goroutines), "misses/op")
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	for _, goroutines := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("LRUCache/goroutines=%d", goroutines), func(b *testing.B) {
+			benchmarkGet(b, NewLRUCache(benchKeyCount), goroutines)
+		})
+		b.Run(fmt.Sprintf("ShardedLRUCache/goroutines=%d", goroutines), func(b *testing.B) {
+			benchmarkGet(b, NewShardedLRUCache(defaultShardCount, benchKeyCount), goroutines)
+		})
+	}
+}
+
+func TestShardedLRUCacheCapacity(t *testing.T) {
+	for _, capacity := range []int{1, 7, 100, 1000} {
+		t.Run(fmt.Sprint(capacity), func(t *testing.T) {
+			c := NewShardedLRUCache(defaultShardCount, capacity)
+			var evicted atomic.Int64
+			c.OnEvict(func(string, CacheItem, EvictReason) { evicted.Add(1) })
+
+			for i := 0; i < capacity*3; i++ {
+				c.Put(fmt.Sprintf("key:%d", i), benchItem{})
+				if want := min(i+1, capacity); c.Size() != want {
+					t.Fatalf("Size() = %d after %d puts, want %d", c.Size(), i+1, want)
+				}
+			}
+			if evicted.Load() != int64(capacity*2) {
+				t.Errorf("%d evictions reported, want %d", evicted.Load(), capacity*2)
+			}
+			if _, ok := c.Get(fmt.Sprintf("key:%d", capacity*3-1)); !ok {
+				t.Errorf("most recent key was evicted")
+			}
+		})
+	}
+}
+
+func TestShardedLRUCacheCapacityConcurrent(t *testing.T) {
+	const capacity = 64
+	c := NewShardedLRUCache(defaultShardCount, capacity)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				c.Put(fmt.Sprintf("key:%d:%d", g, i), benchItem{})
+				c.Get(fmt.Sprintf("key:%d:%d", g, i/2))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if c.Size() != capacity {
+		t.Errorf("Size() = %d, want %d", c.Size(), capacity)
+	}
+}
+
+type sizedItem int
+
+func (s sizedItem) Size() int { return int(s) }
+
+func TestShardedLRUCacheByteBudget(t *testing.T) {
+	c := NewShardedLRUCache(defaultShardCount, 1000)
+	c.SetMaxBytes(100)
+
+	for i := 0; i < 50; i++ {
+		c.Put(fmt.Sprintf("key:%d", i), sizedItem(10))
+		if c.Bytes() > 100 {
+			t.Fatalf("Bytes() = %d after %d puts, budget 100", c.Bytes(), i+1)
+		}
+	}
+	if c.Size() != 10 {
+		t.Errorf("Size() = %d, want 10 items of 10 bytes", c.Size())
+	}
+
+	c.Put("key:0", sizedItem(10))
+	if c.Put("key:0", sizedItem(101)) {
+		t.Errorf("item larger than the budget was stored")
+	}
+	if _, ok := c.Get("key:0"); ok {
+		t.Errorf("oversized update left the previous value cached")
+	}
+}
+
+func TestLRUCacheKeepsHotItems(t *testing.T) {
+	const capacity = 16
+	c := NewLRUCache(capacity)
+	for i := 0; i < capacity; i++ {
+		c.Put(fmt.Sprintf("key:%d", i), benchItem{})
+	}
+
+	// key:0 starts as the least recently used item, hits must keep it alive
+	// although most of them skip the move to front
+	for i := capacity; i < capacity*10; i++ {
+		if _, ok := c.Get("key:0"); !ok {
+			t.Fatalf("hot key evicted after %d puts", i)
+		}
+		c.Put(fmt.Sprintf("key:%d", i), benchItem{})
+	}
+
+	if _, ok := c.Get(fmt.Sprintf("key:%d", capacity)); ok {
+		t.Errorf("cold key survived %d newer puts", capacity*9)
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import "time"
+
+// TieredCache composes several caches into one, ordered from the fastest
+// tier to the slowest, e.g. an in-memory LRUCache in front of a DiskCache
+//
+// Get checks the tiers in order and promotes a hit into all faster tiers,
+// Put, Remove and Clear apply to every tier. A TTL only applies to the tiers
+// that implement Expirer; an item expired in memory but still on disk is
+// served from disk. Evictions are reported by the slowest tier, see OnEvict.
+type TieredCache struct {
+	tiers []Cache
+}
+
+// NewTieredCache creates a tiered cache from the given tiers, fastest first
+func NewTieredCache(tiers ...Cache) *TieredCache {
+	return &TieredCache{
+		tiers: tiers,
+	}
+}
+
+// Get retrieves an item from the first tier that contains it
+func (c *TieredCache) Get(key string) (CacheItem, bool) {
+	for i, tier := range c.tiers {
+		item, found := tier.Get(key)
+		if !found {
+			continue
+		}
+
+		// Promote to faster tiers
+		for j := 0; j < i; j++ {
+			c.tiers[j].Put(key, item)
+		}
+		return item, true
+	}
+	return nil, false
+}
+
+// Put adds or updates an item in all tiers
+// It returns true if at least one tier accepted the item
+func (c *TieredCache) Put(key string, item CacheItem) bool {
+	stored := false
+	for _, tier := range c.tiers {
+		if tier.Put(key, item) {
+			stored = true
+		}
+	}
+	return stored
+}
+
+// Remove removes an item from all tiers
+func (c *TieredCache) Remove(key string) {
+	for _, tier := range c.tiers {
+		tier.Remove(key)
+	}
+}
+
+// Clear removes all items from all tiers
+func (c *TieredCache) Clear() {
+	for _, tier := range c.tiers {
+		tier.Clear()
+	}
+}
+
+// Size returns the number of items in the slowest tier, which is expected
+// to be the most complete one
+func (c *TieredCache) Size() int {
+	if len(c.tiers) == 0 {
+		return 0
+	}
+	return c.tiers[len(c.tiers)-1].Size()
+}
+
+// Tiers returns the underlying caches, fastest first
+func (c *TieredCache) Tiers() []Cache {
+	return c.tiers
+}
+
+// SetTTL implements Expirer by forwarding to the tiers that support expiry
+func (c *TieredCache) SetTTL(ttl time.Duration) {
+	for _, tier := range c.tiers {
+		applyTTL(tier, ttl)
+	}
+}
+
+// OnEvict implements EvictNotifier by registering on the slowest tier only
+//
+// The slowest tier is the most complete one, an item leaves the tiered
+// cache when it leaves that tier. Evictions of the faster tiers are not
+// reported, whatever the reason: capacity and TTL evictions there leave the
+// item in the slower tiers, where the next Get finds and promotes it, and
+// Remove and Clear are reported once by the slowest tier. Nothing is
+// reported if the slowest tier does not implement EvictNotifier.
+func (c *TieredCache) OnEvict(callback EvictCallback) {
+	if len(c.tiers) == 0 {
+		return
+	}
+	if notifier, ok := c.tiers[len(c.tiers)-1].(EvictNotifier); ok {
+		notifier.OnEvict(callback)
+	}
+}
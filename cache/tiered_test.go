@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTieredCachePromotesHits(t *testing.T) {
+	memory := NewLRUCache(10)
+	disk := newTestDiskCache(t, t.TempDir(), 0)
+	c := NewTieredCache(memory, disk)
+
+	disk.Put("icon", testImage("data"))
+	if _, ok := memory.Get("icon"); ok {
+		t.Fatal("memory tier populated before Get")
+	}
+
+	item, ok := c.Get("icon")
+	if !ok || string(item.(*EncodedImage).Bytes()) != "data" {
+		t.Fatalf("Get = %v, %v", item, ok)
+	}
+	if _, ok = memory.Get("icon"); !ok {
+		t.Errorf("disk hit was not promoted into memory")
+	}
+
+	c.Remove("icon")
+	for i, tier := range c.Tiers() {
+		if _, ok = tier.Get("icon"); ok {
+			t.Errorf("Remove left the item in tier %d", i)
+		}
+	}
+}
+
+func TestTieredCacheOnEvict(t *testing.T) {
+	memory := NewLRUCache(1)
+	disk := newTestDiskCache(t, t.TempDir(), 0)
+	c := NewTieredCache(memory, disk)
+	var rec evictRecorder
+	c.OnEvict(rec.record)
+
+	// Memory capacity evictions leave the item on disk
+	c.Put("a", testImage("1"))
+	c.Put("b", testImage("2"))
+	if got := rec.got(); got != "" {
+		t.Errorf("memory capacity eviction reported: %q", got)
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("item evicted from memory was not served from disk")
+	}
+
+	// Memory TTL evictions leave the item on disk as well
+	c.SetTTL(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	memory.RemoveExpired()
+	if got := rec.got(); got != "" {
+		t.Errorf("memory TTL eviction reported: %q", got)
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Errorf("item expired in memory was not served from disk")
+	}
+	c.SetTTL(0)
+
+	// Removal is reported once, by the disk tier
+	c.Remove("a")
+	if got := rec.got(); got != "a:removed" {
+		t.Errorf("evictions after Remove = %q, want a:removed", got)
+	}
+
+	// Disk capacity evictions are reported
+	entrySize := disk.Bytes()
+	disk.SetMaxBytes(entrySize)
+	c.Put("c", testImage("3"))
+	if got := rec.got(); got != "a:removed,b:capacity" {
+		t.Errorf("evictions after disk overflow = %q, want a:removed,b:capacity", got)
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Typed.Lookup
+var (
+	ErrCacheMiss    = errors.New("cache miss")
+	ErrTypeMismatch = errors.New("cache item type mismatch")
+)
+
+// Typed is a type-safe view over a Cache
+//
+// Keys are converted to strings and namespaced with a prefix, values are
+// type-checked on the way out so callers never type-assert CacheItem.
+// Any Cache implementation can back a Typed, so the untyped interface
+// keeps working alongside it.
+type Typed[K comparable, V CacheItem] struct {
+	cache  Cache
+	prefix string
+}
+
+// NewTyped creates a typed view over cache, keys are stored as "prefix:key"
+// or as the plain key when prefix is empty
+func NewTyped[K comparable, V CacheItem](cache Cache, prefix string) *Typed[K, V] {
+	return &Typed[K, V]{
+		cache:  cache,
+		prefix: prefix,
+	}
+}
+
+// Get retrieves an item from cache
+// An item of the wrong type is removed and reported as a miss, so the
+// caller's reload replaces it instead of colliding with it forever
+func (t *Typed[K, V]) Get(key K) (V, bool) {
+	v, err := t.Lookup(key)
+	return v, err == nil
+}
+
+// Lookup retrieves an item from cache, distinguishing a miss (ErrCacheMiss)
+// from an item of the wrong type (ErrTypeMismatch)
+func (t *Typed[K, V]) Lookup(key K) (V, error) {
+	var zero V
+
+	cacheKey := t.key(key)
+	item, found := t.cache.Get(cacheKey)
+	if !found {
+		return zero, ErrCacheMiss
+	}
+
+	v, ok := item.(V)
+	if !ok {
+		t.cache.Remove(cacheKey)
+		return zero, fmt.Errorf("%w: key %q holds %T, want %T", ErrTypeMismatch, cacheKey, item, zero)
+	}
+	return v, nil
+}
+
+// Put adds or updates an item in cache
+func (t *Typed[K, V]) Put(key K, value V) bool {
+	return t.cache.Put(t.key(key), value)
+}
+
+// Remove removes an item from cache
+func (t *Typed[K, V]) Remove(key K) {
+	t.cache.Remove(t.key(key))
+}
+
+// Cache returns the underlying untyped cache
+func (t *Typed[K, V]) Cache() Cache {
+	return t.cache
+}
+
+// key converts a typed key into the underlying string key
+func (t *Typed[K, V]) key(key K) string {
+	var k string
+	if s, ok := any(key).(string); ok {
+		k = s
+	} else {
+		k = fmt.Sprint(key)
+	}
+
+	if t.prefix == "" {
+		return k
+	}
+	return t.prefix + ":" + k
+}
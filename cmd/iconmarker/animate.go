@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bagaking/iconmarker/core"
+)
+
+// runAnimate 实现 animate 子命令，按动画文档输出动画 GIF 或 APNG
+func runAnimate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("animate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	specPath := fs.String("spec", "", "AnimationSpec file (JSON or YAML) (required)")
+	output := fs.String("o", "", `output file, "-" writes to stdout (required)`)
+	format := fs.String("format", "", "output format gif or apng, inferred from -o when empty")
+	colors := fs.Int("colors", core.DefaultGIFColors, "maximum GIF palette size 2-256")
+	dither := fs.Bool("dither", false, "dither GIF output")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *specPath == "" {
+		return userErrorf("-spec is required")
+	}
+	if *output == "" {
+		return userErrorf("-o is required")
+	}
+
+	name := strings.ToLower(*format)
+	if name == "" {
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".gif":
+			name = "gif"
+		case ".png", ".apng":
+			name = "apng"
+		default:
+			return userErrorf("cannot infer animation format from %q, use -format gif or apng", *output)
+		}
+	}
+	if name != "gif" && name != "apng" {
+		return userErrorf("invalid -format %q, expected gif or apng", *format)
+	}
+	if *colors < 2 || *colors > 256 {
+		return userErrorf("invalid -colors: %d", *colors)
+	}
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		return &userError{err: err}
+	}
+	anim, err := core.ParseAnimationSpec(data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	marker := newMarker()
+	if name == "gif" {
+		err = marker.WriteAnimatedGIF(context.Background(), &buf, anim, core.AnimationEncodeOptions{GIFColors: *colors, GIFDither: *dither})
+	} else {
+		err = marker.WriteAPNG(context.Background(), &buf, anim)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err = stdout.Write(buf.Bytes())
+		return err
+	}
+	return writeFile(*output, buf.Bytes())
+}
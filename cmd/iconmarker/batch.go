@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/workpool"
+)
+
+// batchItem 是清单中的一项
+type batchItem struct {
+	line   int            // 清单中的行号，用于报告错误
+	output string         // 输出文件路径
+	params iconParams     // 通过参数描述的图标
+	spec   *core.IconSpec // 内联的 IconSpec，设置后忽略 params
+}
+
+// batchEntryJSON 是 JSONL 清单中一行的格式
+// spec 可以是 IconSpec 文件路径，也可以是内联的 IconSpec 对象
+type batchEntryJSON struct {
+	Output string          `json:"output"`
+	Spec   json.RawMessage `json:"spec,omitempty"`
+	iconParams
+}
+
+// runBatch 实现 batch 子命令
+// 清单无效时不渲染任何图标并返回用户错误；部分图标渲染失败时在全部完成后返回渲染错误
+func runBatch(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	manifest := fs.String("manifest", "", "CSV or JSONL manifest, format inferred from the extension (required)")
+	outDir := fs.String("out-dir", "", "directory relative outputs are written to, defaults to the manifest directory")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of icons rendered concurrently")
+	var enc encodeFlags
+	enc.bind(fs, "output format png, jpeg, gif, bmp or tiff for every icon, inferred from each output when empty")
+	quiet := fs.Bool("q", false, "do not print the progress line")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *manifest == "" {
+		return userErrorf("-manifest is required")
+	}
+	if *workers <= 0 {
+		return userErrorf("invalid -workers: %d", *workers)
+	}
+	if _, err := enc.options(""); err != nil {
+		return err
+	}
+
+	items, err := readManifest(*manifest)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return userErrorf("manifest %s lists no icons", *manifest)
+	}
+
+	baseDir := filepath.Dir(*manifest)
+	if *outDir == "" {
+		*outDir = baseDir
+	}
+
+	progress := &progressLine{w: stderr, total: len(items), quiet: *quiet}
+	marker := newMarker()
+	pool := workpool.NewPool(*workers)
+	results := workpool.Map(context.Background(), pool, items,
+		func(ctx context.Context, _ int, item batchItem) (struct{}, error) {
+			err := renderItem(ctx, marker, item, baseDir, *outDir, &enc)
+			progress.done(err == nil)
+			return struct{}{}, err
+		})
+	progress.finish()
+
+	failed := 0
+	for i, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(stderr, "line %d (%s): %v\n", items[i].line, items[i].output, r.Err)
+		}
+	}
+	fmt.Fprintf(stdout, "rendered %d of %d icons\n", len(items)-failed, len(items))
+	if failed > 0 {
+		return fmt.Errorf("%d icons failed", failed)
+	}
+	return nil
+}
+
+// renderItem 渲染清单中的一项并写入文件
+func renderItem(ctx context.Context, marker *core.IconMarker, item batchItem, baseDir, outDir string,
+	enc *encodeFlags) error {
+	encodeOpts, err := enc.options(item.output)
+	if err != nil {
+		return err
+	}
+
+	spec := item.spec
+	if spec == nil {
+		if spec, err = item.params.loadSpec(baseDir); err != nil {
+			return err
+		}
+	}
+
+	img, err := marker.RenderSpecCtx(ctx, spec)
+	if err != nil {
+		return err
+	}
+	return writeImage(resolvePath(outDir, item.output), img, encodeOpts)
+}
+
+// readManifest 读取清单，.csv 按 CSV 解析，其余按 JSONL 解析
+func readManifest(path string) ([]batchItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &userError{err: err}
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSVManifest(f)
+	}
+	return readJSONLManifest(f)
+}
+
+// readCSVManifest 解析 CSV 清单
+// 第一行是表头，output 列必填，其余列与 render 的参数同名，例如 text、icon、bg、size、spec
+func readCSVManifest(r io.Reader) ([]batchItem, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, userErrorf("error reading manifest header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+		if !isManifestColumn(name) {
+			return nil, userErrorf("manifest header: unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["output"]; !ok {
+		return nil, userErrorf("manifest header: output column is required")
+	}
+
+	var items []batchItem
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &userError{err: err}
+		}
+		line, _ := cr.FieldPos(0)
+
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		item := batchItem{
+			line:   line,
+			output: get("output"),
+			params: iconParams{
+				Spec:      get("spec"),
+				Text:      get("text"),
+				Icon:      get("icon"),
+				IconColor: get("icon_color"),
+				Bg:        get("bg"),
+				BgImage:   get("bg_image"),
+				TextColor: get("text_color"),
+				Font:      get("font"),
+			},
+		}
+		if s := get("size"); s != "" {
+			if item.params.Size, err = strconv.Atoi(s); err != nil {
+				return nil, userErrorf("line %d: invalid size %q", line, s)
+			}
+		}
+		if item.output == "" {
+			return nil, userErrorf("line %d: output is required", line)
+		}
+		item.params = item.params.withDefaults()
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// isManifestColumn 判断 CSV 表头中的列名是否有效
+func isManifestColumn(name string) bool {
+	switch name {
+	case "output", "spec", "text", "icon", "icon_color", "bg", "bg_image", "text_color", "size", "font":
+		return true
+	}
+	return false
+}
+
+// readJSONLManifest 解析 JSONL 清单，每行一个对象，空行和 # 开头的行被忽略
+func readJSONLManifest(r io.Reader) ([]batchItem, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+
+	var items []batchItem
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		var entry batchEntryJSON
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&entry); err != nil {
+			return nil, userErrorf("line %d: %v", line, err)
+		}
+		if entry.Output == "" {
+			return nil, userErrorf("line %d: output is required", line)
+		}
+
+		item := batchItem{line: line, output: entry.Output, params: entry.iconParams.withDefaults()}
+		if spec := bytes.TrimSpace(entry.Spec); len(spec) > 0 {
+			if spec[0] == '"' {
+				if err := json.Unmarshal(spec, &item.params.Spec); err != nil {
+					return nil, userErrorf("line %d: %v", line, err)
+				}
+			} else {
+				parsed, err := core.ParseIconSpec(spec)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+				item.spec = parsed
+			}
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &userError{err: err}
+	}
+	return items, nil
+}
+
+// progressLine 在一行中刷新批量渲染的进度
+type progressLine struct {
+	mu         sync.Mutex
+	w          io.Writer
+	total      int
+	ok, failed int
+	quiet      bool
+}
+
+// done 记录一项完成并刷新进度
+func (p *progressLine) done(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		p.ok++
+	} else {
+		p.failed++
+	}
+	if !p.quiet {
+		fmt.Fprintf(p.w, "\r[%d/%d] ok=%d failed=%d", p.ok+p.failed, p.total, p.ok, p.failed)
+	}
+}
+
+// finish 结束进度行
+func (p *progressLine) finish() {
+	if !p.quiet {
+		fmt.Fprintln(p.w)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bagaking/iconmarker/core"
+)
+
+// runFavicon 实现 favicon 子命令，将网站图标集写入目录
+func runFavicon(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("favicon", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var params iconParams
+	params.bind(fs)
+	outDir := fs.String("out-dir", "", "directory the favicon files are written to (required)")
+	name := fs.String("name", "", "application name written to site.webmanifest")
+	themeColor := fs.String("theme-color", "", "theme color written to site.webmanifest")
+	bgColor := fs.String("background-color", "#FFFFFF", "manifest background color, also fills transparent areas of apple-touch-icon.png")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *outDir == "" {
+		return userErrorf("-out-dir is required")
+	}
+
+	opts := core.FaviconOptions{Name: *name}
+	var err error
+	if opts.ThemeColor, err = parseColorParam("theme color", *themeColor); err != nil {
+		return err
+	}
+	if opts.BackgroundColor, err = parseColorParam("background color", *bgColor); err != nil {
+		return err
+	}
+
+	spec, err := params.loadSpec(".")
+	if err != nil {
+		return err
+	}
+	if err = newMarker().WriteFaviconSet(context.Background(), *outDir, spec, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "favicon set written to %s\n", *outDir)
+	return nil
+}
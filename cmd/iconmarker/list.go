@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/bagaking/iconmarker/assets"
+)
+
+// runListIcons 实现 list-icons 子命令，每行输出一个内嵌图标名称
+func runListIcons(args []string, stdout, stderr io.Writer) error {
+	if err := parseNoFlags("list-icons", args, stderr); err != nil {
+		return err
+	}
+
+	names, err := assets.ListAvailableIcons()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintln(stdout, name)
+	}
+	return nil
+}
+
+// runFilters 实现 filters 子命令，每行输出一个已注册的滤镜
+// 可以在 IconSpec 中通过 options 配置的滤镜会额外标注
+func runFilters(args []string, stdout, stderr io.Writer) error {
+	if err := parseNoFlags("filters", args, stderr); err != nil {
+		return err
+	}
+
+	fm := newMarker().GetFilterManager()
+	for _, name := range fm.Names() {
+		if fm.HasOptionDecoder(name) {
+			fmt.Fprintf(stdout, "%s\t(options)\n", name)
+		} else {
+			fmt.Fprintln(stdout, name)
+		}
+	}
+	return nil
+}
+
+// parseNoFlags 解析不接受任何参数的子命令
+func parseNoFlags(name string, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("%s takes no arguments", name)
+	}
+	return nil
+}
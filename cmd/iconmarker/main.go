@@ -0,0 +1,127 @@
+// Command iconmarker renders icons from the command line
+//
+// Usage:
+//
+//	iconmarker render     -o out.png|out.svg [-spec icon.yaml | -text T -icon NAME -bg #RRGGBB ...]
+//	iconmarker batch      -manifest icons.csv|icons.jsonl [-workers N] [-out-dir DIR]
+//	iconmarker animate    -spec anim.yaml -o out.gif|out.png [-format gif|apng]
+//	iconmarker favicon    -out-dir DIR [-spec icon.yaml | -text T -icon NAME ...] [-name APP]
+//	iconmarker serve      [-addr :8080] [-max-age 24h]
+//	iconmarker list-icons
+//	iconmarker filters
+//
+// Exit codes: 0 on success, 1 when rendering fails, 2 on invalid usage or
+// invalid input such as a malformed spec or a violated resource limit.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+// 退出码
+const (
+	exitOK          = 0
+	exitRenderError = 1
+	exitUserError   = 2
+)
+
+// command 是一个子命令
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands 按帮助信息中的顺序列出所有子命令
+var commands = []command{
+	{"render", "render one icon from flags or an IconSpec file", runRender},
+	{"batch", "render icons listed in a CSV or JSONL manifest", runBatch},
+	{"animate", "render a keyframed AnimationSpec to an animated GIF or APNG", runAnimate},
+	{"favicon", "write favicon.ico, touch icons and site.webmanifest", runFavicon},
+	{"serve", "serve rendered icons over HTTP", runServe},
+	{"list-icons", "list the embedded SVG icons", runListIcons},
+	{"filters", "list the registered filters", runFilters},
+}
+
+// userError 表示由用户输入导致的错误，对应退出码 2
+type userError struct {
+	err error
+}
+
+func (e *userError) Error() string { return e.err.Error() }
+func (e *userError) Unwrap() error { return e.err }
+
+// userErrorf 创建 userError
+func userErrorf(format string, args ...any) error {
+	return &userError{err: fmt.Errorf(format, args...)}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行子命令并返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return exitUserError
+		}
+		return exitOK
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], stdout, stderr)
+		if err == nil {
+			return exitOK
+		}
+		if errors.Is(err, errHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "iconmarker %s: %v\n", cmd.name, err)
+		return exitCode(err)
+	}
+
+	fmt.Fprintf(stderr, "iconmarker: unknown command %q\n\n", args[0])
+	printUsage(stderr)
+	return exitUserError
+}
+
+// exitCode 区分用户错误和渲染失败
+// 无效的参数、文档以及超出资源限制的输入都属于用户错误
+func exitCode(err error) int {
+	var ue *userError
+	switch {
+	case errors.As(err, &ue),
+		errors.Is(err, core.ErrInvalidSpec),
+		errors.Is(err, core.ErrSVGUnsupported),
+		errors.Is(err, limits.ErrInputTooLarge),
+		errors.Is(err, limits.ErrImageTooLarge),
+		errors.Is(err, limits.ErrSVGTooComplex),
+		errors.Is(err, limits.ErrOutputTooLarge):
+		return exitUserError
+	default:
+		return exitRenderError
+	}
+}
+
+// printUsage 输出总体帮助信息
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: iconmarker <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "iconmarker <command> -h" for the flags of a command.`)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/filter/utils"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+// errHelp 表示用户请求了子命令的帮助信息
+var errHelp = flag.ErrHelp
+
+// iconParams 是通过参数描述的简单图标：背景、可选的图标和文字
+// render 的命令行参数和 batch 清单中的每一行都解析为 iconParams
+type iconParams struct {
+	Spec      string `json:"spec"`       // IconSpec 文件路径，设置后忽略其余描述字段
+	Text      string `json:"text"`       // 文字
+	Icon      string `json:"icon"`       // 内嵌图标名称
+	IconColor string `json:"icon_color"` // 图标着色
+	Bg        string `json:"bg"`         // 背景色
+	BgImage   string `json:"bg_image"`   // 背景图片路径，覆盖在背景色之上
+	TextColor string `json:"text_color"` // 文字颜色
+	Size      int    `json:"size"`       // 输出边长
+	Font      string `json:"font"`       // 字体文件路径
+}
+
+// bind 将字段绑定到命令行参数
+func (p *iconParams) bind(fs *flag.FlagSet) {
+	fs.StringVar(&p.Spec, "spec", "", "IconSpec file (JSON or YAML), overrides the other icon flags")
+	fs.StringVar(&p.Text, "text", "", "label text")
+	fs.StringVar(&p.Icon, "icon", "", "embedded icon name, see list-icons")
+	fs.StringVar(&p.IconColor, "icon-color", "", "tint color of the icon, e.g. #FFFFFF")
+	fs.StringVar(&p.Bg, "bg", "#336699", "background color")
+	fs.StringVar(&p.BgImage, "bg-image", "", "background image file (JPEG, PNG or GIF), cropped to fill the icon")
+	fs.StringVar(&p.TextColor, "text-color", "#FFFFFF", "text color")
+	fs.IntVar(&p.Size, "size", 128, "icon width and height in pixels")
+	fs.StringVar(&p.Font, "font", "", "TrueType font file, defaults to the embedded font")
+}
+
+// withDefaults 为清单中省略的字段补上与命令行相同的默认值
+func (p iconParams) withDefaults() iconParams {
+	if p.Bg == "" {
+		p.Bg = "#336699"
+	}
+	if p.TextColor == "" {
+		p.TextColor = "#FFFFFF"
+	}
+	if p.Size == 0 {
+		p.Size = 128
+	}
+	return p
+}
+
+// loadSpec 读取 IconSpec 文件，或根据参数构造 IconSpec
+// 相对路径相对于 baseDir 解析
+func (p iconParams) loadSpec(baseDir string) (*core.IconSpec, error) {
+	if p.Spec != "" {
+		data, err := os.ReadFile(resolvePath(baseDir, p.Spec))
+		if err != nil {
+			return nil, &userError{err: err}
+		}
+		return core.ParseIconSpec(data)
+	}
+	return p.buildSpec(baseDir)
+}
+
+// buildSpec 解析颜色、读取文件，并通过 core.IconParams 构造 IconSpec
+func (p iconParams) buildSpec(baseDir string) (*core.IconSpec, error) {
+	params := core.IconParams{Text: p.Text, Icon: p.Icon, Size: p.Size}
+	var err error
+	if params.Background, err = parseColorParam("bg", p.Bg); err != nil {
+		return nil, err
+	}
+	if params.IconColor, err = parseColorParam("icon color", p.IconColor); err != nil {
+		return nil, err
+	}
+	if params.TextColor, err = parseColorParam("text color", p.TextColor); err != nil {
+		return nil, err
+	}
+
+	if p.BgImage != "" {
+		if params.BackgroundImage, err = os.ReadFile(resolvePath(baseDir, p.BgImage)); err != nil {
+			return nil, &userError{err: err}
+		}
+	}
+	if p.Font != "" {
+		if params.Font, err = os.ReadFile(resolvePath(baseDir, p.Font)); err != nil {
+			return nil, &userError{err: err}
+		}
+	}
+	return params.Spec()
+}
+
+// parseColorParam 解析颜色参数，空字符串返回 nil
+func parseColorParam(name, value string) (color.Color, error) {
+	if value == "" {
+		return nil, nil
+	}
+	c, err := utils.ParseColor(value)
+	if err != nil {
+		return nil, userErrorf("invalid %s: %v", name, err)
+	}
+	return c, nil
+}
+
+// runRender 实现 render 子命令
+func runRender(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var params iconParams
+	params.bind(fs)
+	output := fs.String("o", "", `output file, "-" writes to stdout (required)`)
+	var enc encodeFlags
+	enc.bind(fs, "output format png, jpeg, gif, bmp, tiff or svg, inferred from -o when empty; .ico writes a multi-size ICO")
+	svgText := fs.String("svg-text", string(core.SVGTextPath), "how SVG output writes text: path (glyph outlines) or text (<text> elements)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *output == "" {
+		return userErrorf("-o is required")
+	}
+
+	spec, err := params.loadSpec(".")
+	if err != nil {
+		return err
+	}
+
+	marker := newMarker()
+	if strings.EqualFold(enc.format, "svg") || (enc.format == "" && strings.EqualFold(filepath.Ext(*output), ".svg")) {
+		// 由内嵌 SVG、生成背景和文字构成的图标直接输出矢量文档
+		mode := core.SVGTextMode(*svgText)
+		if mode != core.SVGTextPath && mode != core.SVGTextElement {
+			return userErrorf("invalid -svg-text %q, expected path or text", *svgText)
+		}
+		data, err := marker.RenderSpecSVG(spec, core.SVGWriteOptions{TextMode: mode})
+		if err != nil {
+			return err
+		}
+		if *output == "-" {
+			_, err = stdout.Write(data)
+			return err
+		}
+		return writeFile(*output, data)
+	}
+	if strings.EqualFold(filepath.Ext(*output), ".ico") && enc.format == "" {
+		// ICO 包含多个尺寸，每个尺寸都按缩放后的文档重新渲染
+		var buf bytes.Buffer
+		if err = marker.WriteICO(&buf, spec); err != nil {
+			return err
+		}
+		return writeFile(*output, buf.Bytes())
+	}
+
+	encodeOpts, err := enc.options(*output)
+	if err != nil {
+		return err
+	}
+	img, err := marker.RenderSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		return core.Encode(stdout, img, encodeOpts)
+	}
+	return writeImage(*output, img, encodeOpts)
+}
+
+// newMarker 创建启用默认资源限制的 IconMarker
+func newMarker() *core.IconMarker {
+	marker := core.NewIconMarker()
+	marker.SetLimits(limits.Default())
+	return marker
+}
+
+// encodeFlags 是控制输出编码的命令行参数
+type encodeFlags struct {
+	format  string
+	quality int
+	matte   string
+	colors  int
+	dither  bool
+}
+
+// bind 将字段绑定到命令行参数
+func (e *encodeFlags) bind(fs *flag.FlagSet, formatUsage string) {
+	fs.StringVar(&e.format, "format", "", formatUsage)
+	fs.IntVar(&e.quality, "quality", core.DefaultJPEGQuality, "JPEG quality 1-100")
+	fs.StringVar(&e.matte, "matte", "#FFFFFF", "color transparent pixels are flattened onto for JPEG")
+	fs.IntVar(&e.colors, "colors", core.DefaultGIFColors, "maximum GIF palette size 2-256")
+	fs.BoolVar(&e.dither, "dither", false, "dither GIF output")
+}
+
+// options 返回输出文件的编码选项，未指定 -format 时根据扩展名推断，无扩展名时为 PNG
+func (e *encodeFlags) options(output string) (core.EncodeOptions, error) {
+	opts := core.EncodeOptions{Quality: e.quality, GIFColors: e.colors, GIFDither: e.dither}
+
+	var err error
+	switch {
+	case e.format != "":
+		opts.Format, err = core.ParseImageFormat(e.format)
+	case filepath.Ext(output) != "":
+		opts.Format, err = core.FormatFromPath(output)
+	default:
+		opts.Format = core.FormatPNG
+	}
+	if err != nil {
+		return opts, &userError{err: err}
+	}
+
+	if e.quality < 1 || e.quality > 100 {
+		return opts, userErrorf("invalid -quality: %d", e.quality)
+	}
+	if e.colors < 2 || e.colors > 256 {
+		return opts, userErrorf("invalid -colors: %d", e.colors)
+	}
+	if opts.Matte, err = parseColorParam("matte", e.matte); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// writeImage 编码图像并写入文件，必要时创建目录
+func writeImage(path string, img image.Image, opts core.EncodeOptions) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return core.SaveImage(img, path, opts)
+}
+
+// writeFile 写入文件，目录不存在时创建
+func writeFile(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// resolvePath 将相对路径解析为相对于 baseDir 的路径
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
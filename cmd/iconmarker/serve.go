@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bagaking/iconmarker/httpserver"
+)
+
+// runServe 实现 serve 子命令，收到 SIGINT 或 SIGTERM 时优雅退出
+func runServe(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", ":8080", "listen address")
+	maxAge := fs.Duration("max-age", httpserver.DefaultMaxAge, "Cache-Control max-age of rendered icons, negative disables caching")
+	size := fs.Int("size", httpserver.DefaultIconSize, "icon size when the size query parameter is omitted")
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum duration of a single request")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return &userError{err: err}
+	}
+	if fs.NArg() > 0 {
+		return userErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *size <= 0 {
+		return userErrorf("invalid -size: %d", *size)
+	}
+
+	errorLog := log.New(stderr, "", log.LstdFlags)
+	handler := httpserver.NewServer(newMarker(), httpserver.Options{
+		MaxAge:      *maxAge,
+		DefaultSize: *size,
+		ErrorLog:    errorLog,
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           http.TimeoutHandler(handler, *timeout, "render timed out"),
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          errorLog,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(stdout, "serving icons on %s\n", *addr)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
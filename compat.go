@@ -35,6 +35,12 @@ func SaveImage2File(img image.Image, path string, encoder func(io.Writer, image.
 	return core.SaveImage2File(img, path, encoder)
 }
 
+// SaveImage encodes image with opts and saves it to file, inferring the
+// format from the file extension when opts.Format is empty
+func SaveImage(img image.Image, path string, opts core.EncodeOptions) error {
+	return core.SaveImage(img, path, opts)
+}
+
 // DrawCenteredFont draws text on image with center alignment (兼容旧API)
 func DrawCenteredFont(f *truetype.Font, outI *image.RGBA, opt DrawTextOption) error {
 	return core.DrawCenteredFont(f, outI, opt)
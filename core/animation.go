@@ -0,0 +1,464 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 动画的默认值和上限
+const (
+	DefaultAnimationFPS = 25
+	MaxAnimationFPS     = 50  // GIF 的帧延迟以 1/100 秒为单位，浏览器会把更短的延迟放慢
+	MaxAnimationFrames  = 500 // 单个动画的最大帧数，避免一次请求渲染过多帧
+)
+
+// Easing 是关键帧之间的缓动函数
+type Easing string
+
+// 支持的缓动函数
+const (
+	EaseLinear Easing = "linear"      // 匀速，空值等同于 EaseLinear
+	EaseIn     Easing = "ease-in"     // 由慢到快
+	EaseOut    Easing = "ease-out"    // 由快到慢
+	EaseInOut  Easing = "ease-in-out" // 两端慢、中间快
+	EaseStep   Easing = "step"        // 保持上一关键帧的值，到达本关键帧时跳变
+)
+
+// 图层变换属性，见 AnimationTrack
+const (
+	animPropX        = "x"
+	animPropY        = "y"
+	animPropOpacity  = "opacity"
+	animPropScale    = "scale"
+	animPropRotation = "rotation"
+)
+
+// AnimationSpec 描述由关键帧驱动的图标动画
+// 每一帧由 Spec 按该时刻各轨道的取值修改后，经过与 RenderSpec 相同的流程渲染
+type AnimationSpec struct {
+	Spec       IconSpec         `json:"spec"`            // 基础文档
+	DurationMS int              `json:"duration_ms"`     // 一次播放的时长，单位毫秒
+	FPS        int              `json:"fps,omitempty"`   // 帧率，为 0 时使用 DefaultAnimationFPS
+	Loops      int              `json:"loops,omitempty"` // 播放次数，0 表示无限循环
+	Tracks     []AnimationTrack `json:"tracks,omitempty"`
+}
+
+// AnimationTrack 是一个属性的关键帧序列
+//
+// Property 取值：
+//   - x、y：图层位移，单位像素
+//   - scale：以图层中心为基准的缩放倍数，1 为原始大小，文字和矢量内容按缩放后的尺寸重新渲染
+//   - rotation：以图层中心为基准顺时针旋转的角度
+//   - opacity：图层不透明度，覆盖图层的 opacity
+//   - effects[i].<字段>：图层第 i 个效果滤镜选项中的数值字段，例如 effects[0].intensity
+//   - filters[i].<字段>：顶层第 i 个滤镜选项中的数值字段，此时 Layer 为 nil
+type AnimationTrack struct {
+	Layer     *int       `json:"layer,omitempty"` // 图层下标
+	Property  string     `json:"property"`
+	Keyframes []Keyframe `json:"keyframes"`
+}
+
+// Keyframe 是轨道在某一时刻的取值
+// 早于第一个关键帧时取第一个值，晚于最后一个关键帧时保持最后的值
+type Keyframe struct {
+	TimeMS int     `json:"time_ms"`
+	Value  float64 `json:"value"`
+	Easing Easing  `json:"easing,omitempty"` // 从上一关键帧过渡到本关键帧使用的缓动函数
+}
+
+// AnimationFrame 是渲染完成的一帧
+type AnimationFrame struct {
+	Image *image.RGBA
+	Delay time.Duration // 该帧的显示时长
+}
+
+// filterPropertyRe 匹配滤镜参数属性
+var filterPropertyRe = regexp.MustCompile(`^(effects|filters)\[(\d+)\]\.([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// animationSpecWire 用于反序列化 AnimationSpec
+type animationSpecWire struct {
+	Spec       json.RawMessage  `json:"spec"`
+	DurationMS int              `json:"duration_ms"`
+	FPS        int              `json:"fps,omitempty"`
+	Loops      int              `json:"loops,omitempty"`
+	Tracks     []AnimationTrack `json:"tracks,omitempty"`
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，拒绝未知字段，出错时返回带 JSON 路径的 *SpecError
+func (a *AnimationSpec) UnmarshalJSON(data []byte) error {
+	var in animationSpecWire
+	if err := decodeStrict(data, &in, "$"); err != nil {
+		return err
+	}
+
+	out := AnimationSpec{DurationMS: in.DurationMS, FPS: in.FPS, Loops: in.Loops, Tracks: in.Tracks}
+	if len(in.Spec) == 0 || isJSONNull(in.Spec) {
+		return &SpecError{Path: "$.spec", Err: errors.New("spec is required")}
+	}
+	if err := out.Spec.UnmarshalJSON(in.Spec); err != nil {
+		return rebaseSpecError(err, "$.spec")
+	}
+	*a = out
+	return nil
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler，YAML 转换为 JSON 后按 JSON 规则解析
+func (a *AnimationSpec) UnmarshalYAML(value *yaml.Node) error {
+	var doc any
+	if err := value.Decode(&doc); err != nil {
+		return &SpecError{Path: "$", Err: err}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return &SpecError{Path: "$", Err: fmt.Errorf("YAML is not representable as JSON: %w", err)}
+	}
+	return a.UnmarshalJSON(data)
+}
+
+// ParseAnimationSpec 解析 JSON 或 YAML 格式的 AnimationSpec 并做静态校验
+// 格式判断与 ParseIconSpec 相同
+func ParseAnimationSpec(data []byte) (*AnimationSpec, error) {
+	anim := new(AnimationSpec)
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = anim.UnmarshalJSON(trimmed)
+	} else if err = yaml.Unmarshal(data, anim); err != nil {
+		if _, ok := err.(*SpecError); !ok {
+			err = &SpecError{Path: "$", Err: err}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = anim.Validate(); err != nil {
+		return nil, err
+	}
+	return anim, nil
+}
+
+// Validate 静态校验动画及其基础文档，返回包含所有问题的 SpecErrors
+func (a *AnimationSpec) Validate() error {
+	var errs SpecErrors
+	add := func(path, format string, args ...any) {
+		errs = append(errs, &SpecError{Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	if err := a.Spec.Validate(); err != nil {
+		for _, se := range err.(SpecErrors) {
+			errs = append(errs, rebaseSpecError(se, "$.spec").(*SpecError))
+		}
+	}
+	if a.DurationMS <= 0 {
+		add("$.duration_ms", "duration must be positive, got %d", a.DurationMS)
+	}
+	if a.FPS < 0 || a.FPS > MaxAnimationFPS {
+		add("$.fps", "fps %d out of range [1, %d]", a.FPS, MaxAnimationFPS)
+	} else if a.DurationMS > 0 {
+		if n := a.frameCount(); n > MaxAnimationFrames {
+			add("$", "animation has %d frames, at most %d are allowed", n, MaxAnimationFrames)
+		}
+	}
+	if a.Loops < 0 {
+		add("$.loops", "loops must not be negative, got %d", a.Loops)
+	}
+
+	for i, track := range a.Tracks {
+		errs = append(errs, a.validateTrack(track, fmt.Sprintf("$.tracks[%d]", i))...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateTrack 静态校验一个轨道
+func (a *AnimationSpec) validateTrack(t AnimationTrack, path string) SpecErrors {
+	var errs SpecErrors
+	add := func(field, format string, args ...any) {
+		errs = append(errs, &SpecError{Path: joinPath(path, field), Err: fmt.Errorf(format, args...)})
+	}
+
+	if t.Layer != nil && (*t.Layer < 0 || *t.Layer >= len(a.Spec.Layers)) {
+		add("layer", "layer %d out of range, spec has %d layers", *t.Layer, len(a.Spec.Layers))
+	}
+
+	minValue, maxValue := math.Inf(-1), math.Inf(1)
+	switch t.Property {
+	case animPropX, animPropY, animPropRotation:
+	case animPropOpacity:
+		minValue, maxValue = 0, 1
+	case animPropScale:
+		minValue = 0
+	default:
+		m := filterPropertyRe.FindStringSubmatch(t.Property)
+		if m == nil {
+			add("property", "unknown property %q", t.Property)
+			break
+		}
+		index, _ := strconv.Atoi(m[2])
+		if m[1] == "filters" {
+			if t.Layer != nil {
+				add("property", "%s animates a top-level filter and cannot target a layer", t.Property)
+			} else if index >= len(a.Spec.Filters) {
+				add("property", "filter %d out of range, spec has %d filters", index, len(a.Spec.Filters))
+			}
+		} else if t.Layer != nil && *t.Layer >= 0 && *t.Layer < len(a.Spec.Layers) {
+			if effects := a.Spec.Layers[*t.Layer].Effects; index >= len(effects) {
+				add("property", "effect %d out of range, layer has %d effects", index, len(effects))
+			}
+		}
+	}
+	if t.Layer == nil && !strings.HasPrefix(t.Property, "filters[") {
+		add("layer", "property %q requires a layer", t.Property)
+	}
+
+	if len(t.Keyframes) == 0 {
+		add("keyframes", "track requires at least one keyframe")
+	}
+	for i, k := range t.Keyframes {
+		at := fmt.Sprintf("keyframes[%d]", i)
+		if k.TimeMS < 0 || k.TimeMS > a.DurationMS {
+			add(at+".time_ms", "time %d out of range [0, %d]", k.TimeMS, a.DurationMS)
+		}
+		if k.Value < minValue || k.Value > maxValue {
+			add(at+".value", "%s value %g out of range [%g, %g]", t.Property, k.Value, minValue, maxValue)
+		}
+		switch k.Easing {
+		case "", EaseLinear, EaseIn, EaseOut, EaseInOut, EaseStep:
+		default:
+			add(at+".easing", "unknown easing %q", k.Easing)
+		}
+	}
+	return errs
+}
+
+// ValidateAnimation 完整校验动画
+// 在静态校验之外按第一帧解析滤镜，检查滤镜参数轨道指向的选项能否被解码
+func (im *IconMarker) ValidateAnimation(anim *AnimationSpec) error {
+	if err := anim.Validate(); err != nil {
+		return err
+	}
+	spec, _, err := anim.frameSpec(0)
+	if err != nil {
+		return err
+	}
+	_, _, err = im.resolveSpec(spec)
+	return err
+}
+
+// RenderAnimation 逐帧渲染动画，帧之间检查 ctx
+// 完全相同的相邻帧在编码时合并，这里按帧率原样返回
+func (im *IconMarker) RenderAnimation(ctx context.Context, anim *AnimationSpec) ([]AnimationFrame, error) {
+	if err := anim.Validate(); err != nil {
+		return nil, err
+	}
+
+	n := anim.frameCount()
+	frames := make([]AnimationFrame, n)
+	for i := range frames {
+		if err := checkCtx(ctx, "frame %d of %d", i, n); err != nil {
+			return nil, err
+		}
+		start, end := anim.frameTime(i), anim.frameTime(i+1)
+		spec, rotations, err := anim.frameSpec(float64(start))
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		img, err := im.renderSpec(ctx, spec, rotations)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		frames[i] = AnimationFrame{Image: img, Delay: time.Duration(end-start) * time.Millisecond}
+	}
+	return frames, nil
+}
+
+// fps 返回生效的帧率
+func (a *AnimationSpec) fps() int {
+	if a.FPS == 0 {
+		return DefaultAnimationFPS
+	}
+	return a.FPS
+}
+
+// frameCount 返回一次播放的帧数
+func (a *AnimationSpec) frameCount() int {
+	return max(int(math.Ceil(float64(a.DurationMS)*float64(a.fps())/1000)), 1)
+}
+
+// frameTime 返回第 i 帧的开始时刻，单位毫秒
+// 取整到毫秒并以累计时刻计算，帧延迟的舍入误差不会累积
+func (a *AnimationSpec) frameTime(i int) int {
+	return min(int(math.Round(float64(i)*1000/float64(a.fps()))), a.DurationMS)
+}
+
+// layerAnimation 是图层在某一时刻的变换
+type layerAnimation struct {
+	dx, dy, scale, rotation float64
+	opacity                 *float64
+}
+
+// frameSpec 返回时刻 t 的文档以及各图层的旋转角度
+func (a *AnimationSpec) frameSpec(t float64) (*IconSpec, []float64, error) {
+	spec := a.Spec
+	spec.Layers = make([]LayerSpec, 0, len(a.Spec.Layers))
+	spec.Filters = append([]FilterSpec(nil), a.Spec.Filters...)
+
+	anims := make([]layerAnimation, len(a.Spec.Layers))
+	for i := range anims {
+		anims[i].scale = 1
+	}
+	effects := make([][]FilterSpec, len(a.Spec.Layers))
+	for i, layer := range a.Spec.Layers {
+		effects[i] = append([]FilterSpec(nil), layer.Effects...)
+	}
+
+	for i, track := range a.Tracks {
+		v := track.valueAt(t)
+		if m := filterPropertyRe.FindStringSubmatch(track.Property); m != nil {
+			index, _ := strconv.Atoi(m[2])
+			chain := spec.Filters
+			if track.Layer != nil {
+				chain = effects[*track.Layer]
+			}
+			f, err := setFilterParam(chain[index], m[3], v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("tracks[%d]: %w", i, err)
+			}
+			chain[index] = f
+			continue
+		}
+
+		la := &anims[*track.Layer]
+		switch track.Property {
+		case animPropX:
+			la.dx = v
+		case animPropY:
+			la.dy = v
+		case animPropScale:
+			la.scale = v
+		case animPropRotation:
+			la.rotation = v
+		case animPropOpacity:
+			la.opacity = &v
+		}
+	}
+
+	canvas := image.Rect(0, 0, a.Spec.Canvas.Width, a.Spec.Canvas.Height)
+	var rotations []float64
+	for i, layer := range a.Spec.Layers {
+		la := anims[i]
+		layer.Effects = effects[i]
+		if la.opacity != nil {
+			layer.Opacity = la.opacity
+		}
+
+		if la.dx != 0 || la.dy != 0 || la.scale != 1 {
+			// 以图层中心为基准缩放，图层区域先按画布补全宽高
+			box := layer.box(canvas)
+			width := int(math.Round(float64(box.Dx()) * la.scale))
+			height := int(math.Round(float64(box.Dy()) * la.scale))
+			if width <= 0 || height <= 0 {
+				// 缩放到 0 的图层在这一帧不可见
+				continue
+			}
+			layer.X = box.Min.X + (box.Dx()-width)/2 + int(math.Round(la.dx))
+			layer.Y = box.Min.Y + (box.Dy()-height)/2 + int(math.Round(la.dy))
+			layer.Width, layer.Height = width, height
+			if layer.Text != nil && la.scale != 1 {
+				text := layer.Text.scaled(la.scale, la.scale)
+				layer.Text = &text
+			}
+		}
+		spec.Layers = append(spec.Layers, layer)
+		rotations = append(rotations, la.rotation)
+	}
+	return &spec, rotations, nil
+}
+
+// valueAt 返回轨道在时刻 t 的取值
+func (t AnimationTrack) valueAt(at float64) float64 {
+	keys := append([]Keyframe(nil), t.Keyframes...)
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].TimeMS < keys[j].TimeMS })
+
+	if at <= float64(keys[0].TimeMS) {
+		return keys[0].Value
+	}
+	for i := 1; i < len(keys); i++ {
+		prev, next := keys[i-1], keys[i]
+		if at >= float64(next.TimeMS) {
+			continue
+		}
+		f := (at - float64(prev.TimeMS)) / float64(next.TimeMS-prev.TimeMS)
+		return prev.Value + (next.Value-prev.Value)*next.Easing.apply(f)
+	}
+	return keys[len(keys)-1].Value
+}
+
+// apply 将 [0, 1) 内的线性进度映射为缓动后的进度
+func (e Easing) apply(f float64) float64 {
+	switch e {
+	case EaseIn:
+		return f * f * f
+	case EaseOut:
+		return 1 - math.Pow(1-f, 3)
+	case EaseInOut:
+		if f < 0.5 {
+			return 4 * f * f * f
+		}
+		return 1 - math.Pow(-2*f+2, 3)/2
+	case EaseStep:
+		return 0
+	default:
+		return f
+	}
+}
+
+// setFilterParam 返回将选项中数值字段 field 设为 v 的滤镜副本
+// 在代码中直接设置的 Option 先序列化为 JSON，修改后由注册的解码器重新解析
+func setFilterParam(f FilterSpec, field string, v float64) (FilterSpec, error) {
+	raw := []byte(f.Options)
+	if f.Option != nil {
+		var err error
+		if raw, err = json.Marshal(f.Option); err != nil {
+			return FilterSpec{}, fmt.Errorf("filter %q options: %w", f.Name, err)
+		}
+	}
+
+	options := make(map[string]any)
+	if len(raw) > 0 && !isJSONNull(raw) {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return FilterSpec{}, fmt.Errorf("filter %q options are not an object: %w", f.Name, err)
+		}
+	}
+	options[field] = v
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		return FilterSpec{}, err
+	}
+	return FilterSpec{Name: f.Name, Options: data}, nil
+}
+
+// rebaseSpecError 将以 $ 开头的错误路径改为以 base 开头，用于嵌套的 IconSpec
+func rebaseSpecError(err error, base string) error {
+	var se *SpecError
+	if !errors.As(err, &se) {
+		return &SpecError{Path: base, Err: err}
+	}
+	return &SpecError{Path: base + strings.TrimPrefix(se.Path, "$"), Err: se.Err}
+}
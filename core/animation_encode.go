@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// AnimationEncodeOptions 配置动画编码
+type AnimationEncodeOptions struct {
+	Loops     int  // 播放次数，0 表示无限循环
+	GIFColors int  // GIF 全局调色板的最大颜色数 2-256，为 0 时使用 DefaultGIFColors
+	GIFDither bool // GIF 量化是否使用 Floyd-Steinberg 抖动
+}
+
+// animationSegment 是编码输出的一帧：来源帧中与上一帧不同的区域及其显示时间段
+// 与上一帧完全相同的帧不单独输出，而是延长上一段的显示时间
+type animationSegment struct {
+	frame      int
+	rect       image.Rectangle
+	start, end time.Duration
+}
+
+// EncodeAnimatedGIF 将帧序列编码为动画 GIF
+//
+// 所有帧共享一个由中位切分生成的全局调色板；第一帧之后的每帧只写入与上一帧不同的
+// 最小矩形。GIF 的透明像素无法覆盖已绘制的像素，出现不透明像素变为透明时，
+// 改为整帧写入并在显示后清除
+func EncodeAnimatedGIF(w io.Writer, frames []AnimationFrame, opts AnimationEncodeOptions) error {
+	bounds, err := checkFrames(frames)
+	if err != nil {
+		return err
+	}
+	colors := opts.GIFColors
+	if colors == 0 {
+		colors = DefaultGIFColors
+	}
+	if colors < 2 || colors > 256 {
+		return fmt.Errorf("invalid GIF color count %d, expected 2-256", colors)
+	}
+
+	srcs := make([]*image.NRGBA, len(frames))
+	for i, f := range frames {
+		srcs[i] = binarizeAlpha(f.Image)
+	}
+	palette := buildPalette(srcs, colors)
+	paletted := make([]*image.Paletted, len(frames))
+	for i, src := range srcs {
+		paletted[i] = mapToPalette(src, palette, opts.GIFDither)
+	}
+
+	// buildPalette 只在存在透明像素时把透明色放在首项
+	clears := false
+	if palette[0] == (color.NRGBA{}) {
+		for i := 1; i < len(paletted) && !clears; i++ {
+			for j, idx := range paletted[i].Pix {
+				if idx == 0 && paletted[i-1].Pix[j] != 0 {
+					clears = true
+					break
+				}
+			}
+		}
+	}
+
+	segments := splitSegments(frames, bounds, func(i int) image.Rectangle {
+		rect := diffRect(bounds, paletted[i-1].Pix, paletted[i].Pix, 1)
+		if clears && !rect.Empty() {
+			return bounds
+		}
+		return rect
+	})
+
+	g := &gif.GIF{
+		Config:    image.Config{ColorModel: palette, Width: bounds.Dx(), Height: bounds.Dy()},
+		LoopCount: gifLoopCount(opts.Loops),
+	}
+	disposal := byte(gif.DisposalNone)
+	if clears {
+		disposal = gif.DisposalBackground
+	}
+	for _, seg := range segments {
+		// 延迟以 1/100 秒为单位，按累计时刻取整，误差不会累积
+		delay := int(seg.end.Round(10*time.Millisecond)/(10*time.Millisecond)) -
+			int(seg.start.Round(10*time.Millisecond)/(10*time.Millisecond))
+		g.Image = append(g.Image, paletted[seg.frame].SubImage(seg.rect).(*image.Paletted))
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// gifLoopCount 将播放次数转换为 gif.GIF.LoopCount：0 无限循环，-1 只播放一次，n 额外重复 n 次
+func gifLoopCount(loops int) int {
+	switch loops {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	default:
+		return loops - 1
+	}
+}
+
+// checkFrames 检查帧序列非空且大小一致，返回帧的区域
+func checkFrames(frames []AnimationFrame) (image.Rectangle, error) {
+	if len(frames) == 0 {
+		return image.Rectangle{}, fmt.Errorf("animation requires at least one frame")
+	}
+	bounds := frames[0].Image.Bounds()
+	if bounds.Min != (image.Point{}) || bounds.Empty() {
+		return image.Rectangle{}, fmt.Errorf("invalid frame bounds %v", bounds)
+	}
+	for i, f := range frames {
+		if f.Image.Bounds() != bounds {
+			return image.Rectangle{}, fmt.Errorf("frame %d bounds %v differ from %v", i, f.Image.Bounds(), bounds)
+		}
+		if f.Delay < 0 {
+			return image.Rectangle{}, fmt.Errorf("frame %d has negative delay %v", i, f.Delay)
+		}
+	}
+	return bounds, nil
+}
+
+// splitSegments 将帧序列划分为输出段，diff 返回第 i 帧相对于第 i-1 帧变化的区域
+func splitSegments(frames []AnimationFrame, bounds image.Rectangle, diff func(i int) image.Rectangle) []animationSegment {
+	segments := []animationSegment{{frame: 0, rect: bounds, end: frames[0].Delay}}
+	for i := 1; i < len(frames); i++ {
+		last := &segments[len(segments)-1]
+		rect := diff(i)
+		if rect.Empty() {
+			last.end += frames[i].Delay
+			continue
+		}
+		segments = append(segments, animationSegment{frame: i, rect: rect, start: last.end, end: last.end + frames[i].Delay})
+	}
+	return segments
+}
+
+// diffRect 返回两帧像素不同的最小矩形，bpp 为每像素字节数，帧左上角位于原点
+func diffRect(bounds image.Rectangle, prev, cur []byte, bpp int) image.Rectangle {
+	stride := bounds.Dx() * bpp
+	rect := image.Rectangle{}
+	for y := 0; y < bounds.Dy(); y++ {
+		row := y * stride
+		if bytes.Equal(prev[row:row+stride], cur[row:row+stride]) {
+			continue
+		}
+		x0, x1 := 0, bounds.Dx()
+		for x0 < x1 && bytes.Equal(prev[row+x0*bpp:row+(x0+1)*bpp], cur[row+x0*bpp:row+(x0+1)*bpp]) {
+			x0++
+		}
+		for x1 > x0 && bytes.Equal(prev[row+(x1-1)*bpp:row+x1*bpp], cur[row+(x1-1)*bpp:row+x1*bpp]) {
+			x1--
+		}
+		rect = rect.Union(image.Rect(x0, y, x1, y+1))
+	}
+	return rect
+}
+
+// WriteAnimatedGIF 渲染动画并写为动画 GIF，播放次数取自 anim.Loops，忽略 opts.Loops
+func (im *IconMarker) WriteAnimatedGIF(ctx context.Context, w io.Writer, anim *AnimationSpec, opts AnimationEncodeOptions) error {
+	frames, err := im.RenderAnimation(ctx, anim)
+	if err != nil {
+		return err
+	}
+	opts.Loops = anim.Loops
+	return EncodeAnimatedGIF(w, frames, opts)
+}
+
+// WriteAPNG 渲染动画并写为 APNG，播放次数取自 anim.Loops
+func (im *IconMarker) WriteAPNG(ctx context.Context, w io.Writer, anim *AnimationSpec) error {
+	frames, err := im.RenderAnimation(ctx, anim)
+	if err != nil {
+		return err
+	}
+	return EncodeAPNG(w, frames, AnimationEncodeOptions{Loops: anim.Loops})
+}
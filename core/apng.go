@@ -0,0 +1,240 @@
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// pngSignature 是 PNG 文件头
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// PNG 颜色类型，位深均为 8
+const (
+	pngColorRGB  = 2
+	pngColorRGBA = 6
+)
+
+// APNG 帧控制块的处理方式
+const (
+	apngDisposeNone = 0 // 显示后保留帧内容
+	apngBlendSource = 0 // 用帧内容直接替换区域，包括透明像素
+)
+
+// EncodeAPNG 将帧序列编码为 APNG，不支持 APNG 的查看器显示第一帧
+//
+// 第一帧之后的每帧只写入与上一帧不同的最小矩形，并以替换方式合成，
+// 透明像素同样能覆盖上一帧；所有帧都不透明时使用 RGB 颜色类型
+func EncodeAPNG(w io.Writer, frames []AnimationFrame, opts AnimationEncodeOptions) error {
+	bounds, err := checkFrames(frames)
+	if err != nil {
+		return err
+	}
+
+	srcs := make([]*image.NRGBA, len(frames))
+	colorType := pngColorRGB
+	for i, f := range frames {
+		srcs[i] = image.NewNRGBA(bounds)
+		draw.Draw(srcs[i], bounds, f.Image, bounds.Min, draw.Src)
+		if !srcs[i].Opaque() {
+			colorType = pngColorRGBA
+		}
+	}
+	segments := splitSegments(frames, bounds, func(i int) image.Rectangle {
+		return diffRect(bounds, srcs[i-1].Pix, srcs[i].Pix, 4)
+	})
+
+	pw := &pngWriter{w: w}
+	pw.write(pngSignature)
+
+	var ihdr [13]byte
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(bounds.Dy()))
+	ihdr[8], ihdr[9] = 8, byte(colorType)
+	pw.chunk("IHDR", ihdr[:])
+
+	var actl [8]byte
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(segments)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(opts.Loops))
+	pw.chunk("acTL", actl[:])
+
+	// fcTL 和 fdAT 共用一个从 0 开始的序列号
+	var seq uint32
+	for i, seg := range segments {
+		num, den := apngDelay(seg.end.Round(time.Millisecond) - seg.start.Round(time.Millisecond))
+		var fctl [26]byte
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(seg.rect.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(seg.rect.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(seg.rect.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(seg.rect.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:], num)
+		binary.BigEndian.PutUint16(fctl[22:], den)
+		fctl[24], fctl[25] = apngDisposeNone, apngBlendSource
+		pw.chunk("fcTL", fctl[:])
+		seq++
+
+		data, err := pngImageData(srcs[seg.frame], seg.rect, colorType)
+		if err != nil {
+			return err
+		}
+		// 第一帧同时是默认图像，写入 IDAT
+		if i == 0 {
+			pw.chunk("IDAT", data)
+			continue
+		}
+		fdat := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, seq)
+		copy(fdat[4:], data)
+		pw.chunk("fdAT", fdat)
+		seq++
+	}
+
+	pw.chunk("IEND", nil)
+	return pw.err
+}
+
+// apngDelay 将显示时长转换为分数形式的帧延迟，超出毫秒精度的范围时改用 1/100 秒
+func apngDelay(d time.Duration) (num, den uint16) {
+	ms := d.Milliseconds()
+	if ms <= 0xffff {
+		return uint16(ms), 1000
+	}
+	return uint16(min(d.Round(10*time.Millisecond).Milliseconds()/10, 0xffff)), 100
+}
+
+// pngWriter 写出 PNG 数据块，记录第一个写入错误
+type pngWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *pngWriter) write(b []byte) {
+	if pw.err == nil {
+		_, pw.err = pw.w.Write(b)
+	}
+}
+
+// chunk 写出一个数据块：长度、类型、数据以及类型和数据的 CRC
+func (pw *pngWriter) chunk(typ string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	pw.write(header[:])
+	pw.write(data)
+	pw.write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
+
+// pngImageData 返回 r 区域的 zlib 压缩图像数据
+// 每行从五种 PNG 滤波方式中选择绝对值之和最小的一种，与 image/png 的策略相同
+func pngImageData(img *image.NRGBA, r image.Rectangle, colorType int) ([]byte, error) {
+	bpp := 4
+	if colorType == pngColorRGB {
+		bpp = 3
+	}
+	rowLen := r.Dx() * bpp
+	prev, cur := make([]byte, rowLen), make([]byte, rowLen)
+	var filtered [5][]byte
+	for i := range filtered {
+		filtered[i] = make([]byte, rowLen)
+	}
+
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		pix := img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)]
+		if bpp == 4 {
+			copy(cur, pix)
+		} else {
+			for x, j := 0, 0; x < len(pix); x, j = x+4, j+3 {
+				copy(cur[j:j+3], pix[x:x+3])
+			}
+		}
+
+		best, bestSum := 0, -1
+		for ft := range filtered {
+			sum := filterRow(filtered[ft], ft, cur, prev, bpp)
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = ft, sum
+			}
+		}
+		if _, err = zw.Write([]byte{byte(best)}); err != nil {
+			return nil, err
+		}
+		if _, err = zw.Write(filtered[best]); err != nil {
+			return nil, err
+		}
+		prev, cur = cur, prev
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// filterRow 按 PNG 滤波方式 ft 滤波一行写入 dst，返回结果按有符号字节计算的绝对值之和
+func filterRow(dst []byte, ft int, cur, prev []byte, bpp int) int {
+	sum := 0
+	for i, x := range cur {
+		var a, b, c byte
+		if i >= bpp {
+			a, c = cur[i-bpp], prev[i-bpp]
+		}
+		b = prev[i]
+
+		var v byte
+		switch ft {
+		case 0:
+			v = x
+		case 1:
+			v = x - a
+		case 2:
+			v = x - b
+		case 3:
+			v = x - byte((int(a)+int(b))/2)
+		case 4:
+			v = x - paeth(a, b, c)
+		}
+		dst[i] = v
+		if s := int(int8(v)); s < 0 {
+			sum -= s
+		} else {
+			sum += s
+		}
+	}
+	return sum
+}
+
+// paeth 是 PNG 的 Paeth 预测函数
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+// abs 返回整数的绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultAvatarPalette 是头像背景的默认调色板
+var DefaultAvatarPalette = []color.RGBA{
+	{R: 239, G: 68, B: 68, A: 255},   // red
+	{R: 249, G: 115, B: 22, A: 255},  // orange
+	{R: 234, G: 179, B: 8, A: 255},   // amber
+	{R: 34, G: 197, B: 94, A: 255},   // green
+	{R: 20, G: 184, B: 166, A: 255},  // teal
+	{R: 6, G: 182, B: 212, A: 255},   // cyan
+	{R: 59, G: 130, B: 246, A: 255},  // blue
+	{R: 99, G: 102, B: 241, A: 255},  // indigo
+	{R: 168, G: 85, B: 247, A: 255},  // purple
+	{R: 236, G: 72, B: 153, A: 255},  // pink
+	{R: 100, G: 116, B: 139, A: 255}, // slate
+	{R: 30, G: 41, B: 59, A: 255},    // navy
+}
+
+// AvatarStyle 描述首字母头像的样式
+type AvatarStyle struct {
+	Palette   []color.RGBA // 背景调色板，为空时使用 DefaultAvatarPalette
+	TextColor color.Color  // 文字颜色，为 nil 时根据背景自动选择黑色或白色
+	FontBytes []byte       // 字体数据，为空时使用内嵌默认字体
+	TextScale float64      // 文字高度占边长的比例，为 0 时使用 0.45
+	Circle    bool         // 是否裁剪为圆形
+}
+
+// defaultAvatarMarker 是包级 InitialsAvatar 使用的 IconMarker，首次使用时创建
+var defaultAvatarMarker = sync.OnceValue(NewIconMarker)
+
+// InitialsAvatar 根据名称生成确定性的首字母头像
+// 首字母由 Initials 提取，背景色由名称的哈希从调色板中选取，
+// 因此同一名称总是得到同一头像；字体经由共享的默认 IconMarker 的字体缓存加载
+func InitialsAvatar(name string, size int, style AvatarStyle) (*image.RGBA, error) {
+	return defaultAvatarMarker().InitialsAvatar(name, size, style)
+}
+
+// InitialsAvatar 根据名称生成确定性的首字母头像，字体经由字体缓存加载，
+// 输出尺寸和字体大小受资源限制约束
+func (im *IconMarker) InitialsAvatar(name string, size int, style AvatarStyle) (*image.RGBA, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid avatar size: %d", size)
+	}
+	if err := im.Limits().CheckOutputSize(size, size); err != nil {
+		return nil, err
+	}
+	if err := im.Limits().CheckInputBytes("font", len(style.FontBytes)); err != nil {
+		return nil, err
+	}
+
+	initials := Initials(name)
+	if initials == "" {
+		return nil, fmt.Errorf("no initials in name: %q", name)
+	}
+
+	// 字体字节数组为空时加载默认字体
+	font, err := im.textRenderer.LoadFont(style.FontBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w, error parsing font file", err)
+	}
+
+	bg := AvatarColor(name, style.Palette)
+	textColor := style.TextColor
+	if textColor == nil {
+		textColor = ContrastColor(bg)
+	}
+	textScale := style.TextScale
+	if textScale <= 0 {
+		textScale = 0.45
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	opt := DrawTextOption{
+		FontColor: textColor,
+		Text:      initials,
+	}.SetAdaptedSize(size*7/10, int(float64(size)*textScale))
+	if err = DrawCenteredFont(font, img, opt); err != nil {
+		return nil, fmt.Errorf("%w, error drawing initials", err)
+	}
+
+	if style.Circle {
+		ApplyCircleMask(img)
+	}
+	return img, nil
+}
+
+// Initials 从名称中提取首字母
+// 以中日韩文字开头时取第一个字，否则取前两个单词的首字母并转为大写
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	first := []rune(words[0])
+	if IsCJK(first[0]) {
+		return string(first[0])
+	}
+
+	initials := []rune{first[0]}
+	if len(words) > 1 {
+		if second := []rune(words[1]); !IsCJK(second[0]) {
+			initials = append(initials, second[0])
+		}
+	}
+	return strings.ToUpper(string(initials))
+}
+
+// AvatarColor 根据名称的哈希从调色板中确定性地选取颜色
+// palette 为空时使用 DefaultAvatarPalette
+func AvatarColor(name string, palette []color.RGBA) color.RGBA {
+	if len(palette) == 0 {
+		palette = DefaultAvatarPalette
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.TrimSpace(name)))
+	return palette[h.Sum32()%uint32(len(palette))]
+}
+
+// ContrastColor 返回与背景对比度更高的文字颜色（黑色或白色）
+// 对比度按 WCAG 相对亮度计算
+func ContrastColor(bg color.Color) color.RGBA {
+	r, g, b, _ := bg.RGBA()
+	lum := 0.2126*linearize(r) + 0.7152*linearize(g) + 0.0722*linearize(b)
+
+	contrastWhite := 1.05 / (lum + 0.05)
+	contrastBlack := (lum + 0.05) / 0.05
+	if contrastWhite >= contrastBlack {
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	return color.RGBA{A: 255}
+}
+
+// linearize 将 16 位 sRGB 分量转换为线性亮度
+func linearize(c uint32) float64 {
+	v := float64(c) / 0xffff
+	if v <= 0.03928 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// ApplyCircleMask 将图像裁剪为内切圆，边缘做抗锯齿处理
+func ApplyCircleMask(img *image.RGBA) {
+	bounds := img.Bounds()
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	cx, cy := w/2, h/2
+	radius := math.Min(w, h) / 2
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			dx := float64(x-bounds.Min.X) + 0.5 - cx
+			dy := float64(y-bounds.Min.Y) + 0.5 - cy
+			coverage := radius - math.Sqrt(dx*dx+dy*dy) + 0.5
+			if coverage >= 1 {
+				continue
+			}
+			if coverage < 0 {
+				coverage = 0
+			}
+
+			// RGBA 为预乘格式，所有通道同比缩放
+			i := img.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				img.Pix[i+c] = uint8(float64(img.Pix[i+c]) * coverage)
+			}
+		}
+	}
+}
+
+// IsCJK 判断字符是否为中日韩文字
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册 GIF 解码器，供 decodeImage 使用
+	"image/jpeg"
+	_ "image/png" // 注册 PNG 解码器，供 decodeImage 使用
+
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+// decodeBackground 在资源限制内解码背景图片
+// 先检查输入大小，再通过 DecodeConfig 检查像素尺寸，通过后才完整解码
+// 输出尺寸由调用方在确定是否缩放后检查
+//
+// data 也可以是 background.Marshal 序列化的背景描述，此时按描述生成背景
+func (im *IconMarker) decodeBackground(ctx context.Context, data []byte) (image.Image, error) {
+	return decodeBackgroundData(ctx, data, im.Limits())
+}
+
+// decodeBackgroundData 按给定的资源限制解码 JPEG 背景或生成程序化背景
+func decodeBackgroundData(ctx context.Context, data []byte, lim limits.Limits) (image.Image, error) {
+	if err := lim.CheckInputBytes("background", len(data)); err != nil {
+		return nil, err
+	}
+
+	if background.IsSpec(data) {
+		spec, err := background.Unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w, error parsing background spec", err)
+		}
+		if err = lim.CheckImageSize("background", spec.Width, spec.Height); err != nil {
+			return nil, err
+		}
+		return background.GenerateCtx(ctx, spec)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w, error decoding background", err)
+	}
+	if err = lim.CheckImageSize("background", cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w, error decoding background", err)
+	}
+	return img, nil
+}
+
+// decodeImage 在资源限制内解码 JPEG、PNG 或 GIF 图片，subject 用于错误信息
+func (im *IconMarker) decodeImage(subject string, data []byte) (image.Image, error) {
+	lim := im.Limits()
+	if err := lim.CheckInputBytes(subject, len(data)); err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w, error decoding %s", err, subject)
+	}
+	if err = lim.CheckImageSize(subject, cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w, error decoding %s", err, subject)
+	}
+	return img, nil
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+// hugeJPEG returns a JPEG header claiming 60000x60000 pixels, cut off right
+// after the frame header: DecodeConfig succeeds, a full decode fails
+func hugeJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	sof := bytes.Index(data, []byte{0xff, 0xc0})
+	if sof < 0 {
+		t.Fatal("no SOF0 marker")
+	}
+	binary.BigEndian.PutUint16(data[sof+5:], 60000) // height
+	binary.BigEndian.PutUint16(data[sof+7:], 60000) // width
+	header := data[2 : sof+2+int(binary.BigEndian.Uint16(data[sof+2:]))]
+
+	// DecodeConfig stops at the frame header only for JFIF files
+	jfif := []byte{0xff, 0xd8, 0xff, 0xe0, 0, 16, 'J', 'F', 'I', 'F', 0, 1, 1, 0, 0, 1, 0, 1, 0, 0}
+	return append(jfif, header...)
+}
+
+// hugePNG returns a PNG with only an IHDR chunk claiming 60000x60000 pixels
+func hugePNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	const ihdr = 8 // signature length, IHDR is the first chunk
+	binary.BigEndian.PutUint32(data[ihdr+8:], 60000)
+	binary.BigEndian.PutUint32(data[ihdr+12:], 60000)
+	binary.BigEndian.PutUint32(data[ihdr+21:], crc32.ChecksumIEEE(data[ihdr+4:ihdr+21]))
+	return data[:ihdr+25]
+}
+
+func TestDecodeChecksDimensionsBeforeDecoding(t *testing.T) {
+	im := NewIconMarker()
+	tests := []struct {
+		name   string
+		decode func() error
+	}{
+		{"jpeg background", func() error {
+			_, err := im.decodeBackground(context.Background(), hugeJPEG(t))
+			return err
+		}},
+		{"png image", func() error {
+			_, err := im.decodeImage("image layer", hugePNG(t))
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without limits the truncated data reaches the decoder and fails there
+			im.SetLimits(limits.Limits{})
+			if err := tt.decode(); err == nil || errors.Is(err, limits.ErrImageTooLarge) {
+				t.Fatalf("unlimited decode returned %v, want a decode error", err)
+			}
+
+			// With limits it is rejected from the header alone
+			im.SetLimits(limits.Default())
+			err := tt.decode()
+			var limitErr *limits.Error
+			if !errors.As(err, &limitErr) || !errors.Is(err, limits.ErrImageTooLarge) {
+				t.Fatalf("limited decode returned %v, want an image size limit error", err)
+			}
+			if limitErr.Actual != 60000 {
+				t.Errorf("limit error reports %d, want the header width 60000", limitErr.Actual)
+			}
+		})
+	}
+}
+
+func TestCreateImgRejectsOversizedBackgroundSpec(t *testing.T) {
+	// Hand-written specs bypass background.Marshal, and the package-level
+	// CreateImg runs without limits, so only Validate stands in the way
+	data := []byte(`ICMKBG:{"kind":"solid","width":1000000,"height":1000000,"color":"#ffffff"}`)
+	if !background.IsSpec(data) {
+		t.Fatal("test data is not a background spec")
+	}
+	if _, err := CreateImg(nil, data); err == nil {
+		t.Fatal("CreateImg accepted a 1000000x1000000 background spec")
+	}
+	if _, err := decodeBackgroundData(context.Background(), data, limits.Limits{}); err == nil {
+		t.Fatal("decodeBackgroundData accepted a 1000000x1000000 background spec without limits")
+	}
+
+	valid := []byte(`ICMKBG:{"kind":"solid","width":16,"height":16,"color":"#ffffff"}`)
+	if _, err := decodeBackgroundData(context.Background(), valid, limits.Limits{}); err != nil {
+		t.Fatalf("valid spec in the same format failed: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"image"
+
+	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/workpool"
+)
+
+// ImgRequest 描述批量渲染中的一个图像
+type ImgRequest struct {
+	FontBytes       []byte
+	BackgroundBytes []byte
+	Filters         []string
+	FilterOptions   []filter.FilterOption
+	TextOptions     []DrawTextOption
+	Size            ResizeOption // 输出尺寸，零值时与背景一致
+}
+
+// SetConcurrency 设置批量渲染的并发上限，n <= 0 时使用 GOMAXPROCS
+// SVG 批量渲染与图像批量渲染共享该上限；可与批量渲染并发调用，
+// 已经开始的批量渲染继续使用原来的工作池
+func (im *IconMarker) SetConcurrency(n int) {
+	pool := workpool.NewPool(n)
+	im.pool.Store(pool)
+	im.svgRenderer.SetPool(pool)
+}
+
+// GetPool 返回批量渲染使用的工作池
+func (im *IconMarker) GetPool() *workpool.Pool {
+	return im.pool.Load()
+}
+
+// CreateImgBatch 在工作池上并行创建多个图像
+// 结果与请求顺序一致，每个请求单独返回错误；ctx 结束后尚未开始的请求返回 ctx.Err()
+func (im *IconMarker) CreateImgBatch(ctx context.Context, requests []ImgRequest) []workpool.Result[*image.RGBA] {
+	return workpool.Map(ctx, im.pool.Load(), requests, func(ctx context.Context, _ int, req ImgRequest) (*image.RGBA, error) {
+		img, err := im.CreateImgSizedCtx(ctx, req.FontBytes, req.BackgroundBytes, req.Size, req.TextOptions...)
+		if err != nil {
+			return nil, err
+		}
+		return im.applyFiltersRGBA(ctx, img, req.Filters, req.FilterOptions)
+	})
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bagaking/iconmarker/workpool"
+)
+
+func TestSetConcurrencyDuringBatch(t *testing.T) {
+	fontBytes, bgBytes := renderFixtures(t)
+	im := NewIconMarker()
+
+	requests := make([]ImgRequest, 4)
+	for i := range requests {
+		requests[i] = ImgRequest{
+			FontBytes:       fontBytes,
+			BackgroundBytes: bgBytes,
+			TextOptions:     []DrawTextOption{textAt(i)},
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for n := 1; n <= 20; n++ {
+			im.SetConcurrency(n % 4)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			results := im.CreateImgBatch(context.Background(), requests)
+			if err := workpool.Errors(results); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	im.SetConcurrency(3)
+	if got := im.GetPool().Size(); got != 3 {
+		t.Errorf("pool size = %d after SetConcurrency(3)", got)
+	}
+}
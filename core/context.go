@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// copyBand 是复制背景时每次处理的行数，两次 ctx 检查之间最多处理这么多行
+const copyBand = 64
+
+// checkCtx 检查 ctx，已结束时返回包装了阶段信息的 ctx.Err()
+func checkCtx(ctx context.Context, stage string, args ...any) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if len(args) > 0 {
+		stage = fmt.Sprintf(stage, args...)
+	}
+	return fmt.Errorf("render canceled at %s: %w", stage, err)
+}
+
+// toRGBACtx 按行带将图像复制为 RGBA，在行带之间检查 ctx
+func toRGBACtx(ctx context.Context, img image.Image) (*image.RGBA, error) {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += copyBand {
+		if err := checkCtx(ctx, "copying background row %d of %d", y-bounds.Min.Y, bounds.Dy()); err != nil {
+			return nil, err
+		}
+		band := image.Rect(bounds.Min.X, y, bounds.Max.X, min(y+copyBand, bounds.Max.Y))
+		draw.Draw(out, band, img, band.Min, draw.Src)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// 网站图标集中各文件的默认尺寸
+var (
+	DefaultFaviconICOSizes = []int{16, 32, 48}
+	DefaultAndroidSizes    = []int{192, 512}
+)
+
+// appleTouchIconSize 是 iOS 主屏幕图标的尺寸
+const appleTouchIconSize = 180
+
+// FaviconOptions 配置网站图标集
+type FaviconOptions struct {
+	Name            string      // 应用名称，写入 site.webmanifest
+	ShortName       string      // 应用短名称，为空时使用 Name
+	ThemeColor      color.Color // 浏览器界面的主题色，为 nil 时不写入
+	BackgroundColor color.Color // 启动画面背景色，也是 apple-touch-icon 透明区域的填充色，为 nil 时为白色
+	ICOSizes        []int       // favicon.ico 包含的尺寸，为空时使用 DefaultFaviconICOSizes
+	AndroidSizes    []int       // android-chrome 图标的尺寸，为空时使用 DefaultAndroidSizes
+}
+
+// FaviconFile 是网站图标集中的一个文件
+type FaviconFile struct {
+	Name string
+	Data []byte
+}
+
+// webManifest 是 site.webmanifest 的内容
+type webManifest struct {
+	Name            string             `json:"name"`
+	ShortName       string             `json:"short_name"`
+	Icons           []webManifestImage `json:"icons"`
+	ThemeColor      string             `json:"theme_color,omitempty"`
+	BackgroundColor string             `json:"background_color"`
+	Display         string             `json:"display"`
+}
+
+type webManifestImage struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// FaviconSet 按 IconSpec 生成网站图标集：
+// favicon.ico、favicon-16x16.png、favicon-32x32.png、apple-touch-icon.png、
+// android-chrome-NxN.png 以及引用 android-chrome 图标的 site.webmanifest
+func (im *IconMarker) FaviconSet(ctx context.Context, spec *IconSpec, opts FaviconOptions) ([]FaviconFile, error) {
+	icoSizes := opts.ICOSizes
+	if len(icoSizes) == 0 {
+		icoSizes = DefaultFaviconICOSizes
+	}
+	androidSizes := opts.AndroidSizes
+	if len(androidSizes) == 0 {
+		androidSizes = DefaultAndroidSizes
+	}
+	bgColor := opts.BackgroundColor
+	if bgColor == nil {
+		bgColor = color.White
+	}
+
+	var files []FaviconFile
+	var ico bytes.Buffer
+	if err := im.WriteICOCtx(ctx, &ico, spec, icoSizes...); err != nil {
+		return nil, fmt.Errorf("favicon.ico: %w", err)
+	}
+	files = append(files, FaviconFile{Name: "favicon.ico", Data: ico.Bytes()})
+
+	addPNG := func(name string, size int, matte color.Color) error {
+		img, err := im.RenderSpecCtx(ctx, spec.Scaled(size, size))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		var out image.Image = img
+		if matte != nil {
+			out = Flatten(img, matte)
+		}
+		data, err := EncodeToBytes(out, EncodeOptions{Format: FormatPNG})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		files = append(files, FaviconFile{Name: name, Data: data})
+		return nil
+	}
+
+	for _, size := range []int{16, 32} {
+		if err := addPNG(fmt.Sprintf("favicon-%dx%d.png", size, size), size, nil); err != nil {
+			return nil, err
+		}
+	}
+	// iOS 将透明区域显示为黑色，预先合成到背景色上
+	if err := addPNG("apple-touch-icon.png", appleTouchIconSize, bgColor); err != nil {
+		return nil, err
+	}
+
+	manifest := webManifest{
+		Name:            opts.Name,
+		ShortName:       opts.ShortName,
+		BackgroundColor: utils.FormatColor(bgColor),
+		Display:         "standalone",
+	}
+	if manifest.ShortName == "" {
+		manifest.ShortName = opts.Name
+	}
+	if opts.ThemeColor != nil {
+		manifest.ThemeColor = utils.FormatColor(opts.ThemeColor)
+	}
+	for _, size := range androidSizes {
+		name := fmt.Sprintf("android-chrome-%dx%d.png", size, size)
+		if err := addPNG(name, size, nil); err != nil {
+			return nil, err
+		}
+		manifest.Icons = append(manifest.Icons, webManifestImage{
+			Src:   "/" + name,
+			Sizes: strconv.Itoa(size) + "x" + strconv.Itoa(size),
+			Type:  FormatPNG.ContentType(),
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, FaviconFile{Name: "site.webmanifest", Data: append(data, '\n')})
+	return files, nil
+}
+
+// WriteFaviconSet 生成网站图标集并写入目录，目录不存在时创建
+func (im *IconMarker) WriteFaviconSet(ctx context.Context, dir string, spec *IconSpec, opts FaviconOptions) error {
+	files, err := im.FaviconSet(ctx, spec, opts)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err = os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// ImageFormat 表示输出图像的编码格式
+type ImageFormat string
+
+// 支持的输出格式
+const (
+	FormatPNG  ImageFormat = "png"
+	FormatJPEG ImageFormat = "jpeg"
+	FormatGIF  ImageFormat = "gif"
+	FormatBMP  ImageFormat = "bmp"
+	FormatTIFF ImageFormat = "tiff"
+)
+
+// 编码默认值
+const (
+	DefaultJPEGQuality = 90
+	DefaultGIFColors   = 256
+)
+
+// ImageFormats 返回所有支持的输出格式
+func ImageFormats() []ImageFormat {
+	return []ImageFormat{FormatPNG, FormatJPEG, FormatGIF, FormatBMP, FormatTIFF}
+}
+
+// ParseImageFormat 解析格式名称，不区分大小写，接受 jpg、tif 等常见别名
+func ParseImageFormat(name string) (ImageFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "png":
+		return FormatPNG, nil
+	case "jpeg", "jpg":
+		return FormatJPEG, nil
+	case "gif":
+		return FormatGIF, nil
+	case "bmp":
+		return FormatBMP, nil
+	case "tiff", "tif":
+		return FormatTIFF, nil
+	default:
+		return "", fmt.Errorf("unsupported image format: %q", name)
+	}
+}
+
+// FormatFromPath 根据文件扩展名推断输出格式
+func FormatFromPath(path string) (ImageFormat, error) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "", fmt.Errorf("cannot infer image format from %q without an extension", path)
+	}
+	return ParseImageFormat(ext)
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func (f ImageFormat) ContentType() string {
+	switch f {
+	case FormatPNG:
+		return "image/png"
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatGIF:
+		return "image/gif"
+	case FormatBMP:
+		return "image/bmp"
+	case FormatTIFF:
+		return "image/tiff"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// EncodeOptions 配置图像编码，零值按默认参数编码为 PNG
+type EncodeOptions struct {
+	Format ImageFormat // 输出格式，为空时为 PNG
+
+	// PNGCompression 是 PNG 压缩级别，零值为 png.DefaultCompression
+	PNGCompression png.CompressionLevel
+
+	// Quality 是 JPEG 质量 1-100，为 0 时使用 DefaultJPEGQuality
+	Quality int
+	// Matte 是 JPEG 的衬底颜色，JPEG 不支持透明，半透明像素先合成到该颜色上，为 nil 时为白色
+	Matte color.Color
+
+	// GIFColors 是 GIF 调色板的最大颜色数 2-256，为 0 时使用 DefaultGIFColors
+	GIFColors int
+	// GIFDither 为 true 时量化使用 Floyd-Steinberg 抖动，适合渐变背景
+	GIFDither bool
+}
+
+// Encode 按选项编码图像
+func Encode(w io.Writer, img image.Image, opts EncodeOptions) error {
+	switch opts.Format {
+	case FormatPNG, "":
+		enc := png.Encoder{CompressionLevel: opts.PNGCompression}
+		return enc.Encode(w, img)
+	case FormatJPEG:
+		quality := opts.Quality
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		if quality < 1 || quality > 100 {
+			return fmt.Errorf("invalid JPEG quality %d, expected 1-100", quality)
+		}
+		return jpeg.Encode(w, Flatten(img, opts.Matte), &jpeg.Options{Quality: quality})
+	case FormatGIF:
+		colors := opts.GIFColors
+		if colors == 0 {
+			colors = DefaultGIFColors
+		}
+		if colors < 2 || colors > 256 {
+			return fmt.Errorf("invalid GIF color count %d, expected 2-256", colors)
+		}
+		return gif.Encode(w, Quantize(img, colors, opts.GIFDither), nil)
+	case FormatBMP:
+		return bmp.Encode(w, img)
+	case FormatTIFF:
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	default:
+		return fmt.Errorf("unsupported image format: %q", opts.Format)
+	}
+}
+
+// EncodeToBytes 按选项编码图像并返回编码结果
+func EncodeToBytes(img image.Image, opts EncodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DataURI 按选项编码图像并返回 data URI，可直接用于 <img src> 或 CSS
+func DataURI(img image.Image, opts EncodeOptions) (string, error) {
+	data, err := EncodeToBytes(img, opts)
+	if err != nil {
+		return "", err
+	}
+	format := opts.Format
+	if format == "" {
+		format = FormatPNG
+	}
+	return "data:" + format.ContentType() + ";base64," + Bytes2Base64(data), nil
+}
+
+// Flatten 将图像合成到不透明的 matte 颜色上，matte 为 nil 时为白色
+// 完全不透明的图像原样返回
+func Flatten(img image.Image, matte color.Color) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+	if matte == nil {
+		matte = color.White
+	}
+	n := color.NRGBAModel.Convert(matte).(color.NRGBA)
+	opaque := color.RGBA{R: n.R, G: n.G, B: n.B, A: 0xff}
+
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, image.NewUniform(opaque), image.Point{}, draw.Src)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Over)
+	return out
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"sort"
+)
+
+// DefaultICOSizes 是 ICO 文件默认包含的尺寸
+var DefaultICOSizes = []int{16, 32, 48, 64, 128, 256}
+
+// maxICOSize 是 ICO 目录项能表示的最大边长
+const maxICOSize = 256
+
+// EncodeICO 将多张图像写为 Windows ICO 文件，每张图像作为一个 PNG 压缩的条目
+// 图像边长不能超过 256，条目按尺寸从小到大排列
+func EncodeICO(w io.Writer, images []image.Image) error {
+	if len(images) == 0 {
+		return fmt.Errorf("ICO requires at least one image")
+	}
+	if len(images) > 0xffff {
+		return fmt.Errorf("too many ICO entries: %d", len(images))
+	}
+
+	sorted := make([]image.Image, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Bounds().Dx() < sorted[j].Bounds().Dx()
+	})
+
+	entries := make([][]byte, len(sorted))
+	for i, img := range sorted {
+		b := img.Bounds()
+		if b.Dx() <= 0 || b.Dy() <= 0 || b.Dx() > maxICOSize || b.Dy() > maxICOSize {
+			return fmt.Errorf("invalid ICO entry size %dx%d, expected 1-%d", b.Dx(), b.Dy(), maxICOSize)
+		}
+		data, err := EncodeToBytes(img, EncodeOptions{Format: FormatPNG})
+		if err != nil {
+			return fmt.Errorf("%w, error encoding ICO entry %dx%d", err, b.Dx(), b.Dy())
+		}
+		entries[i] = data
+	}
+
+	// ICONDIR 头和 ICONDIRENTRY 目录，所有字段为小端序
+	var buf bytes.Buffer
+	header := [3]uint16{0, 1, uint16(len(entries))}
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	offset := 6 + 16*len(entries)
+	for i, img := range sorted {
+		b := img.Bounds()
+		entry := struct {
+			Width, Height, Colors, Reserved uint8
+			Planes, BitCount                uint16
+			Size, Offset                    uint32
+		}{
+			Width:    uint8(b.Dx() % maxICOSize), // 0 表示 256
+			Height:   uint8(b.Dy() % maxICOSize),
+			Planes:   1,
+			BitCount: 32,
+			Size:     uint32(len(entries[i])),
+			Offset:   uint32(offset),
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, entry); err != nil {
+			return err
+		}
+		offset += len(entries[i])
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	for _, data := range entries {
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderSizes 按 IconSpec 渲染多个正方形尺寸，每个尺寸都由缩放后的文档重新渲染
+func (im *IconMarker) RenderSizes(ctx context.Context, spec *IconSpec, sizes []int) ([]*image.RGBA, error) {
+	images := make([]*image.RGBA, len(sizes))
+	for i, size := range sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid icon size %d", size)
+		}
+		img, err := im.RenderSpecCtx(ctx, spec.Scaled(size, size))
+		if err != nil {
+			return nil, fmt.Errorf("size %d: %w", size, err)
+		}
+		images[i] = img
+	}
+	return images, nil
+}
+
+// WriteICO 按 IconSpec 渲染多个尺寸并写为 ICO 文件，sizes 为空时使用 DefaultICOSizes
+func (im *IconMarker) WriteICO(w io.Writer, spec *IconSpec, sizes ...int) error {
+	return im.WriteICOCtx(context.Background(), w, spec, sizes...)
+}
+
+// WriteICOCtx 按 IconSpec 渲染多个尺寸并写为 ICO 文件，支持通过 ctx 取消
+func (im *IconMarker) WriteICOCtx(ctx context.Context, w io.Writer, spec *IconSpec, sizes ...int) error {
+	if len(sizes) == 0 {
+		sizes = DefaultICOSizes
+	}
+	for _, size := range sizes {
+		if size > maxICOSize {
+			return fmt.Errorf("invalid ICO size %d, expected at most %d", size, maxICOSize)
+		}
+	}
+
+	rendered, err := im.RenderSizes(ctx, spec, sizes)
+	if err != nil {
+		return err
+	}
+	images := make([]image.Image, len(rendered))
+	for i, img := range rendered {
+		images[i] = img
+	}
+	return EncodeICO(w, images)
+}
@@ -1,15 +1,15 @@
 package core
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/draw"
-	"image/jpeg"
 	"io"
 	"os"
 
 	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/limits"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
@@ -41,10 +41,10 @@ func CreateImg(fontBytes, backgroundBytes []byte, drawFontOpt ...DrawTextOption)
 		}
 	}
 
-	// Parse bg image
-	img, err := jpeg.Decode(bytes.NewReader(backgroundBytes))
+	// Parse bg image, or generate it from a background spec
+	img, err := decodeBackgroundData(context.Background(), backgroundBytes, limits.Limits{})
 	if err != nil {
-		return nil, fmt.Errorf("%w, error decoding background", err)
+		return nil, err
 	}
 
 	outI := image.NewRGBA(img.Bounds())
@@ -73,11 +73,18 @@ func CreateImg(fontBytes, backgroundBytes []byte, drawFontOpt ...DrawTextOption)
 //		return jpeg.Encode(w, m, &jpeg.Options{Quality: 100})
 //	}
 //
+// if encoder is nil, the format is inferred from the file extension,
+// see SaveImage for encoding options
+//
 // if you want to save image to byte stream, just call encoder outside
 // e.g.
 // var buf bytes.Buffer
 // err = png.Encode(&buf, img)
 func SaveImage2File(img image.Image, path string, encoder func(io.Writer, image.Image) error) error {
+	if encoder == nil {
+		return SaveImage(img, path, EncodeOptions{})
+	}
+
 	outputFile, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("%w, error creating output file", err)
@@ -92,6 +99,29 @@ func SaveImage2File(img image.Image, path string, encoder func(io.Writer, image.
 	return nil
 }
 
+// SaveImage encodes image with opts and saves it to file
+// if opts.Format is empty, the format is inferred from the file extension
+func SaveImage(img image.Image, path string, opts EncodeOptions) error {
+	if opts.Format == "" {
+		format, err := FormatFromPath(path)
+		if err != nil {
+			return err
+		}
+		opts.Format = format
+	}
+
+	outputFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w, error creating output file", err)
+	}
+
+	if err = Encode(outputFile, img, opts); err != nil {
+		outputFile.Close()
+		return fmt.Errorf("%w, error encoding image", err)
+	}
+	return outputFile.Close()
+}
+
 // adaptSize returns the real font size that fits the max width and height
 func adaptSize(f *truetype.Font, text string, maxW, maxH int, fontSize float64) (realSize float64) {
 	if maxH > 0 {
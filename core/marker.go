@@ -2,19 +2,19 @@
 package core
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/draw"
-	"image/jpeg"
 	"io"
+	"sync/atomic"
 	"time"
 
-	"github.com/bagaking/iconmarker/assets"
 	"github.com/bagaking/iconmarker/cache"
 	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/limits"
 	"github.com/bagaking/iconmarker/renderer"
-	"github.com/golang/freetype"
+	"github.com/bagaking/iconmarker/workpool"
 	"github.com/golang/freetype/truetype"
 )
 
@@ -24,6 +24,10 @@ type IconMarker struct {
 	filterManager   *filter.FilterManager
 	textRenderer    *renderer.TextRenderer
 	svgRenderer     *renderer.SVGRenderer
+
+	pool               atomic.Pointer[workpool.Pool] // 批量渲染的工作池
+	limits             atomic.Pointer[limits.Limits] // 资源限制
+	outputCacheEnabled atomic.Bool                   // 是否启用渲染结果缓存
 }
 
 // NewIconMarker 创建一个新的图标标记器
@@ -39,77 +43,152 @@ func NewIconMarker() *IconMarker {
 	textRenderer := renderer.NewTextRenderer(resourceManager)
 	svgRenderer := renderer.NewSVGRenderer(resourceManager)
 
-	return &IconMarker{
+	// 创建工作池，批量渲染共享同一并发上限
+	pool := workpool.NewPool(0)
+	svgRenderer.SetPool(pool)
+
+	im := &IconMarker{
 		resourceManager: resourceManager,
 		filterManager:   filterManager,
 		textRenderer:    textRenderer,
 		svgRenderer:     svgRenderer,
 	}
+	im.pool.Store(pool)
+	return im
 }
 
 // CreateImg 创建带有文本的图像（兼容旧API）
 func (im *IconMarker) CreateImg(fontBytes, backgroundBytes []byte, drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
-	var font *truetype.Font
-	var err error
+	return im.CreateImgCtx(context.Background(), fontBytes, backgroundBytes, drawFontOpt...)
+}
 
-	// 如果字体字节数组为空，尝试加载默认字体
-	if len(fontBytes) == 0 {
-		defaultFontBytes, err := assets.GetDefaultFont()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get default font: %w", err)
-		}
-		font, err = freetype.ParseFont(defaultFontBytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse default font: %w", err)
-		}
-	} else {
-		// 解析用户提供的字体
-		font, err = freetype.ParseFont(fontBytes)
-		if err != nil {
-			return nil, fmt.Errorf("%w, error parsing font file", err)
-		}
+// CreateImgCtx 创建带有文本的图像，支持通过 ctx 取消
+// 在加载字体、解码背景、复制背景的行带之间以及每个文本图层之间检查 ctx，
+// 取消时返回包装了阶段信息的 context.Canceled 或 context.DeadlineExceeded
+func (im *IconMarker) CreateImgCtx(ctx context.Context, fontBytes, backgroundBytes []byte, drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
+	return im.CreateImgSizedCtx(ctx, fontBytes, backgroundBytes, ResizeOption{}, drawFontOpt...)
+}
+
+// CreateImgSized 创建指定输出尺寸的带文本图像，例如从任意照片生成 256x256 的图标
+func (im *IconMarker) CreateImgSized(fontBytes, backgroundBytes []byte, size ResizeOption, drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
+	return im.CreateImgSizedCtx(context.Background(), fontBytes, backgroundBytes, size, drawFontOpt...)
+}
+
+// CreateImgSizedCtx 创建指定输出尺寸的带文本图像，支持通过 ctx 取消
+// 背景先按 size 缩放裁剪再绘制文本，文本因此以输出分辨率渲染；
+// size 为零值时输出尺寸与背景一致
+func (im *IconMarker) CreateImgSizedCtx(ctx context.Context, fontBytes, backgroundBytes []byte, size ResizeOption, drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
+	if err := checkCtx(ctx, "loading font"); err != nil {
+		return nil, err
+	}
+
+	if err := im.Limits().CheckInputBytes("font", len(fontBytes)); err != nil {
+		return nil, err
 	}
 
-	// 解析背景图片
-	img, err := jpeg.Decode(bytes.NewReader(backgroundBytes))
+	// 字体字节数组为空时加载默认字体，解析结果经由字体缓存复用
+	font, err := im.textRenderer.LoadFont(fontBytes)
 	if err != nil {
-		return nil, fmt.Errorf("%w, error decoding background", err)
+		return nil, fmt.Errorf("%w, error parsing font file", err)
 	}
 
-	outI := image.NewRGBA(img.Bounds())
-	draw.Draw(outI, outI.Bounds(), img, img.Bounds().Min, draw.Src)
+	if err = checkCtx(ctx, "decoding background"); err != nil {
+		return nil, err
+	}
+
+	// 解析背景图片，解码前先检查尺寸
+	img, err := im.decodeBackground(ctx, backgroundBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	outI, err := im.prepareCanvas(ctx, img, size)
+	if err != nil {
+		return nil, err
+	}
 
 	// 在图像上绘制文本
-	for _, opt := range drawFontOpt {
-		for _, eop := range opt.ToEffectGroup() {
-			err = DrawCenteredFont(font, outI, eop)
-			if err != nil {
-				return nil, fmt.Errorf("%w, error drawing text", err)
+	if err = drawTextLayers(ctx, font, outI, drawFontOpt); err != nil {
+		return nil, err
+	}
+
+	return outI, nil
+}
+
+// prepareCanvas 检查输出尺寸，并将背景复制或缩放为可绘制的 RGBA 画布
+func (im *IconMarker) prepareCanvas(ctx context.Context, bg image.Image, size ResizeOption) (*image.RGBA, error) {
+	if size.IsZero() {
+		if err := im.Limits().CheckOutputSize(bg.Bounds().Dx(), bg.Bounds().Dy()); err != nil {
+			return nil, err
+		}
+		return toRGBACtx(ctx, bg)
+	}
+
+	width, height, err := size.targetSize(bg.Bounds())
+	if err != nil {
+		return nil, err
+	}
+	if err = im.Limits().CheckOutputSize(width, height); err != nil {
+		return nil, err
+	}
+	if err = checkCtx(ctx, "resizing background"); err != nil {
+		return nil, err
+	}
+	size.Width, size.Height = width, height
+	return Fit(bg, size)
+}
+
+// drawTextLayers 依次绘制每个文本选项的效果图层和文本本身，图层之间检查 ctx
+func drawTextLayers(ctx context.Context, font *truetype.Font, outI *image.RGBA, drawFontOpt []DrawTextOption) error {
+	for i, opt := range drawFontOpt {
+		for j, eop := range opt.ToEffectGroup() {
+			if err := checkCtx(ctx, "text %d effect layer %d", i, j); err != nil {
+				return err
+			}
+			if err := DrawCenteredFont(font, outI, eop); err != nil {
+				return fmt.Errorf("%w, error drawing text", err)
 			}
 		}
 
-		if err = DrawCenteredFont(font, outI, opt); err != nil {
-			return nil, fmt.Errorf("%w, error drawing text", err)
+		if err := checkCtx(ctx, "text %d", i); err != nil {
+			return err
+		}
+		if err := DrawCenteredFont(font, outI, opt); err != nil {
+			return fmt.Errorf("%w, error drawing text", err)
 		}
 	}
 
-	return outI, nil
+	return nil
 }
 
 // CreateImgWithFilters 创建带有文本和滤镜的图像
 func (im *IconMarker) CreateImgWithFilters(fontBytes, backgroundBytes []byte,
 	filters []string, filterOptions []filter.FilterOption,
 	drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
+	return im.CreateImgWithFiltersCtx(context.Background(), fontBytes, backgroundBytes, filters, filterOptions, drawFontOpt...)
+}
+
+// CreateImgWithFiltersCtx 创建带有文本和滤镜的图像，支持通过 ctx 取消
+// 滤镜之间以及滤镜像素循环的行带之间同样检查 ctx
+func (im *IconMarker) CreateImgWithFiltersCtx(ctx context.Context, fontBytes, backgroundBytes []byte,
+	filters []string, filterOptions []filter.FilterOption,
+	drawFontOpt ...DrawTextOption) (*image.RGBA, error) {
 
 	// 先创建基本图像
-	img, err := im.CreateImg(fontBytes, backgroundBytes, drawFontOpt...)
+	img, err := im.CreateImgCtx(ctx, fontBytes, backgroundBytes, drawFontOpt...)
 	if err != nil {
 		return nil, err
 	}
 
+	return im.applyFiltersRGBA(ctx, img, filters, filterOptions)
+}
+
+// applyFiltersRGBA 依次应用滤镜并将结果转换为 RGBA
+func (im *IconMarker) applyFiltersRGBA(ctx context.Context, img *image.RGBA,
+	filters []string, filterOptions []filter.FilterOption) (*image.RGBA, error) {
 	// 应用滤镜
 	if len(filters) > 0 {
-		filteredImg, err := im.filterManager.ApplyFilters(img, filters, filterOptions)
+		filteredImg, err := im.filterManager.ApplyFiltersCtx(ctx, img, filters, filterOptions)
 		if err != nil {
 			return nil, fmt.Errorf("error applying filters: %w", err)
 		}
@@ -128,11 +207,26 @@ func (im *IconMarker) CreateImgWithFilters(fontBytes, backgroundBytes []byte,
 	return img, nil
 }
 
-// SaveImage2File 将图像保存到文件
+// SaveImage2File 将图像保存到文件，encoder 为 nil 时根据扩展名推断格式
 func (im *IconMarker) SaveImage2File(img image.Image, path string, encoder func(io.Writer, image.Image) error) error {
 	return SaveImage2File(img, path, encoder)
 }
 
+// SetLimits 设置资源限制，用于渲染用户上传的背景和 SVG 等不可信输入
+// 违反限制时返回 *limits.Error，可用 errors.Is 匹配 limits.ErrInputTooLarge 等错误
+func (im *IconMarker) SetLimits(l limits.Limits) {
+	im.limits.Store(&l)
+	im.svgRenderer.SetLimits(l)
+}
+
+// Limits 返回当前的资源限制，未设置时所有资源均不受限
+func (im *IconMarker) Limits() limits.Limits {
+	if l := im.limits.Load(); l != nil {
+		return *l
+	}
+	return limits.Limits{}
+}
+
 // GetFilterManager 返回滤镜管理器，允许注册自定义滤镜
 func (im *IconMarker) GetFilterManager() *filter.FilterManager {
 	return im.filterManager
@@ -143,6 +237,16 @@ func (im *IconMarker) GetResourceManager() *cache.ResourceManager {
 	return im.resourceManager
 }
 
+// GetSVGRenderer 返回 SVG 渲染器
+func (im *IconMarker) GetSVGRenderer() *renderer.SVGRenderer {
+	return im.svgRenderer
+}
+
+// GetTextRenderer 返回文本渲染器
+func (im *IconMarker) GetTextRenderer() *renderer.TextRenderer {
+	return im.textRenderer
+}
+
 // ApplyFilter 对图像应用单个滤镜
 func (im *IconMarker) ApplyFilter(img image.Image, filterName string, option filter.FilterOption) (image.Image, error) {
 	// 创建一个新的RGBA图像
@@ -162,3 +266,8 @@ func (im *IconMarker) ApplyFilter(img image.Image, filterName string, option fil
 func (im *IconMarker) ApplyFilters(img image.Image, filterNames []string, options []filter.FilterOption) (image.Image, error) {
 	return im.filterManager.ApplyFilters(img, filterNames, options)
 }
+
+// ApplyFiltersCtx 对图像应用多个滤镜，在滤镜之间和像素循环的行带之间检查 ctx
+func (im *IconMarker) ApplyFiltersCtx(ctx context.Context, img image.Image, filterNames []string, options []filter.FilterOption) (image.Image, error) {
+	return im.filterManager.ApplyFiltersCtx(ctx, img, filterNames, options)
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// MaxMosaicMembers 是拼图头像最多容纳的成员数
+const MaxMosaicMembers = 9
+
+// DefaultMosaicBackground 是拼图头像的默认背景色
+var DefaultMosaicBackground = color.RGBA{R: 221, G: 222, B: 224, A: 255}
+
+// MosaicMember 描述拼图头像中的一个成员，Image 与 Data 二选一
+// Data 为 JPEG、PNG 或 GIF 编码的原始数据，解码前会按资源限制检查
+type MosaicMember struct {
+	Image image.Image
+	Data  []byte
+}
+
+// MosaicOption 描述拼图头像的样式
+type MosaicOption struct {
+	Size       int             // 输出边长
+	Gap        int             // 格子之间的间距，为 0 时使用 Size/40（至少 1 像素），为负数时无间距
+	Padding    int             // 四周留白，为 0 时与间距相同，为负数时无留白
+	Background color.Color     // 背景色，为 nil 时使用 DefaultMosaicBackground
+	Kernel     ResizeKernel    // 成员缩放使用的插值核，为空时使用 KernelCatmullRom
+	FontBytes  []byte          // 标签字体，为空时使用默认字体
+	Label      *DrawTextOption // 可选的叠加标签，绘制在所有成员之上
+}
+
+// CreateMosaicAvatar 将最多 9 个成员头像拼成一个群头像
+func (im *IconMarker) CreateMosaicAvatar(members []MosaicMember, opt MosaicOption) (*image.RGBA, error) {
+	return im.CreateMosaicAvatarCtx(context.Background(), members, opt)
+}
+
+// CreateMosaicAvatarCtx 将最多 9 个成员头像拼成一个群头像，支持通过 ctx 取消
+// 每个成员以 FitCover 方式居中裁剪为正方形并缩放到格子大小，
+// 排列方式与常见聊天软件一致：不满一行的成员放在第一行并居中，整体垂直居中
+func (im *IconMarker) CreateMosaicAvatarCtx(ctx context.Context, members []MosaicMember, opt MosaicOption) (*image.RGBA, error) {
+	if len(members) == 0 || len(members) > MaxMosaicMembers {
+		return nil, fmt.Errorf("invalid member count: %d, must be 1 to %d", len(members), MaxMosaicMembers)
+	}
+	if opt.Size <= 0 {
+		return nil, fmt.Errorf("invalid mosaic size: %d", opt.Size)
+	}
+	if err := im.Limits().CheckOutputSize(opt.Size, opt.Size); err != nil {
+		return nil, err
+	}
+
+	tiles, err := mosaicLayout(len(members), opt.Size, opt.gap(), opt.padding())
+	if err != nil {
+		return nil, err
+	}
+
+	bg := opt.Background
+	if bg == nil {
+		bg = DefaultMosaicBackground
+	}
+	outI := image.NewRGBA(image.Rect(0, 0, opt.Size, opt.Size))
+	draw.Draw(outI, outI.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	for i, member := range members {
+		if err = checkCtx(ctx, "mosaic member %d", i); err != nil {
+			return nil, err
+		}
+
+		src := member.Image
+		if src == nil {
+			if len(member.Data) == 0 {
+				return nil, fmt.Errorf("mosaic member %d has neither image nor data", i)
+			}
+			if src, err = im.decodeImage(fmt.Sprintf("mosaic member %d", i), member.Data); err != nil {
+				return nil, err
+			}
+		}
+		if err = drawFitted(outI, tiles[i], src, ResizeOption{Fit: FitCover, Kernel: opt.Kernel}); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Label != nil {
+		if err = im.Limits().CheckInputBytes("font", len(opt.FontBytes)); err != nil {
+			return nil, err
+		}
+		font, err := im.textRenderer.LoadFont(opt.FontBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w, error parsing font file", err)
+		}
+		if err = drawTextLayers(ctx, font, outI, []DrawTextOption{*opt.Label}); err != nil {
+			return nil, err
+		}
+	}
+
+	return outI, nil
+}
+
+// gap 返回实际使用的格子间距
+func (opt MosaicOption) gap() int {
+	switch {
+	case opt.Gap < 0:
+		return 0
+	case opt.Gap == 0:
+		return max(opt.Size/40, 1)
+	default:
+		return opt.Gap
+	}
+}
+
+// padding 返回实际使用的四周留白
+func (opt MosaicOption) padding() int {
+	switch {
+	case opt.Padding < 0:
+		return 0
+	case opt.Padding == 0:
+		return opt.gap()
+	default:
+		return opt.Padding
+	}
+}
+
+// mosaicLayout 计算 n 个成员的格子位置
+// 1 个成员占满画布，2-4 个按两列排列，5-9 个按三列排列
+func mosaicLayout(n, size, gap, padding int) ([]image.Rectangle, error) {
+	cols := 3
+	switch {
+	case n == 1:
+		cols = 1
+	case n <= 4:
+		cols = 2
+	}
+	rows := (n + cols - 1) / cols
+
+	tile := (size - 2*padding - (cols-1)*gap) / cols
+	if tile <= 0 {
+		return nil, fmt.Errorf("mosaic size %d too small for %d members", size, n)
+	}
+
+	// 整体垂直居中
+	top := (size - rows*tile - (rows-1)*gap) / 2
+
+	tiles := make([]image.Rectangle, 0, n)
+	remaining := n
+	for row := 0; row < rows; row++ {
+		// 第一行放不满一行的余数，其余行放满
+		count := cols
+		if row == 0 && n%cols != 0 {
+			count = n % cols
+		}
+		left := (size - count*tile - (count-1)*gap) / 2
+		y := top + row*(tile+gap)
+		for c := 0; c < count && remaining > 0; c++ {
+			x := left + c*(tile+gap)
+			tiles = append(tiles, image.Rect(x, y, x+tile, y+tile))
+			remaining--
+		}
+	}
+	return tiles, nil
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// fontEffectJSON 是 FontEffect 的序列化形式，颜色以十六进制字符串表示
+type fontEffectJSON struct {
+	Type    string `json:"type"`
+	Color   string `json:"color,omitempty"`
+	XOffset int    `json:"x_offset,omitempty"`
+	YOffset int    `json:"y_offset,omitempty"`
+}
+
+// drawTextOptionJSON 是 DrawTextOption 的序列化形式
+type drawTextOptionJSON struct {
+	FontColor string       `json:"font_color,omitempty"`
+	FontSize  float64      `json:"font_size,omitempty"`
+	MaxWidth  int          `json:"max_width,omitempty"`
+	MaxHeight int          `json:"max_height,omitempty"`
+	Text      string       `json:"text"`
+	YOffset   int          `json:"y_offset,omitempty"`
+	XOffset   int          `json:"x_offset,omitempty"`
+	Effect    []FontEffect `json:"effect,omitempty"`
+}
+
+// drawTextOptionWire 用于反序列化 DrawTextOption，效果逐个解析以便报告出错位置
+type drawTextOptionWire struct {
+	drawTextOptionJSON
+	Effect []json.RawMessage `json:"effect,omitempty"`
+}
+
+// MarshalJSON 实现 json.Marshaler，颜色写为十六进制字符串
+func (e FontEffect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fontEffectJSON{
+		Type:    e.Type,
+		Color:   formatOptionalColor(e.Color),
+		XOffset: e.XOffset,
+		YOffset: e.YOffset,
+	})
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，拒绝未知字段
+func (e *FontEffect) UnmarshalJSON(data []byte) error {
+	var in fontEffectJSON
+	if err := decodeStrict(data, &in, ""); err != nil {
+		return err
+	}
+
+	c, err := parseOptionalColor(in.Color)
+	if err != nil {
+		return &SpecError{Path: "color", Err: err}
+	}
+	*e = FontEffect{Type: in.Type, Color: c, XOffset: in.XOffset, YOffset: in.YOffset}
+	return nil
+}
+
+// MarshalJSON 实现 json.Marshaler，颜色写为十六进制字符串
+func (o DrawTextOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(drawTextOptionJSON{
+		FontColor: formatOptionalColor(o.FontColor),
+		FontSize:  o.FontSize,
+		MaxWidth:  o.MaxWidth,
+		MaxHeight: o.MaxHeight,
+		Text:      o.Text,
+		YOffset:   o.YOffset,
+		XOffset:   o.XOffset,
+		Effect:    o.Effect,
+	})
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，拒绝未知字段
+// 出错时返回带相对路径的 *SpecError，例如 effect[1].color
+func (o *DrawTextOption) UnmarshalJSON(data []byte) error {
+	var in drawTextOptionWire
+	if err := decodeStrict(data, &in, ""); err != nil {
+		return err
+	}
+
+	c, err := parseOptionalColor(in.FontColor)
+	if err != nil {
+		return &SpecError{Path: "font_color", Err: err}
+	}
+
+	out := DrawTextOption{
+		FontColor: c,
+		FontSize:  in.FontSize,
+		MaxWidth:  in.MaxWidth,
+		MaxHeight: in.MaxHeight,
+		Text:      in.Text,
+		YOffset:   in.YOffset,
+		XOffset:   in.XOffset,
+	}
+	for i, raw := range in.Effect {
+		var effect FontEffect
+		if err = decodeStrict(raw, &effect, fmt.Sprintf("effect[%d]", i)); err != nil {
+			return err
+		}
+		out.Effect = append(out.Effect, effect)
+	}
+
+	*o = out
+	return nil
+}
+
+// formatOptionalColor 将颜色格式化为十六进制字符串，nil 返回空字符串
+func formatOptionalColor(c color.Color) string {
+	if c == nil {
+		return ""
+	}
+	return utils.FormatColor(c)
+}
+
+// parseOptionalColor 解析十六进制颜色，空字符串返回 nil
+func parseOptionalColor(s string) (color.Color, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return utils.ParseColor(s)
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/bagaking/iconmarker/cache"
+	"github.com/bagaking/iconmarker/filter"
+)
+
+// EnableOutputCache 启用渲染结果缓存
+// 编码后的最终图像存放在资源管理器的图像缓存中，图像缓存专用于渲染结果，
+// maxBytes 为其字节预算，0 表示只受条目数量限制。缓存默认关闭
+func (im *IconMarker) EnableOutputCache(maxBytes int64) {
+	if limiter, ok := im.resourceManager.GetImageCache().(cache.ByteLimiter); ok {
+		limiter.SetMaxBytes(maxBytes)
+	}
+	im.outputCacheEnabled.Store(true)
+}
+
+// DisableOutputCache 关闭渲染结果缓存并清空已缓存的结果
+func (im *IconMarker) DisableOutputCache() {
+	im.outputCacheEnabled.Store(false)
+	im.InvalidateOutputCache()
+}
+
+// OutputCacheEnabled 返回渲染结果缓存是否启用
+func (im *IconMarker) OutputCacheEnabled() bool {
+	return im.outputCacheEnabled.Load()
+}
+
+// InvalidateOutput 使指定键的渲染结果失效，键由 OutputCacheKey 或 OutputCacheKeyWithOptions 计算
+func (im *IconMarker) InvalidateOutput(key string) {
+	im.resourceManager.Images().Remove(key)
+}
+
+// InvalidateOutputCache 清空所有已缓存的渲染结果
+func (im *IconMarker) InvalidateOutputCache() {
+	im.resourceManager.GetImageCache().Clear()
+}
+
+// CreateImgEncoded 创建带有文本和滤镜的图像，并按指定格式的默认参数编码
+// 启用渲染结果缓存时，相同参数的渲染直接返回缓存的编码结果
+func (im *IconMarker) CreateImgEncoded(fontBytes, backgroundBytes []byte,
+	filters []string, filterOptions []filter.FilterOption,
+	format ImageFormat, drawFontOpt ...DrawTextOption) ([]byte, error) {
+	return im.CreateImgEncodedWithOptions(fontBytes, backgroundBytes, filters, filterOptions,
+		EncodeOptions{Format: format}, drawFontOpt...)
+}
+
+// CreateImgEncodedWithOptions 创建带有文本和滤镜的图像，并按编码选项编码
+// 编码选项参与缓存键的计算，不同质量或调色板的结果分别缓存
+func (im *IconMarker) CreateImgEncodedWithOptions(fontBytes, backgroundBytes []byte,
+	filters []string, filterOptions []filter.FilterOption,
+	opts EncodeOptions, drawFontOpt ...DrawTextOption) ([]byte, error) {
+
+	var key string
+	if im.outputCacheEnabled.Load() {
+		key = OutputCacheKeyWithOptions(fontBytes, backgroundBytes, filters, filterOptions, opts, drawFontOpt...)
+		if data, ok := im.getCachedOutput(key); ok {
+			return data, nil
+		}
+	}
+
+	img, err := im.CreateImgWithFilters(fontBytes, backgroundBytes, filters, filterOptions, drawFontOpt...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := EncodeToBytes(img, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w, error encoding image", err)
+	}
+	if err = im.Limits().CheckOutputBytes(len(data)); err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		format := opts.Format
+		if format == "" {
+			format = FormatPNG
+		}
+		im.putCachedOutput(key, data, format)
+	}
+	return data, nil
+}
+
+// getCachedOutput 从图像缓存读取编码结果，返回副本以免调用方修改缓存内容
+func (im *IconMarker) getCachedOutput(key string) ([]byte, bool) {
+	encoded, found := im.resourceManager.Images().Get(key)
+	if !found {
+		return nil, false
+	}
+	return encoded.Clone().(*cache.EncodedImage).Bytes(), true
+}
+
+// putCachedOutput 将编码结果写入图像缓存
+func (im *IconMarker) putCachedOutput(key string, data []byte, format ImageFormat) {
+	dataCopy := make([]byte, len(data))
+	copy(dataCopy, data)
+	im.resourceManager.Images().Put(key, cache.NewEncodedImage(dataCopy, string(format)))
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/filter"
+)
+
+// IconParams 用少量参数描述最常见的图标：纯色背景、可选的背景图片、内嵌图标和文字
+// 适合命令行参数、URL 查询参数等扁平的输入，通过 Spec 转换为 IconSpec 后渲染
+type IconParams struct {
+	Text            string      // 文字
+	Icon            string      // 内嵌图标名称，见 assets.ListAvailableIcons
+	IconColor       color.Color // 图标着色，为 nil 时保留图标原色
+	Background      color.Color // 背景色，为 nil 时背景透明
+	BackgroundImage []byte      // 背景图片，裁剪后铺满画布，覆盖在背景色之上
+	TextColor       color.Color // 文字颜色，为 nil 时为白色
+	Size            int         // 输出边长
+	Font            []byte      // 字体，为空时使用默认字体
+}
+
+// Spec 将参数转换为 IconSpec
+// 只有图标或只有文字时居中占据画布的主要部分，两者都有时图标在上、文字在下；
+// 参数无效时返回可用 errors.Is 匹配 ErrInvalidSpec 的错误
+func (p IconParams) Spec() (*IconSpec, error) {
+	if p.Size <= 0 {
+		return nil, fmt.Errorf("%w: invalid size %d", ErrInvalidSpec, p.Size)
+	}
+	if p.Text == "" && p.Icon == "" && p.Background == nil && len(p.BackgroundImage) == 0 {
+		return nil, fmt.Errorf("%w: nothing to render, set text, icon or a background", ErrInvalidSpec)
+	}
+
+	size := p.Size
+	spec := &IconSpec{
+		Version: IconSpecVersion,
+		Canvas:  CanvasSpec{Width: size, Height: size},
+		Font:    p.Font,
+	}
+	if p.Background != nil {
+		spec.Background = &background.Spec{Kind: background.KindSolid, Color: p.Background}
+	}
+	if len(p.BackgroundImage) > 0 {
+		spec.Layers = append(spec.Layers, LayerSpec{Type: LayerImage, Image: p.BackgroundImage, Fit: FitCover})
+	}
+
+	if p.Icon != "" {
+		icon := LayerSpec{Type: LayerSVG, Icon: p.Icon}
+		if p.Text != "" {
+			// 图标占上方 60%，为下方文字留出空间
+			side := size * 6 / 10
+			icon.X, icon.Y, icon.Width, icon.Height = (size-side)/2, size/20, side, side
+		} else {
+			side := size * 3 / 4
+			icon.X, icon.Y, icon.Width, icon.Height = (size-side)/2, (size-side)/2, side, side
+		}
+		if p.IconColor != nil {
+			c := color.NRGBAModel.Convert(p.IconColor).(color.NRGBA)
+			icon.Effects = append(icon.Effects, FilterSpec{
+				Name:   "tint",
+				Option: filter.TintOption{Color: [3]uint8{c.R, c.G, c.B}, Intensity: 1},
+			})
+		}
+		spec.Layers = append(spec.Layers, icon)
+	}
+
+	if p.Text != "" {
+		textColor := p.TextColor
+		if textColor == nil {
+			textColor = color.White
+		}
+		text := LayerSpec{Type: LayerText}
+		opt := DrawTextOption{Text: p.Text, FontColor: textColor}
+		if p.Icon != "" {
+			text.Y = size * 2 / 3
+			opt = opt.SetAdaptedSize(size*8/10, size/4)
+		} else {
+			opt = opt.SetAdaptedSize(size*8/10, size/2)
+		}
+		text.Text = &opt
+		spec.Layers = append(spec.Layers, text)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
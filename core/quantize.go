@@ -0,0 +1,219 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// alphaThreshold 是量化时视为不透明的最小 alpha，低于它的像素映射为透明色
+const alphaThreshold = 0x80
+
+// Quantize 使用中位切分将图像量化为最多 n 种颜色的调色板图像
+// 图像含透明像素时调色板首项为完全透明色，占用一个名额；
+// GIF 只支持一位透明，半透明像素按 alphaThreshold 二值化
+func Quantize(img image.Image, n int, dither bool) *image.Paletted {
+	src := binarizeAlpha(img)
+	palette := buildPalette([]*image.NRGBA{src}, n)
+	return mapToPalette(src, palette, dither)
+}
+
+// buildPalette 统计多张图像的颜色直方图，用中位切分生成共享调色板
+func buildPalette(images []*image.NRGBA, n int) color.Palette {
+	hist := make(map[uint16]*colorBucket)
+	transparent := false
+	for _, img := range images {
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)]
+			for i := 0; i < len(row); i += 4 {
+				if row[i+3] == 0 {
+					transparent = true
+					continue
+				}
+				// 以每通道 5 位聚合颜色，桶内保留精确的颜色和
+				key := uint16(row[i]>>3)<<10 | uint16(row[i+1]>>3)<<5 | uint16(row[i+2]>>3)
+				bucket := hist[key]
+				if bucket == nil {
+					bucket = &colorBucket{}
+					hist[key] = bucket
+				}
+				bucket.add(row[i], row[i+1], row[i+2])
+			}
+		}
+	}
+
+	var palette color.Palette
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+		n--
+	}
+	if len(hist) == 0 || n <= 0 {
+		if len(palette) == 0 {
+			palette = append(palette, color.NRGBA{A: 0xff})
+		}
+		return palette
+	}
+
+	buckets := make([]*colorBucket, 0, len(hist))
+	for _, bucket := range hist {
+		buckets = append(buckets, bucket)
+	}
+	// map 遍历顺序随机，排序保证同一图像总得到同一调色板
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].less(buckets[j]) })
+
+	for _, box := range medianCut(buckets, n) {
+		palette = append(palette, box.average())
+	}
+	return palette
+}
+
+// colorBucket 是直方图中的一个颜色桶
+type colorBucket struct {
+	count   int
+	r, g, b int
+}
+
+func (c *colorBucket) add(r, g, b uint8) {
+	c.count++
+	c.r += int(r)
+	c.g += int(g)
+	c.b += int(b)
+}
+
+// channel 返回桶的平均颜色在指定通道上的值
+func (c *colorBucket) channel(ch int) int {
+	switch ch {
+	case 0:
+		return c.r / c.count
+	case 1:
+		return c.g / c.count
+	default:
+		return c.b / c.count
+	}
+}
+
+func (c *colorBucket) less(o *colorBucket) bool {
+	for ch := 0; ch < 3; ch++ {
+		if a, b := c.channel(ch), o.channel(ch); a != b {
+			return a < b
+		}
+	}
+	return c.count < o.count
+}
+
+// colorBox 是中位切分中的一个颜色盒
+type colorBox []*colorBucket
+
+// widest 返回跨度最大的通道及其跨度
+func (box colorBox) widest() (ch, span int) {
+	for c := 0; c < 3; c++ {
+		lo, hi := 255, 0
+		for _, bucket := range box {
+			v := bucket.channel(c)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > span {
+			ch, span = c, hi-lo
+		}
+	}
+	return ch, span
+}
+
+func (box colorBox) population() int {
+	total := 0
+	for _, bucket := range box {
+		total += bucket.count
+	}
+	return total
+}
+
+// average 返回盒内按像素数加权的平均颜色
+func (box colorBox) average() color.NRGBA {
+	var count, r, g, b int
+	for _, bucket := range box {
+		count += bucket.count
+		r += bucket.r
+		g += bucket.g
+		b += bucket.b
+	}
+	return color.NRGBA{R: uint8(r / count), G: uint8(g / count), B: uint8(b / count), A: 0xff}
+}
+
+// medianCut 反复沿最宽通道在像素中位数处切分得分最高的盒，直到得到 n 个盒
+// 得分为跨度乘以像素数，使大面积的渐变比零星的杂色获得更多颜色
+func medianCut(buckets []*colorBucket, n int) []colorBox {
+	boxes := []colorBox{buckets}
+	for len(boxes) < n {
+		best, bestScore := -1, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			_, span := box.widest()
+			if score := span * box.population(); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		ch, _ := box.widest()
+		sort.SliceStable(box, func(i, j int) bool { return box[i].channel(ch) < box[j].channel(ch) })
+
+		half, acc, cut := box.population()/2, 0, 1
+		for i, bucket := range box[:len(box)-1] {
+			acc += bucket.count
+			if acc >= half {
+				cut = i + 1
+				break
+			}
+		}
+		boxes[best] = box[:cut]
+		boxes = append(boxes, box[cut:])
+	}
+	return boxes
+}
+
+// binarizeAlpha 将图像转换为非预乘的 NRGBA，alpha 低于阈值的像素置为全透明，其余置为不透明
+func binarizeAlpha(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		if out.Pix[i+3] < alphaThreshold {
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = 0, 0, 0, 0
+		} else {
+			out.Pix[i+3] = 0xff
+		}
+	}
+	return out
+}
+
+// mapToPalette 将图像映射到调色板，dither 为 true 时使用 Floyd-Steinberg 抖动
+func mapToPalette(src *image.NRGBA, palette color.Palette, dither bool) *image.Paletted {
+	b := src.Bounds()
+	out := image.NewPaletted(b, palette)
+	if dither {
+		draw.FloydSteinberg.Draw(out, b, src, b.Min)
+		return out
+	}
+
+	// 图标的颜色数有限，缓存每种颜色的最近项
+	cache := make(map[color.NRGBA]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := src.NRGBAAt(x, y)
+			idx, ok := cache[c]
+			if !ok {
+				idx = uint8(palette.Index(c))
+				cache[c] = idx
+			}
+			out.Pix[out.PixOffset(x, y)] = idx
+		}
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"image/color"
+	"math"
+
+	"github.com/bagaking/iconmarker/filter"
+)
+
+// renderKeyVersion 在渲染逻辑发生不兼容变化时递增，使旧的缓存键全部失效
+const renderKeyVersion = 2
+
+// CacheKeyer 可由 filter.FilterOption 实现，提供自定义的规范化缓存键
+// 未实现时使用类型名和 JSON 序列化结果
+type CacheKeyer interface {
+	CacheKey() string
+}
+
+// keyBuilder 以固定顺序和长度前缀写入字段，生成规范化的渲染参数哈希
+type keyBuilder struct {
+	h hash.Hash
+}
+
+func newKeyBuilder() *keyBuilder {
+	kb := &keyBuilder{h: sha256.New()}
+	kb.writeInt(renderKeyVersion)
+	return kb
+}
+
+func (kb *keyBuilder) writeBytes(b []byte) {
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+	kb.h.Write(l[:])
+	kb.h.Write(b)
+}
+
+func (kb *keyBuilder) writeString(s string) {
+	kb.writeBytes([]byte(s))
+}
+
+func (kb *keyBuilder) writeInt(v int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(int64(v)))
+	kb.h.Write(b[:])
+}
+
+func (kb *keyBuilder) writeFloat(v float64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	kb.h.Write(b[:])
+}
+
+// writeColor 写入颜色的 16 位预乘 RGBA 分量，nil 颜色与任何实际颜色都不同
+func (kb *keyBuilder) writeColor(c color.Color) {
+	if c == nil {
+		kb.writeString("nil")
+		return
+	}
+	r, g, b, a := c.RGBA()
+	kb.writeString("rgba")
+	kb.writeInt(int(r))
+	kb.writeInt(int(g))
+	kb.writeInt(int(b))
+	kb.writeInt(int(a))
+}
+
+// writeData 写入数据的摘要，避免把大块背景和字体数据重复送入哈希
+func (kb *keyBuilder) writeData(data []byte) {
+	sum := sha256.Sum256(data)
+	kb.writeBytes(sum[:])
+}
+
+// writeTextOption 写入 DrawTextOption 的全部字段
+func (kb *keyBuilder) writeTextOption(opt DrawTextOption) {
+	kb.writeColor(opt.FontColor)
+	kb.writeFloat(opt.FontSize)
+	kb.writeInt(opt.MaxWidth)
+	kb.writeInt(opt.MaxHeight)
+	kb.writeString(opt.Text)
+	kb.writeInt(opt.YOffset)
+	kb.writeInt(opt.XOffset)
+	kb.writeInt(len(opt.Effect))
+	for _, e := range opt.Effect {
+		kb.writeString(e.Type)
+		kb.writeColor(e.Color)
+		kb.writeInt(e.XOffset)
+		kb.writeInt(e.YOffset)
+	}
+}
+
+// writeFilters 写入滤镜链，包括滤镜名称和对应选项
+func (kb *keyBuilder) writeFilters(filters []string, options []filter.FilterOption) {
+	kb.writeInt(len(filters))
+	for i, name := range filters {
+		kb.writeString(name)
+
+		var option filter.FilterOption
+		if i < len(options) {
+			option = options[i]
+		}
+		kb.writeFilterOption(option)
+	}
+}
+
+func (kb *keyBuilder) writeFilterOption(option filter.FilterOption) {
+	if option == nil {
+		kb.writeString("nil")
+		return
+	}
+
+	kb.writeString(fmt.Sprintf("%T", option))
+	if keyer, ok := option.(CacheKeyer); ok {
+		kb.writeString(keyer.CacheKey())
+		return
+	}
+	if data, err := json.Marshal(option); err == nil {
+		kb.writeBytes(data)
+		return
+	}
+	kb.writeString(fmt.Sprintf("%+v", option))
+}
+
+func (kb *keyBuilder) sum() string {
+	return hex.EncodeToString(kb.h.Sum(nil))
+}
+
+// OutputCacheKey 计算一次渲染的规范化缓存键，按指定格式的默认参数编码
+// 等同于 OutputCacheKeyWithOptions 传入 EncodeOptions{Format: format}
+func OutputCacheKey(fontBytes, backgroundBytes []byte,
+	filters []string, filterOptions []filter.FilterOption,
+	format ImageFormat, drawFontOpt ...DrawTextOption) string {
+	return OutputCacheKeyWithOptions(fontBytes, backgroundBytes, filters, filterOptions,
+		EncodeOptions{Format: format}, drawFontOpt...)
+}
+
+// OutputCacheKeyWithOptions 计算一次渲染的规范化缓存键
+// 键覆盖背景、字体、每个 DrawTextOption 的全部字段、滤镜链以及编码选项，
+// 任一参数变化都会得到不同的键；编码选项中与格式无关的字段和取默认值的零值不影响键
+func OutputCacheKeyWithOptions(fontBytes, backgroundBytes []byte,
+	filters []string, filterOptions []filter.FilterOption,
+	opts EncodeOptions, drawFontOpt ...DrawTextOption) string {
+
+	kb := newKeyBuilder()
+	kb.writeData(backgroundBytes)
+	kb.writeData(fontBytes)
+
+	kb.writeInt(len(drawFontOpt))
+	for _, opt := range drawFontOpt {
+		kb.writeTextOption(opt)
+	}
+
+	kb.writeFilters(filters, filterOptions)
+	kb.writeEncodeOptions(opts)
+	return kb.sum()
+}
+
+// writeEncodeOptions 写入按格式规范化后的编码选项，只写入该格式实际使用的字段
+func (kb *keyBuilder) writeEncodeOptions(opts EncodeOptions) {
+	format := opts.Format
+	if format == "" {
+		format = FormatPNG
+	}
+	kb.writeString(string(format))
+
+	switch format {
+	case FormatPNG:
+		kb.writeInt(int(opts.PNGCompression))
+	case FormatJPEG:
+		quality := opts.Quality
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		kb.writeInt(quality)
+		matte := opts.Matte
+		if matte == nil {
+			matte = color.White
+		}
+		kb.writeColor(matte)
+	case FormatGIF:
+		colors := opts.GIFColors
+		if colors == 0 {
+			colors = DefaultGIFColors
+		}
+		kb.writeInt(colors)
+		if opts.GIFDither {
+			kb.writeInt(1)
+		} else {
+			kb.writeInt(0)
+		}
+	}
+}
+
+// SpecCacheKey 计算按 IconSpec 渲染并以指定格式编码的结果的规范化键
+// 键由文档的 JSON 序列化计算，内容相同的文档得到相同的键
+func SpecCacheKey(spec *IconSpec, format ImageFormat) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("%w, error serializing spec", err)
+	}
+
+	kb := newKeyBuilder()
+	kb.writeString("spec")
+	kb.writeBytes(data)
+	kb.writeString(string(format))
+	return kb.sum(), nil
+}
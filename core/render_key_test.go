@@ -0,0 +1,276 @@
+package core
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/cache"
+	"github.com/bagaking/iconmarker/filter"
+)
+
+var (
+	keyFont       = []byte("font")
+	keyBackground = []byte("background")
+)
+
+// baseTextOption sets every DrawTextOption field so each can be varied alone
+func baseTextOption() DrawTextOption {
+	return DrawTextOption{
+		FontColor: color.RGBA{R: 10, G: 20, B: 30, A: 255},
+		FontSize:  24,
+		MaxWidth:  100,
+		MaxHeight: 50,
+		Text:      "hi",
+		YOffset:   1,
+		XOffset:   2,
+		Effect: []FontEffect{
+			{Type: EShadow, Color: color.Black, XOffset: 1, YOffset: 1},
+		},
+	}
+}
+
+func TestOutputCacheKeyCoversTextOptionFields(t *testing.T) {
+	base := OutputCacheKey(keyFont, keyBackground, nil, nil, FormatPNG, baseTextOption())
+	if again := OutputCacheKey(keyFont, keyBackground, nil, nil, FormatPNG, baseTextOption()); again != base {
+		t.Fatalf("identical parameters produced different keys")
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*DrawTextOption)
+	}{
+		{"FontColor", func(o *DrawTextOption) { o.FontColor = color.RGBA{R: 11, G: 20, B: 30, A: 255} }},
+		{"nil FontColor", func(o *DrawTextOption) { o.FontColor = nil }},
+		{"FontSize", func(o *DrawTextOption) { o.FontSize = 25 }},
+		{"MaxWidth", func(o *DrawTextOption) { o.MaxWidth = 101 }},
+		{"MaxHeight", func(o *DrawTextOption) { o.MaxHeight = 51 }},
+		{"Text", func(o *DrawTextOption) { o.Text = "ho" }},
+		{"YOffset", func(o *DrawTextOption) { o.YOffset = 3 }},
+		{"XOffset", func(o *DrawTextOption) { o.XOffset = 3 }},
+		{"Effect.Type", func(o *DrawTextOption) { o.Effect[0].Type = EOutline }},
+		{"Effect.Color", func(o *DrawTextOption) { o.Effect[0].Color = color.White }},
+		{"Effect.XOffset", func(o *DrawTextOption) { o.Effect[0].XOffset = 2 }},
+		{"Effect.YOffset", func(o *DrawTextOption) { o.Effect[0].YOffset = 2 }},
+		{"no effects", func(o *DrawTextOption) { o.Effect = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := baseTextOption()
+			tt.modify(&opt)
+			if OutputCacheKey(keyFont, keyBackground, nil, nil, FormatPNG, opt) == base {
+				t.Errorf("changing %s did not change the key", tt.name)
+			}
+		})
+	}
+}
+
+func TestOutputCacheKeyInputs(t *testing.T) {
+	opt := baseTextOption()
+	filters := []string{"tint", "opacity"}
+	options := []filter.FilterOption{
+		filter.TintOption{Color: [3]uint8{0, 0, 255}, Intensity: 0.5},
+		filter.OpacityOption{Opacity: 0.8},
+	}
+	base := OutputCacheKey(keyFont, keyBackground, filters, options, FormatPNG, opt)
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"font", OutputCacheKey([]byte("other"), keyBackground, filters, options, FormatPNG, opt)},
+		{"background", OutputCacheKey(keyFont, []byte("other"), filters, options, FormatPNG, opt)},
+		{"format", OutputCacheKey(keyFont, keyBackground, filters, options, FormatJPEG, opt)},
+		{"filter order", OutputCacheKey(keyFont, keyBackground,
+			[]string{"opacity", "tint"}, []filter.FilterOption{options[1], options[0]}, FormatPNG, opt)},
+		{"filter option order", OutputCacheKey(keyFont, keyBackground,
+			filters, []filter.FilterOption{options[1], options[0]}, FormatPNG, opt)},
+		{"filter option value", OutputCacheKey(keyFont, keyBackground,
+			filters, []filter.FilterOption{options[0], filter.OpacityOption{Opacity: 0.9}}, FormatPNG, opt)},
+		{"missing filter option", OutputCacheKey(keyFont, keyBackground, filters, options[:1], FormatPNG, opt)},
+		{"text option count", OutputCacheKey(keyFont, keyBackground, filters, options, FormatPNG, opt, opt)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.key == base {
+				t.Errorf("changing the %s did not change the key", tt.name)
+			}
+		})
+	}
+}
+
+func TestOutputCacheKeyEncodeOptions(t *testing.T) {
+	key := func(opts EncodeOptions) string {
+		return OutputCacheKeyWithOptions(keyFont, keyBackground, nil, nil, opts, baseTextOption())
+	}
+
+	same := []struct {
+		name string
+		a, b EncodeOptions
+	}{
+		{"default GIF colors", EncodeOptions{Format: FormatGIF},
+			EncodeOptions{Format: FormatGIF, GIFColors: DefaultGIFColors}},
+		{"empty format is PNG", EncodeOptions{}, EncodeOptions{Format: FormatPNG}},
+		{"default JPEG quality", EncodeOptions{Format: FormatJPEG},
+			EncodeOptions{Format: FormatJPEG, Quality: DefaultJPEGQuality}},
+		{"default JPEG matte", EncodeOptions{Format: FormatJPEG},
+			EncodeOptions{Format: FormatJPEG, Matte: color.White}},
+		{"JPEG ignores GIF fields", EncodeOptions{Format: FormatJPEG},
+			EncodeOptions{Format: FormatJPEG, GIFColors: 16, GIFDither: true}},
+		{"PNG ignores JPEG fields", EncodeOptions{Format: FormatPNG},
+			EncodeOptions{Format: FormatPNG, Quality: 50, Matte: color.Black}},
+	}
+	for _, tt := range same {
+		t.Run(tt.name, func(t *testing.T) {
+			if key(tt.a) != key(tt.b) {
+				t.Errorf("%+v and %+v produced different keys", tt.a, tt.b)
+			}
+		})
+	}
+	if OutputCacheKey(keyFont, keyBackground, nil, nil, FormatJPEG, baseTextOption()) !=
+		key(EncodeOptions{Format: FormatJPEG}) {
+		t.Errorf("OutputCacheKey differs from OutputCacheKeyWithOptions with default options")
+	}
+
+	different := []struct {
+		name string
+		a, b EncodeOptions
+	}{
+		{"JPEG quality", EncodeOptions{Format: FormatJPEG, Quality: 80}, EncodeOptions{Format: FormatJPEG, Quality: 95}},
+		{"JPEG matte", EncodeOptions{Format: FormatJPEG}, EncodeOptions{Format: FormatJPEG, Matte: color.Black}},
+		{"PNG compression", EncodeOptions{Format: FormatPNG}, EncodeOptions{Format: FormatPNG, PNGCompression: png.BestSpeed}},
+		{"GIF colors", EncodeOptions{Format: FormatGIF}, EncodeOptions{Format: FormatGIF, GIFColors: 16}},
+		{"GIF dither", EncodeOptions{Format: FormatGIF}, EncodeOptions{Format: FormatGIF, GIFDither: true}},
+	}
+	for _, tt := range different {
+		t.Run(tt.name, func(t *testing.T) {
+			if key(tt.a) == key(tt.b) {
+				t.Errorf("%+v and %+v produced the same key", tt.a, tt.b)
+			}
+		})
+	}
+}
+
+// renderFixtures returns the default font and a small JPEG background
+func renderFixtures(t *testing.T) (fontBytes, bgBytes []byte) {
+	t.Helper()
+	fontBytes, err := assets.GetDefaultFont()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bg := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range bg.Pix {
+		bg.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, bg, nil); err != nil {
+		t.Fatal(err)
+	}
+	return fontBytes, buf.Bytes()
+}
+
+func textAt(x int) DrawTextOption {
+	return DrawTextOption{FontColor: color.Black, FontSize: 20, Text: "A", XOffset: x}
+}
+
+func TestOutputCacheInvalidateOutput(t *testing.T) {
+	fontBytes, bgBytes := renderFixtures(t)
+	im := NewIconMarker()
+	im.EnableOutputCache(0)
+	images := im.GetResourceManager().Images()
+
+	data, err := im.CreateImgEncoded(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := OutputCacheKey(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(0))
+	if _, ok := images.Get(key); !ok {
+		t.Fatalf("output was not cached under OutputCacheKey")
+	}
+
+	// callers may modify the returned bytes without corrupting the cache
+	data[0] ^= 0xff
+	cached, err := im.CreateImgEncoded(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached[0] == data[0] {
+		t.Errorf("cached output shares memory with a returned slice")
+	}
+
+	other := OutputCacheKey(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(1))
+	if _, err = im.CreateImgEncoded(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	im.InvalidateOutput(key)
+	if _, ok := images.Get(key); ok {
+		t.Errorf("InvalidateOutput left the output cached")
+	}
+	if _, ok := images.Get(other); !ok {
+		t.Errorf("InvalidateOutput removed an unrelated output")
+	}
+
+	im.InvalidateOutputCache()
+	if _, ok := images.Get(other); ok {
+		t.Errorf("InvalidateOutputCache left outputs cached")
+	}
+}
+
+func TestOutputCacheByteBudget(t *testing.T) {
+	fontBytes, bgBytes := renderFixtures(t)
+	im := NewIconMarker()
+	images := im.GetResourceManager().Images()
+	limiter := im.GetResourceManager().GetImageCache().(cache.ByteLimiter)
+
+	data, err := im.CreateImgEncoded(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the budget fits two outputs of roughly this size, never three
+	budget := int64(len(data))*5/2 + 64
+	im.EnableOutputCache(budget)
+
+	const renders = 6
+	for x := 0; x < renders; x++ {
+		if _, err = im.CreateImgEncoded(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(x)); err != nil {
+			t.Fatal(err)
+		}
+		if got := limiter.Bytes(); got > budget {
+			t.Fatalf("cache holds %d bytes after render %d, budget %d", got, x, budget)
+		}
+	}
+
+	last := OutputCacheKey(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(renders-1))
+	if _, ok := images.Get(last); !ok {
+		t.Errorf("most recent output was evicted")
+	}
+	first := OutputCacheKey(fontBytes, bgBytes, nil, nil, FormatPNG, textAt(0))
+	if _, ok := images.Get(first); ok {
+		t.Errorf("oldest output survived a full budget")
+	}
+}
+
+func TestCreateImgEncodedWithOptions(t *testing.T) {
+	fontBytes, bgBytes := renderFixtures(t)
+	im := NewIconMarker()
+	im.EnableOutputCache(0)
+
+	low, err := im.CreateImgEncodedWithOptions(fontBytes, bgBytes, nil, nil,
+		EncodeOptions{Format: FormatJPEG, Quality: 10}, textAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, err := im.CreateImgEncodedWithOptions(fontBytes, bgBytes, nil, nil,
+		EncodeOptions{Format: FormatJPEG, Quality: 100}, textAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(low, high) {
+		t.Errorf("JPEG quality did not reach the encoder or the cache key")
+	}
+}
@@ -0,0 +1,266 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ResizeKernel 表示缩放时使用的插值核
+type ResizeKernel string
+
+// 支持的插值核，质量与耗时依次递增
+const (
+	KernelNearest    ResizeKernel = "nearest"
+	KernelBilinear   ResizeKernel = "bilinear"
+	KernelCatmullRom ResizeKernel = "catmullrom"
+	KernelLanczos    ResizeKernel = "lanczos"
+)
+
+// Lanczos3 是支撑半径为 3 的 Lanczos 插值核，适合大比例缩小照片
+var Lanczos3 = &xdraw.Kernel{
+	Support: 3,
+	At: func(t float64) float64 {
+		if t == 0 {
+			return 1
+		}
+		if t >= 3 {
+			return 0
+		}
+		x := math.Pi * t
+		return 3 * math.Sin(x) * math.Sin(x/3) / (x * x)
+	},
+}
+
+// interpolator 返回插值核对应的 x/image/draw 实现，空值使用 CatmullRom
+func (k ResizeKernel) interpolator() (xdraw.Interpolator, error) {
+	switch k {
+	case KernelNearest:
+		return xdraw.NearestNeighbor, nil
+	case KernelBilinear:
+		return xdraw.ApproxBiLinear, nil
+	case KernelCatmullRom, "":
+		return xdraw.CatmullRom, nil
+	case KernelLanczos:
+		return Lanczos3, nil
+	default:
+		return nil, fmt.Errorf("unknown resize kernel: %q", k)
+	}
+}
+
+// FitMode 表示源图像与目标尺寸宽高比不一致时的适配方式
+type FitMode string
+
+// 支持的适配方式
+const (
+	FitCover      FitMode = "cover"       // 等比缩放至铺满目标，裁掉超出部分
+	FitContain    FitMode = "contain"     // 等比缩放至完整放入目标，空白处填充背景色
+	FitStretch    FitMode = "stretch"     // 不保持宽高比，直接拉伸到目标尺寸
+	FitCenterCrop FitMode = "center-crop" // 不缩放，按原始像素裁出目标尺寸
+)
+
+// validate 检查适配方式是否受支持，空值表示 FitCover
+func (m FitMode) validate() error {
+	switch m {
+	case FitCover, FitContain, FitStretch, FitCenterCrop, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown fit mode: %q", m)
+	}
+}
+
+// FocalPoint 是以源图像宽高归一化的焦点坐标，(0.5, 0.5) 为中心
+type FocalPoint struct {
+	X, Y float64
+}
+
+// ResizeOption 描述缩放和裁剪的目标
+type ResizeOption struct {
+	Width, Height int          // 目标尺寸，均为 0 时表示不缩放
+	Fit           FitMode      // 适配方式，为空时使用 FitCover
+	Kernel        ResizeKernel // 插值核，为空时使用 KernelCatmullRom
+	Focus         *FocalPoint  // 裁剪时尽量保持在中心的焦点，为 nil 时居中裁剪
+	SmartCrop     bool         // Focus 为 nil 时通过 SmartFocus 自动寻找焦点
+	Background    color.Color  // FitContain 等留白处的填充色，为 nil 时透明
+}
+
+// IsZero 判断是否未指定目标尺寸
+func (opt ResizeOption) IsZero() bool {
+	return opt.Width == 0 && opt.Height == 0
+}
+
+// Resize 将图像拉伸缩放到指定尺寸
+func Resize(src image.Image, width, height int, kernel ResizeKernel) (*image.RGBA, error) {
+	return Fit(src, ResizeOption{Width: width, Height: height, Fit: FitStretch, Kernel: kernel})
+}
+
+// Fit 按适配方式将图像缩放、裁剪到目标尺寸
+// 只指定宽或高之一时，另一边按源图像宽高比推算
+func Fit(src image.Image, opt ResizeOption) (*image.RGBA, error) {
+	width, height, err := opt.targetSize(src.Bounds())
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err = drawFitted(dst, dst.Bounds(), src, opt); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// drawFitted 按适配方式将 src 绘制到 dst 的 r 区域内
+func drawFitted(dst draw.Image, r image.Rectangle, src image.Image, opt ResizeOption) error {
+	interp, err := opt.Kernel.interpolator()
+	if err != nil {
+		return err
+	}
+
+	if opt.Background != nil {
+		draw.Draw(dst, r, image.NewUniform(opt.Background), image.Point{}, draw.Src)
+	}
+
+	srcRect, dstRect, err := fitRects(src, r, opt)
+	if err != nil {
+		return err
+	}
+	if srcRect.Empty() || dstRect.Empty() {
+		return nil
+	}
+
+	// 透明像素叠加在 dst 已有内容之上，对新建的透明图像等同于直接覆盖
+	if srcRect.Size() == dstRect.Size() {
+		draw.Draw(dst, dstRect, src, srcRect.Min, draw.Over)
+		return nil
+	}
+	interp.Scale(dst, dstRect, src, srcRect, xdraw.Over, nil)
+	return nil
+}
+
+// fitRects 计算从源图像取样的区域以及在目标中绘制的区域
+func fitRects(src image.Image, r image.Rectangle, opt ResizeOption) (srcRect, dstRect image.Rectangle, err error) {
+	sb := src.Bounds()
+	sw, sh := float64(sb.Dx()), float64(sb.Dy())
+	dw, dh := float64(r.Dx()), float64(r.Dy())
+	if sw <= 0 || sh <= 0 {
+		return image.Rectangle{}, image.Rectangle{}, nil
+	}
+
+	switch opt.Fit {
+	case FitStretch:
+		return sb, r, nil
+
+	case FitContain:
+		scale := math.Min(dw/sw, dh/sh)
+		w, h := int(math.Round(sw*scale)), int(math.Round(sh*scale))
+		x := r.Min.X + (r.Dx()-w)/2
+		y := r.Min.Y + (r.Dy()-h)/2
+		return sb, image.Rect(x, y, x+w, y+h), nil
+
+	case FitCover, "":
+		scale := math.Max(dw/sw, dh/sh)
+		w, h := int(math.Round(dw/scale)), int(math.Round(dh/scale))
+		return cropAround(sb, w, h, opt.focus(src)), r, nil
+
+	case FitCenterCrop:
+		// 源图像小于目标时不放大，居中放置在目标区域内
+		w, h := min(sb.Dx(), r.Dx()), min(sb.Dy(), r.Dy())
+		x := r.Min.X + (r.Dx()-w)/2
+		y := r.Min.Y + (r.Dy()-h)/2
+		return cropAround(sb, w, h, opt.focus(src)), image.Rect(x, y, x+w, y+h), nil
+
+	default:
+		return image.Rectangle{}, image.Rectangle{}, fmt.Errorf("unknown fit mode: %q", opt.Fit)
+	}
+}
+
+// cropAround 返回 sb 内大小为 w×h 的区域，使焦点尽量位于区域中心
+func cropAround(sb image.Rectangle, w, h int, focus FocalPoint) image.Rectangle {
+	w, h = min(w, sb.Dx()), min(h, sb.Dy())
+	cx := sb.Min.X + int(math.Round(focus.X*float64(sb.Dx())))
+	cy := sb.Min.Y + int(math.Round(focus.Y*float64(sb.Dy())))
+
+	x := min(max(cx-w/2, sb.Min.X), sb.Max.X-w)
+	y := min(max(cy-h/2, sb.Min.Y), sb.Max.Y-h)
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// focus 返回裁剪使用的焦点
+func (opt ResizeOption) focus(src image.Image) FocalPoint {
+	switch {
+	case opt.Focus != nil:
+		return FocalPoint{X: clamp01(opt.Focus.X), Y: clamp01(opt.Focus.Y)}
+	case opt.SmartCrop:
+		return SmartFocus(src)
+	default:
+		return FocalPoint{X: 0.5, Y: 0.5}
+	}
+}
+
+// targetSize 返回目标尺寸，缺省的一边按源图像宽高比推算
+func (opt ResizeOption) targetSize(sb image.Rectangle) (int, int, error) {
+	width, height := opt.Width, opt.Height
+	if width < 0 || height < 0 || (width == 0 && height == 0) {
+		return 0, 0, fmt.Errorf("invalid resize target: width=%d, height=%d", width, height)
+	}
+	if sb.Empty() {
+		return 0, 0, fmt.Errorf("empty source image")
+	}
+
+	if width == 0 {
+		width = max(int(math.Round(float64(height)*float64(sb.Dx())/float64(sb.Dy()))), 1)
+	}
+	if height == 0 {
+		height = max(int(math.Round(float64(width)*float64(sb.Dy())/float64(sb.Dx()))), 1)
+	}
+	return width, height, nil
+}
+
+// smartFocusSample 是 SmartFocus 采样网格的边长
+const smartFocusSample = 64
+
+// SmartFocus 估计图像中细节最丰富的位置，用作智能裁剪的焦点
+// 在缩小的采样网格上计算亮度梯度，并取以梯度强度加权的重心；
+// 对于纯色等没有细节的图像返回中心点
+func SmartFocus(img image.Image) FocalPoint {
+	b := img.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return FocalPoint{X: 0.5, Y: 0.5}
+	}
+
+	cols, rows := min(smartFocusSample, b.Dx()), min(smartFocusSample, b.Dy())
+	lum := make([]float64, cols*rows)
+	for j := 0; j < rows; j++ {
+		y := b.Min.Y + (2*j+1)*b.Dy()/(2*rows)
+		for i := 0; i < cols; i++ {
+			x := b.Min.X + (2*i+1)*b.Dx()/(2*cols)
+			r, g, bl, _ := img.At(x, y).RGBA()
+			lum[j*cols+i] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+		}
+	}
+
+	var sum, sx, sy float64
+	for j := 1; j < rows-1; j++ {
+		for i := 1; i < cols-1; i++ {
+			gx := lum[j*cols+i+1] - lum[j*cols+i-1]
+			gy := lum[(j+1)*cols+i] - lum[(j-1)*cols+i]
+			energy := math.Hypot(gx, gy)
+			sum += energy
+			sx += energy * (float64(i) + 0.5)
+			sy += energy * (float64(j) + 0.5)
+		}
+	}
+	if sum == 0 {
+		return FocalPoint{X: 0.5, Y: 0.5}
+	}
+	return FocalPoint{X: sx / sum / float64(cols), Y: sy / sum / float64(rows)}
+}
+
+// clamp01 将数值限制在 [0, 1] 内
+func clamp01(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
@@ -0,0 +1,344 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/renderer"
+)
+
+// IconSpecVersion 是当前 IconSpec 文档的版本号
+const IconSpecVersion = 1
+
+// LayerType 表示图层类型
+type LayerType string
+
+// 支持的图层类型
+const (
+	LayerSVG   LayerType = "svg"
+	LayerText  LayerType = "text"
+	LayerImage LayerType = "image"
+)
+
+// IconSpec 是描述一个图标的声明式文档，可以用 JSON 或 YAML 表示
+// 渲染顺序为：背景、按顺序绘制的图层、作用于整个画布的滤镜链
+type IconSpec struct {
+	Version    int              `json:"version"`
+	Canvas     CanvasSpec       `json:"canvas"`
+	Font       []byte           `json:"font,omitempty"`       // 文本图层使用的字体，为空时使用默认字体，JSON 中为 base64
+	Background *background.Spec `json:"background,omitempty"` // 生成的背景，宽高为 0 时与画布一致
+	Layers     []LayerSpec      `json:"layers,omitempty"`
+	Filters    []FilterSpec     `json:"filters,omitempty"`
+}
+
+// CanvasSpec 描述画布尺寸
+type CanvasSpec struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// LayerSpec 描述一个图层
+// 图层绘制在 (X, Y) 处大小为 Width×Height 的区域内，宽高为 0 时延伸到画布边缘；
+// 各类型只使用与其相关的字段
+type LayerSpec struct {
+	Type LayerType `json:"type"`
+
+	Icon string `json:"icon,omitempty"` // svg: 内嵌图标名称，见 assets.ListAvailableIcons
+	SVG  string `json:"svg,omitempty"`  // svg: 内联 SVG 源码，与 Icon 二选一
+
+	Colors *renderer.SVGColorMap `json:"colors,omitempty"` // svg: 栅格化前替换的颜色，保留多色图标的明暗层次
+
+	Text *DrawTextOption `json:"text,omitempty"` // text: 文本及其阴影、描边效果
+
+	Image []byte  `json:"image,omitempty"` // image: JPEG、PNG 或 GIF 数据，JSON 中为 base64
+	Fit   FitMode `json:"fit,omitempty"`   // image: 适配方式，为空时使用 FitCover
+
+	X       int          `json:"x,omitempty"`
+	Y       int          `json:"y,omitempty"`
+	Width   int          `json:"width,omitempty"`
+	Height  int          `json:"height,omitempty"`
+	Opacity *float64     `json:"opacity,omitempty"` // 图层不透明度，为 nil 时完全不透明
+	Effects []FilterSpec `json:"effects,omitempty"` // 合成前作用于该图层的滤镜链
+}
+
+// FilterSpec 按注册名称引用一个滤镜
+// Options 在渲染时由 FilterManager 中注册的选项解码器解析；
+// 在代码中构造时也可以直接设置 Option，此时忽略 Options
+type FilterSpec struct {
+	Name    string              `json:"name"`
+	Options json.RawMessage     `json:"options,omitempty"`
+	Option  filter.FilterOption `json:"-"`
+}
+
+// filterSpecJSON 是 FilterSpec 的序列化形式
+type filterSpecJSON struct {
+	Name    string          `json:"name"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// MarshalJSON 实现 json.Marshaler，Option 不为 nil 时序列化 Option
+func (f FilterSpec) MarshalJSON() ([]byte, error) {
+	out := filterSpecJSON{Name: f.Name, Options: f.Options}
+	if f.Option != nil {
+		data, err := json.Marshal(f.Option)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q options: %w", f.Name, err)
+		}
+		out.Options = data
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，拒绝未知字段
+func (f *FilterSpec) UnmarshalJSON(data []byte) error {
+	var in filterSpecJSON
+	if err := decodeStrict(data, &in, ""); err != nil {
+		return err
+	}
+	*f = FilterSpec{Name: in.Name, Options: in.Options}
+	return nil
+}
+
+// iconSpecWire 用于反序列化 IconSpec，需要报告内部路径的字段先保留为原始 JSON
+type iconSpecWire struct {
+	Version    int               `json:"version"`
+	Canvas     CanvasSpec        `json:"canvas"`
+	Font       []byte            `json:"font,omitempty"`
+	Background json.RawMessage   `json:"background,omitempty"`
+	Layers     []json.RawMessage `json:"layers,omitempty"`
+	Filters    []json.RawMessage `json:"filters,omitempty"`
+}
+
+// layerSpecWire 用于反序列化 LayerSpec
+type layerSpecWire struct {
+	Type    LayerType         `json:"type"`
+	Icon    string            `json:"icon,omitempty"`
+	SVG     string            `json:"svg,omitempty"`
+	Colors  json.RawMessage   `json:"colors,omitempty"`
+	Text    json.RawMessage   `json:"text,omitempty"`
+	Image   []byte            `json:"image,omitempty"`
+	Fit     FitMode           `json:"fit,omitempty"`
+	X       int               `json:"x,omitempty"`
+	Y       int               `json:"y,omitempty"`
+	Width   int               `json:"width,omitempty"`
+	Height  int               `json:"height,omitempty"`
+	Opacity *float64          `json:"opacity,omitempty"`
+	Effects []json.RawMessage `json:"effects,omitempty"`
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+// 拒绝未知字段，出错时返回带 JSON 路径的 *SpecError
+func (s *IconSpec) UnmarshalJSON(data []byte) error {
+	var in iconSpecWire
+	if err := decodeStrict(data, &in, "$"); err != nil {
+		return err
+	}
+
+	out := IconSpec{Version: in.Version, Canvas: in.Canvas, Font: in.Font}
+	if len(in.Background) > 0 && !isJSONNull(in.Background) {
+		out.Background = new(background.Spec)
+		if err := decodeStrict(in.Background, out.Background, "$.background"); err != nil {
+			return err
+		}
+	}
+
+	for i, raw := range in.Layers {
+		layer, err := decodeLayer(raw, fmt.Sprintf("$.layers[%d]", i))
+		if err != nil {
+			return err
+		}
+		out.Layers = append(out.Layers, layer)
+	}
+
+	filters, err := decodeFilterSpecs(in.Filters, "$.filters")
+	if err != nil {
+		return err
+	}
+	out.Filters = filters
+
+	*s = out
+	return nil
+}
+
+// decodeLayer 解析一个图层
+func decodeLayer(data []byte, path string) (LayerSpec, error) {
+	var in layerSpecWire
+	if err := decodeStrict(data, &in, path); err != nil {
+		return LayerSpec{}, err
+	}
+
+	out := LayerSpec{
+		Type:    in.Type,
+		Icon:    in.Icon,
+		SVG:     in.SVG,
+		Image:   in.Image,
+		Fit:     in.Fit,
+		X:       in.X,
+		Y:       in.Y,
+		Width:   in.Width,
+		Height:  in.Height,
+		Opacity: in.Opacity,
+	}
+	if len(in.Colors) > 0 && !isJSONNull(in.Colors) {
+		out.Colors = new(renderer.SVGColorMap)
+		if err := decodeStrict(in.Colors, out.Colors, path+".colors"); err != nil {
+			return LayerSpec{}, err
+		}
+	}
+	if len(in.Text) > 0 && !isJSONNull(in.Text) {
+		out.Text = new(DrawTextOption)
+		if err := decodeStrict(in.Text, out.Text, path+".text"); err != nil {
+			return LayerSpec{}, err
+		}
+	}
+
+	effects, err := decodeFilterSpecs(in.Effects, path+".effects")
+	if err != nil {
+		return LayerSpec{}, err
+	}
+	out.Effects = effects
+	return out, nil
+}
+
+// decodeFilterSpecs 解析滤镜链
+func decodeFilterSpecs(raws []json.RawMessage, path string) ([]FilterSpec, error) {
+	var out []FilterSpec
+	for i, raw := range raws {
+		var f FilterSpec
+		if err := decodeStrict(raw, &f, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// ParseIconSpec 解析 JSON 或 YAML 格式的 IconSpec 并做静态校验
+// 以 { 开头的文档按 JSON 解析，其余按 YAML 解析；
+// 滤镜名称和选项依赖 FilterManager，在 IconMarker.ValidateSpec 或渲染时校验
+func ParseIconSpec(data []byte) (*IconSpec, error) {
+	spec := new(IconSpec)
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = spec.UnmarshalJSON(trimmed)
+	} else {
+		err = unmarshalYAMLSpec(data, spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Validate 静态校验 IconSpec，返回包含所有问题的 SpecErrors
+func (s *IconSpec) Validate() error {
+	var errs SpecErrors
+	add := func(path, format string, args ...any) {
+		errs = append(errs, &SpecError{Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	if s.Version != IconSpecVersion {
+		add("$.version", "unsupported version %d, expected %d", s.Version, IconSpecVersion)
+	}
+	if s.Canvas.Width <= 0 || s.Canvas.Height <= 0 {
+		add("$.canvas", "invalid canvas size: width=%d, height=%d", s.Canvas.Width, s.Canvas.Height)
+	}
+
+	if s.Background != nil {
+		if err := s.backgroundSpec().Validate(); err != nil {
+			add("$.background", "%w", err)
+		}
+	}
+
+	for i, layer := range s.Layers {
+		errs = append(errs, layer.validate(fmt.Sprintf("$.layers[%d]", i))...)
+	}
+	for i, f := range s.Filters {
+		if f.Name == "" {
+			add(fmt.Sprintf("$.filters[%d].name", i), "filter name is required")
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate 静态校验图层
+func (l LayerSpec) validate(path string) SpecErrors {
+	var errs SpecErrors
+	add := func(field, format string, args ...any) {
+		errs = append(errs, &SpecError{Path: joinPath(path, field), Err: fmt.Errorf(format, args...)})
+	}
+
+	switch l.Type {
+	case LayerSVG:
+		switch {
+		case l.Icon == "" && l.SVG == "":
+			add("", "svg layer requires icon or svg")
+		case l.Icon != "" && l.SVG != "":
+			add("", "svg layer accepts only one of icon and svg")
+		case l.Icon != "":
+			if _, err := assets.ParseIconType(l.Icon); err != nil {
+				add("icon", "unknown icon %q", l.Icon)
+			}
+		}
+		if err := l.Colors.Validate(); err != nil {
+			add("colors", "%w", err)
+		}
+	case LayerText:
+		if l.Text == nil || l.Text.Text == "" {
+			add("text", "text layer requires text")
+		} else if l.Text.MaxWidth <= 0 && l.Text.FontSize < 1 {
+			add("text", "text layer requires font_size or max_width")
+		}
+	case LayerImage:
+		if len(l.Image) == 0 {
+			add("image", "image layer requires image data")
+		}
+		if err := l.Fit.validate(); err != nil {
+			add("fit", "%w", err)
+		}
+	default:
+		add("type", "unknown layer type %q", l.Type)
+	}
+
+	if l.Width < 0 || l.Height < 0 {
+		add("", "invalid layer size: width=%d, height=%d", l.Width, l.Height)
+	}
+	if l.Opacity != nil && (*l.Opacity < 0 || *l.Opacity > 1) {
+		add("opacity", "opacity %g out of range [0, 1]", *l.Opacity)
+	}
+	for i, f := range l.Effects {
+		if f.Name == "" {
+			add(fmt.Sprintf("effects[%d].name", i), "filter name is required")
+		}
+	}
+	return errs
+}
+
+// backgroundSpec 返回补全画布尺寸后的背景描述
+func (s *IconSpec) backgroundSpec() background.Spec {
+	bg := *s.Background
+	if bg.Width == 0 {
+		bg.Width = s.Canvas.Width
+	}
+	if bg.Height == 0 {
+		bg.Height = s.Canvas.Height
+	}
+	return bg
+}
+
+// isJSONNull 判断原始 JSON 是否为 null
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrInvalidSpec 匹配所有 IconSpec 解析和校验错误，可用 errors.Is 判断
+	ErrInvalidSpec = errors.New("invalid icon spec")
+	// ErrUnknownField 表示文档中出现了未定义的字段
+	ErrUnknownField = errors.New("unknown field")
+)
+
+// SpecError 是 IconSpec 中某个位置的错误，Path 为 JSON 路径，例如 $.layers[0].text.font_color
+type SpecError struct {
+	Path string
+	Err  error
+}
+
+// Error 实现 error
+func (e *SpecError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *SpecError) Unwrap() error {
+	return e.Err
+}
+
+// Is 使所有 SpecError 都能匹配 ErrInvalidSpec
+func (e *SpecError) Is(target error) bool {
+	return target == ErrInvalidSpec
+}
+
+// SpecErrors 是校验 IconSpec 时发现的全部错误
+type SpecErrors []*SpecError
+
+// Error 实现 error
+func (e SpecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap 返回所有错误，使 errors.Is 和 errors.As 能匹配其中任意一个
+func (e SpecErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// decodeStrict 解析单个 JSON 值，拒绝未知字段和多余数据，
+// 错误统一转换为以 path 为前缀的 *SpecError
+func decodeStrict(data []byte, v any, path string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return specErrorAt(path, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return &SpecError{Path: path, Err: errors.New("unexpected data after value")}
+	}
+	return nil
+}
+
+// specErrorAt 将解析错误转换为 *SpecError，尽可能定位到出错的字段
+func specErrorAt(path string, err error) *SpecError {
+	var se *SpecError
+	if errors.As(err, &se) {
+		return &SpecError{Path: joinPath(path, se.Path), Err: se.Err}
+	}
+
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return &SpecError{Path: joinPath(path, te.Field), Err: fmt.Errorf("cannot use %s as %s", te.Value, te.Type)}
+	}
+
+	// encoding/json 对未知字段只返回文本错误
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &SpecError{Path: joinPath(path, strings.Trim(field, `"`)), Err: ErrUnknownField}
+	}
+
+	return &SpecError{Path: path, Err: err}
+}
+
+// joinPath 拼接 JSON 路径
+func joinPath(base, rel string) string {
+	switch {
+	case rel == "":
+		return base
+	case base == "":
+		return rel
+	case strings.HasPrefix(rel, "["):
+		return base + rel
+	default:
+		return base + "." + rel
+	}
+}
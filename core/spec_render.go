@@ -0,0 +1,302 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/renderer"
+	"github.com/golang/freetype/truetype"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// resolvedFilters 是解析完成、可直接交给 FilterManager 的滤镜链
+type resolvedFilters struct {
+	names   []string
+	options []filter.FilterOption
+}
+
+// RenderSpec 按 IconSpec 渲染图像
+func (im *IconMarker) RenderSpec(spec *IconSpec) (*image.RGBA, error) {
+	return im.RenderSpecCtx(context.Background(), spec)
+}
+
+// RenderSpecCtx 按 IconSpec 渲染图像，支持通过 ctx 取消
+// 渲染前完整校验文档，校验失败时返回可用 errors.Is 匹配 ErrInvalidSpec 的 SpecErrors
+func (im *IconMarker) RenderSpecCtx(ctx context.Context, spec *IconSpec) (*image.RGBA, error) {
+	return im.renderSpec(ctx, spec, nil)
+}
+
+// renderSpec 渲染文档，rotations 为各图层绕中心顺时针旋转的角度，为 nil 时都不旋转
+func (im *IconMarker) renderSpec(ctx context.Context, spec *IconSpec, rotations []float64) (*image.RGBA, error) {
+	filters, effects, err := im.resolveSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	lim := im.Limits()
+	if err = im.checkSpecLimits(spec); err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, spec.Canvas.Width, spec.Canvas.Height))
+	if spec.Background != nil {
+		if err = checkCtx(ctx, "generating background"); err != nil {
+			return nil, err
+		}
+		bg, err := background.GenerateCtx(ctx, spec.backgroundSpec())
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(canvas, canvas.Bounds(), bg, image.Point{}, draw.Src)
+	}
+
+	var font *truetype.Font
+	for i, layer := range spec.Layers {
+		if err = checkCtx(ctx, "layer %d", i); err != nil {
+			return nil, err
+		}
+
+		// 字体只在需要时加载，经由字体缓存复用
+		if layer.Type == LayerText && font == nil {
+			if err = lim.CheckInputBytes("font", len(spec.Font)); err != nil {
+				return nil, err
+			}
+			if font, err = im.textRenderer.LoadFont(spec.Font); err != nil {
+				return nil, fmt.Errorf("%w, error parsing font file", err)
+			}
+		}
+
+		var rotation float64
+		if i < len(rotations) {
+			rotation = rotations[i]
+		}
+		if err = im.drawLayer(ctx, canvas, layer, effects[i], font, rotation); err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+	}
+
+	return im.applyFiltersRGBA(ctx, canvas, filters.names, filters.options)
+}
+
+// ValidateSpec 完整校验 IconSpec
+// 在静态校验之外检查滤镜是否已注册，滤镜选项能否被注册的解码器解析，
+// 以及画布和背景的尺寸是否超出资源限制；超出限制时返回 *limits.Error
+func (im *IconMarker) ValidateSpec(spec *IconSpec) error {
+	if _, _, err := im.resolveSpec(spec); err != nil {
+		return err
+	}
+	return im.checkSpecLimits(spec)
+}
+
+// checkSpecLimits 在分配内存之前检查画布和背景的尺寸
+// 背景按自身尺寸生成后才绘制到画布上，因此单独按输入图像的限制检查
+func (im *IconMarker) checkSpecLimits(spec *IconSpec) error {
+	lim := im.Limits()
+	if err := lim.CheckOutputSize(spec.Canvas.Width, spec.Canvas.Height); err != nil {
+		return err
+	}
+	if spec.Background != nil {
+		bg := spec.backgroundSpec()
+		if err := lim.CheckImageSize("background", bg.Width, bg.Height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSpec 校验文档并解析顶层滤镜链和各图层的效果滤镜链
+func (im *IconMarker) resolveSpec(spec *IconSpec) (resolvedFilters, []resolvedFilters, error) {
+	var errs SpecErrors
+	if err := spec.Validate(); err != nil {
+		errs = append(errs, err.(SpecErrors)...)
+	}
+
+	filters, filterErrs := im.resolveFilters(spec.Filters, "$.filters")
+	errs = append(errs, filterErrs...)
+
+	effects := make([]resolvedFilters, len(spec.Layers))
+	for i, layer := range spec.Layers {
+		var effectErrs SpecErrors
+		effects[i], effectErrs = im.resolveFilters(layer.Effects, fmt.Sprintf("$.layers[%d].effects", i))
+		errs = append(errs, effectErrs...)
+	}
+
+	if len(errs) > 0 {
+		return resolvedFilters{}, nil, errs
+	}
+	return filters, effects, nil
+}
+
+// resolveFilters 检查滤镜名称并解码选项
+func (im *IconMarker) resolveFilters(specs []FilterSpec, path string) (resolvedFilters, SpecErrors) {
+	var out resolvedFilters
+	var errs SpecErrors
+	for i, f := range specs {
+		if f.Name == "" {
+			// 空名称已在静态校验中报告
+			continue
+		}
+
+		option := f.Option
+		if option == nil {
+			var err error
+			if option, err = im.filterManager.DecodeOption(f.Name, f.Options); err != nil {
+				at := fmt.Sprintf("%s[%d]", path, i)
+				if _, ok := im.filterManager.Get(f.Name); !ok {
+					errs = append(errs, &SpecError{Path: at + ".name", Err: err})
+				} else {
+					errs = append(errs, specErrorAt(at+".options", err))
+				}
+				continue
+			}
+		}
+
+		out.names = append(out.names, f.Name)
+		out.options = append(out.options, option)
+	}
+	return out, errs
+}
+
+// drawLayer 渲染图层、应用效果滤镜并按不透明度合成到画布上，rotation 不为 0 时绕图层中心旋转
+func (im *IconMarker) drawLayer(ctx context.Context, canvas *image.RGBA, layer LayerSpec,
+	effects resolvedFilters, font *truetype.Font, rotation float64) error {
+	box := layer.box(canvas.Bounds())
+	if box.Empty() {
+		return fmt.Errorf("layer box %v is empty", box)
+	}
+	if err := im.Limits().CheckOutputSize(box.Dx(), box.Dy()); err != nil {
+		return err
+	}
+
+	// 没有效果和不透明度的 SVG 图层直接光栅化到画布上，旋转时也保持矢量精度
+	// 不透明度需要整层合成，逐路径应用会让重叠的形状互相透出
+	if layer.Type == LayerSVG && len(effects.names) == 0 && layer.Opacity == nil {
+		return im.drawSVGLayer(ctx, canvas, layer, box, rotation)
+	}
+
+	var img image.Image
+	var err error
+	switch layer.Type {
+	case LayerSVG:
+		img, err = im.renderSVGLayer(ctx, layer, box.Dx(), box.Dy())
+	case LayerText:
+		rgba := image.NewRGBA(image.Rect(0, 0, box.Dx(), box.Dy()))
+		err = drawTextLayers(ctx, font, rgba, []DrawTextOption{*layer.Text})
+		img = rgba
+	case LayerImage:
+		img, err = im.renderImageLayer(layer, box.Dx(), box.Dy())
+	default:
+		err = fmt.Errorf("unknown layer type %q", layer.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(effects.names) > 0 {
+		if img, err = im.filterManager.ApplyFiltersCtx(ctx, img, effects.names, effects.options); err != nil {
+			return fmt.Errorf("error applying effects: %w", err)
+		}
+	}
+
+	var mask image.Image
+	if layer.Opacity != nil {
+		mask = image.NewUniform(color.Alpha{A: uint8(*layer.Opacity*255 + 0.5)})
+	}
+	if rotation != 0 {
+		drawRotated(canvas, box, img, mask, rotation)
+		return nil
+	}
+	draw.DrawMask(canvas, box, img, img.Bounds().Min, mask, image.Point{}, draw.Over)
+	return nil
+}
+
+// drawRotated 将图层图像绕 box 中心顺时针旋转 degrees 度后合成到画布上
+func drawRotated(canvas *image.RGBA, box image.Rectangle, img, mask image.Image, degrees float64) {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	sb := img.Bounds()
+	// 源图像中心映射到图层区域中心
+	sx, sy := float64(sb.Min.X)+float64(sb.Dx())/2, float64(sb.Min.Y)+float64(sb.Dy())/2
+	cx, cy := float64(box.Min.X)+float64(box.Dx())/2, float64(box.Min.Y)+float64(box.Dy())/2
+	s2d := f64.Aff3{
+		cos, -sin, cx - cos*sx + sin*sy,
+		sin, cos, cy - sin*sx - cos*sy,
+	}
+
+	var opts *xdraw.Options
+	if mask != nil {
+		opts = &xdraw.Options{SrcMask: mask}
+	}
+	xdraw.CatmullRom.Transform(canvas, s2d, img, sb, xdraw.Over, opts)
+}
+
+// renderSVGLayer 将 SVG 图层渲染为指定大小的图像
+func (im *IconMarker) renderSVGLayer(ctx context.Context, layer LayerSpec, width, height int) (image.Image, error) {
+	data, err := im.svgLayerData(layer)
+	if err != nil {
+		return nil, err
+	}
+	return im.svgRenderer.RenderCtx(ctx, &renderer.SVGOptions{Data: data, Width: width, Height: height, Colors: layer.Colors})
+}
+
+// drawSVGLayer 将 SVG 图层直接光栅化到画布的 box 区域，rotation 为绕 box 中心的顺时针角度
+func (im *IconMarker) drawSVGLayer(ctx context.Context, canvas *image.RGBA, layer LayerSpec, box image.Rectangle, rotation float64) error {
+	data, err := im.svgLayerData(layer)
+	if err != nil {
+		return err
+	}
+	return im.svgRenderer.RenderOnImageCtx(ctx, canvas, &renderer.SVGOptions{
+		Data:   data,
+		Width:  box.Dx(),
+		Height: box.Dy(),
+		Colors: layer.Colors,
+		Placement: renderer.SVGPlacement{
+			X:        float64(box.Min.X),
+			Y:        float64(box.Min.Y),
+			Rotation: rotation,
+		},
+	})
+}
+
+// svgLayerData 返回 SVG 图层的数据，内嵌图标优先于内联 SVG
+func (im *IconMarker) svgLayerData(layer LayerSpec) ([]byte, error) {
+	data := []byte(layer.SVG)
+	if layer.Icon != "" {
+		var err error
+		if data, err = assets.GetSVGIcon(layer.Icon); err != nil {
+			return nil, fmt.Errorf("error loading icon %q: %w", layer.Icon, err)
+		}
+	}
+	if err := im.Limits().CheckInputBytes("svg", len(data)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// renderImageLayer 解码图片图层并按适配方式缩放到指定大小
+func (im *IconMarker) renderImageLayer(layer LayerSpec, width, height int) (image.Image, error) {
+	src, err := im.decodeImage("image layer", layer.Image)
+	if err != nil {
+		return nil, err
+	}
+	return Fit(src, ResizeOption{Width: width, Height: height, Fit: layer.Fit})
+}
+
+// box 返回图层在画布上的区域，宽高为 0 时延伸到画布边缘
+func (l LayerSpec) box(canvas image.Rectangle) image.Rectangle {
+	width, height := l.Width, l.Height
+	if width == 0 {
+		width = canvas.Max.X - l.X
+	}
+	if height == 0 {
+		height = canvas.Max.Y - l.Y
+	}
+	return image.Rect(l.X, l.Y, l.X+width, l.Y+height)
+}
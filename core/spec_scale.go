@@ -0,0 +1,90 @@
+package core
+
+import (
+	"math"
+
+	"github.com/bagaking/iconmarker/background"
+)
+
+// Scaled 返回缩放到 width×height 画布的 IconSpec 副本，用于从同一文档渲染多种尺寸
+// 图层区域、文字大小与偏移、背景的像素参数按比例缩放，矢量内容在目标尺寸下重新渲染，
+// 比缩放位图更清晰；滤镜选项不做缩放
+func (s *IconSpec) Scaled(width, height int) *IconSpec {
+	out := *s
+	out.Canvas = CanvasSpec{Width: width, Height: height}
+	if s.Canvas.Width <= 0 || s.Canvas.Height <= 0 {
+		return &out
+	}
+	sx := float64(width) / float64(s.Canvas.Width)
+	sy := float64(height) / float64(s.Canvas.Height)
+	sf := math.Min(sx, sy)
+
+	if s.Background != nil {
+		bg := *s.Background
+		bg.Width = scaleSize(bg.Width, sx)
+		bg.Height = scaleSize(bg.Height, sy)
+		if bg.CellSize > 0 {
+			bg.CellSize = math.Max(bg.CellSize*sf, background.MinCellSize)
+		}
+		out.Background = &bg
+	}
+
+	out.Layers = make([]LayerSpec, len(s.Layers))
+	for i, layer := range s.Layers {
+		// 按边缘坐标缩放，保证相邻图层缩放后仍然相接
+		if layer.Width > 0 {
+			x1 := scaleInt(layer.X+layer.Width, sx)
+			layer.X = scaleInt(layer.X, sx)
+			layer.Width = max(x1-layer.X, 1)
+		} else {
+			layer.X = scaleInt(layer.X, sx)
+		}
+		if layer.Height > 0 {
+			y1 := scaleInt(layer.Y+layer.Height, sy)
+			layer.Y = scaleInt(layer.Y, sy)
+			layer.Height = max(y1-layer.Y, 1)
+		} else {
+			layer.Y = scaleInt(layer.Y, sy)
+		}
+
+		if layer.Text != nil {
+			text := layer.Text.scaled(sx, sy)
+			layer.Text = &text
+		}
+		out.Layers[i] = layer
+	}
+	return &out
+}
+
+// scaled 返回按比例缩放的文本选项，字号按较小的比例缩放以免文字超出区域
+func (o DrawTextOption) scaled(sx, sy float64) DrawTextOption {
+	o.FontSize *= math.Min(sx, sy)
+	o.MaxWidth = scaleSize(o.MaxWidth, sx)
+	o.MaxHeight = scaleSize(o.MaxHeight, sy)
+	o.XOffset = scaleInt(o.XOffset, sx)
+	o.YOffset = scaleInt(o.YOffset, sy)
+
+	if len(o.Effect) > 0 {
+		effects := make([]FontEffect, len(o.Effect))
+		for i, e := range o.Effect {
+			e.XOffset = scaleInt(e.XOffset, sx)
+			e.YOffset = scaleInt(e.YOffset, sy)
+			effects[i] = e
+		}
+		o.Effect = effects
+	}
+	return o
+}
+
+// scaleInt 按比例缩放像素值并四舍五入
+func scaleInt(v int, factor float64) int {
+	return int(math.Round(float64(v) * factor))
+}
+
+// scaleSize 缩放尺寸，正值至少保留 1 像素
+func scaleSize(v int, factor float64) int {
+	if v <= 0 {
+		return v
+	}
+	return max(scaleInt(v, factor), 1)
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalYAML 实现 yaml.Marshaler
+// 先序列化为 JSON 再转换为 YAML 节点，两种格式因此保持相同的字段和字段顺序
+func (s IconSpec) MarshalYAML() (any, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON 是合法的 YAML，解析后清除流式风格即可输出块状 YAML
+	var node yaml.Node
+	if err = yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&node)
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		return node.Content[0], nil
+	}
+	return &node, nil
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+// YAML 转换为 JSON 后按 JSON 规则解析，错误路径与 JSON 一致
+func (s *IconSpec) UnmarshalYAML(value *yaml.Node) error {
+	var doc any
+	if err := value.Decode(&doc); err != nil {
+		return &SpecError{Path: "$", Err: err}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return &SpecError{Path: "$", Err: fmt.Errorf("YAML is not representable as JSON: %w", err)}
+	}
+	return s.UnmarshalJSON(data)
+}
+
+// unmarshalYAMLSpec 解析 YAML 文档，保留 *SpecError 以便报告路径
+func unmarshalYAMLSpec(data []byte, spec *IconSpec) error {
+	err := yaml.Unmarshal(data, spec)
+	if err == nil {
+		return nil
+	}
+	if se, ok := err.(*SpecError); ok {
+		return se
+	}
+	return &SpecError{Path: "$", Err: err}
+}
+
+// resetYAMLStyle 递归清除节点风格，让编码器自行选择引号和块状格式
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
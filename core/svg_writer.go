@@ -0,0 +1,512 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/background"
+	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/filter/utils"
+	"github.com/bagaking/iconmarker/renderer"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// SVGTextMode 决定文本图层在 SVG 中的表示方式
+type SVGTextMode string
+
+// 支持的文本表示方式
+const (
+	// SVGTextPath 将字形轮廓转换为路径，与位图渲染一致，不依赖查看端安装的字体
+	SVGTextPath SVGTextMode = "path"
+	// SVGTextElement 写为 <text> 元素，体积更小且文字可选中，字形取决于查看端的 font-family
+	SVGTextElement SVGTextMode = "text"
+)
+
+// SVGWriteOptions 配置 SVG 输出
+type SVGWriteOptions struct {
+	TextMode   SVGTextMode // 文本表示方式，为空时使用 SVGTextPath
+	FontFamily string      // SVGTextElement 使用的 font-family，为空时为 sans-serif
+}
+
+// ErrSVGUnsupported 匹配 IconSpec 中无法用 SVG 表示的内容，可用 errors.Is 判断
+var ErrSVGUnsupported = errors.New("icon spec cannot be written as SVG")
+
+// SVGUnsupported 描述 IconSpec 中无法用 SVG 表示的一处内容
+type SVGUnsupported struct {
+	Path   string // JSON 路径，例如 $.layers[0].effects[1]
+	Reason string
+}
+
+// SVGUnsupportedError 列出 IconSpec 中所有无法用 SVG 表示的内容，
+// 例如锥形渐变背景和只能作用于位图的滤镜
+type SVGUnsupportedError []SVGUnsupported
+
+// Error 实现 error
+func (e SVGUnsupportedError) Error() string {
+	msgs := make([]string, len(e))
+	for i, u := range e {
+		msgs[i] = u.Path + ": " + u.Reason
+	}
+	return "not representable as SVG: " + strings.Join(msgs, "; ")
+}
+
+// Is 使 SVGUnsupportedError 能匹配 ErrSVGUnsupported
+func (e SVGUnsupportedError) Is(target error) bool {
+	return target == ErrSVGUnsupported
+}
+
+// svgNamespaces 是输出文档根元素声明的命名空间
+const svgNamespaces = `xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"`
+
+// WriteSpecSVG 将 IconSpec 写为 SVG 文档，见 RenderSpecSVG
+func (im *IconMarker) WriteSpecSVG(w io.Writer, spec *IconSpec, opts SVGWriteOptions) error {
+	data, err := im.RenderSpecSVG(spec, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// RenderSpecSVG 将 IconSpec 组装为 SVG 文档而不是位图，适合由内嵌 SVG、生成背景和文字构成的图标
+//
+// 背景写为矩形、<linearGradient>、<radialGradient> 或 <pattern>；SVG 图层写为嵌套的 <svg>，
+// 与位图渲染一样按 preserveAspectRatio 适配图层区域；文字按 opts.TextMode 写为字形路径或 <text>；
+// 图片图层以 data URI 内嵌；实现了 filter.SVGFilter 的滤镜写为 <filter>
+//
+// 文档先经过与 RenderSpec 相同的校验；锥形渐变和只能作用于位图的滤镜等无法表示的内容
+// 全部收集到 SVGUnsupportedError 中一并返回
+func (im *IconMarker) RenderSpecSVG(spec *IconSpec, opts SVGWriteOptions) ([]byte, error) {
+	filters, effects, err := im.resolveSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.TextMode {
+	case "", SVGTextPath, SVGTextElement:
+	default:
+		return nil, fmt.Errorf("unknown SVG text mode %q", opts.TextMode)
+	}
+
+	sw := &svgWriter{im: im, spec: spec, opts: opts}
+	var body strings.Builder
+	if spec.Background != nil {
+		defs, content, err := spec.backgroundSpec().SVG(sw.newID("bg"))
+		switch {
+		case errors.Is(err, background.ErrSVGUnsupported):
+			sw.unsupported("$.background", err.Error())
+		case err != nil:
+			return nil, err
+		}
+		sw.defs.WriteString(defs)
+		body.WriteString(content)
+	}
+
+	canvas := image.Rect(0, 0, spec.Canvas.Width, spec.Canvas.Height)
+	for i, layer := range spec.Layers {
+		path := fmt.Sprintf("$.layers[%d]", i)
+		box := layer.box(canvas)
+		if box.Empty() {
+			return nil, fmt.Errorf("layer %d: layer box %v is empty", i, box)
+		}
+
+		content, err := sw.layer(layer, box)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+
+		var attrs string
+		if layer.Opacity != nil {
+			attrs += fmt.Sprintf(` opacity="%s"`, svgNum(*layer.Opacity))
+		}
+		filterID, err := sw.filter(effects[i], path+".effects", box)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i, err)
+		}
+		if filterID != "" {
+			attrs += fmt.Sprintf(` filter="url(#%s)"`, filterID)
+		}
+		if attrs != "" {
+			content = "<g" + attrs + ">" + content + "</g>"
+		}
+		body.WriteString(content)
+	}
+
+	filterID, err := sw.filter(filters, "$.filters", canvas)
+	if err != nil {
+		return nil, err
+	}
+	if len(sw.errs) > 0 {
+		return nil, sw.errs
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg %s width="%d" height="%d" viewBox="0 0 %d %d">`,
+		svgNamespaces, spec.Canvas.Width, spec.Canvas.Height, spec.Canvas.Width, spec.Canvas.Height)
+	if sw.defs.Len() > 0 {
+		out.WriteString("<defs>" + sw.defs.String() + "</defs>")
+	}
+	if filterID != "" {
+		fmt.Fprintf(&out, `<g filter="url(#%s)">%s</g>`, filterID, body.String())
+	} else {
+		out.WriteString(body.String())
+	}
+	out.WriteString("</svg>\n")
+
+	if err = im.Limits().CheckOutputBytes(out.Len()); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// svgWriter 保存组装 SVG 文档时的共享状态
+type svgWriter struct {
+	im   *IconMarker
+	spec *IconSpec
+	opts SVGWriteOptions
+
+	defs strings.Builder
+	ids  int
+	font *truetype.Font
+	errs SVGUnsupportedError
+}
+
+// newID 返回文档内唯一的元素 id
+func (sw *svgWriter) newID(prefix string) string {
+	sw.ids++
+	return prefix + strconv.Itoa(sw.ids)
+}
+
+// unsupported 记录一处无法表示的内容
+func (sw *svgWriter) unsupported(path, reason string) {
+	sw.errs = append(sw.errs, SVGUnsupported{Path: path, Reason: reason})
+}
+
+// layer 返回图层内容，坐标为画布坐标
+func (sw *svgWriter) layer(layer LayerSpec, box image.Rectangle) (string, error) {
+	switch layer.Type {
+	case LayerSVG:
+		data := []byte(layer.SVG)
+		if layer.Icon != "" {
+			var err error
+			if data, err = assets.GetSVGIcon(layer.Icon); err != nil {
+				return "", fmt.Errorf("error loading icon %q: %w", layer.Icon, err)
+			}
+		}
+		if err := sw.im.Limits().CheckSVG(data); err != nil {
+			return "", err
+		}
+		if !layer.Colors.IsEmpty() {
+			var err error
+			if data, err = renderer.RecolorSVG(data, layer.Colors); err != nil {
+				return "", err
+			}
+		}
+		return nestedSVG(data, box)
+
+	case LayerText:
+		if sw.font == nil {
+			if err := sw.im.Limits().CheckInputBytes("font", len(sw.spec.Font)); err != nil {
+				return "", err
+			}
+			var err error
+			if sw.font, err = sw.im.textRenderer.LoadFont(sw.spec.Font); err != nil {
+				return "", fmt.Errorf("%w, error parsing font file", err)
+			}
+		}
+		return sw.text(*layer.Text, box)
+
+	case LayerImage:
+		return sw.image(layer, box)
+
+	default:
+		return "", fmt.Errorf("unknown layer type %q", layer.Type)
+	}
+}
+
+// filter 将滤镜链写为 <filter> 定义并返回其 id，滤镜链为空时返回空字符串
+// 滤镜区域为 region，与位图渲染中滤镜作用的范围一致
+func (sw *svgWriter) filter(filters resolvedFilters, path string, region image.Rectangle) (string, error) {
+	if len(filters.names) == 0 {
+		return "", nil
+	}
+
+	var primitives strings.Builder
+	for i, name := range filters.names {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		f, _ := sw.im.filterManager.Get(name)
+		sf, ok := f.(filter.SVGFilter)
+		if !ok {
+			sw.unsupported(at, fmt.Sprintf("filter %q only works on raster images", name))
+			continue
+		}
+		p, err := sf.SVGPrimitives(filters.options[i])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", at, err)
+		}
+		primitives.WriteString(p)
+	}
+
+	id := sw.newID("f")
+	fmt.Fprintf(&sw.defs, `<filter id="%s" filterUnits="userSpaceOnUse" x="%d" y="%d" width="%d" height="%d" color-interpolation-filters="sRGB">%s</filter>`,
+		id, region.Min.X, region.Min.Y, region.Dx(), region.Dy(), primitives.String())
+	return id, nil
+}
+
+// text 按 DrawCenteredFont 的排版写出文本及其阴影、描边效果
+// 字形只定义一次，文本和每个效果都是引用它的 <use>
+func (sw *svgWriter) text(opt DrawTextOption, box image.Rectangle) (string, error) {
+	if opt.MaxWidth > 0 {
+		opt.FontSize = adaptSize(sw.font, opt.Text, opt.MaxWidth, opt.MaxHeight, opt.FontSize)
+	} else if opt.FontSize < 1 {
+		return "", fmt.Errorf("invalid font size: %f", opt.FontSize)
+	}
+	face := truetype.NewFace(sw.font, &truetype.Options{Size: opt.FontSize, DPI: 72, Hinting: font.HintingNone})
+
+	// 与 DrawCenteredFont 相同的基线位置
+	width := font.MeasureString(face, opt.Text).Round()
+	x := box.Min.X + (box.Dx()-width)/2 + opt.XOffset
+	y := box.Min.Y + (box.Dy()+int(opt.FontSize))/2 - face.Metrics().Descent.Round() + opt.YOffset
+
+	id := sw.newID("t")
+	if sw.opts.TextMode == SVGTextElement {
+		family := sw.opts.FontFamily
+		if family == "" {
+			family = "sans-serif"
+		}
+		fmt.Fprintf(&sw.defs, `<text id="%s" x="%s" y="%d" text-anchor="middle" font-family="%s" font-size="%s">%s</text>`,
+			id, svgNum(float64(x)+float64(width)/2), y, xmlEscape(family), svgNum(opt.FontSize), xmlEscape(opt.Text))
+	} else {
+		d, err := glyphPath(sw.font, face, opt.Text, opt.FontSize, x, y)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sw.defs, `<path id="%s" d="%s"/>`, id, d)
+	}
+
+	var sb strings.Builder
+	use := func(dx, dy int, c color.Color, extra string) {
+		fmt.Fprintf(&sb, `<use xlink:href="#%s"`, id)
+		if dx != 0 || dy != 0 {
+			fmt.Fprintf(&sb, ` x="%d" y="%d"`, dx, dy)
+		}
+		sb.WriteString(svgPaintAttrs("fill", c) + extra + "/>")
+	}
+	for _, e := range opt.Effect {
+		switch e.Type {
+		case EShadow:
+			use(e.XOffset, e.YOffset, e.Color, "")
+		case EOutline:
+			// 位图渲染在半径为 XOffset 的圆盘内逐点平移绘制，等价于宽度为两倍半径的圆角描边
+			var stroke string
+			if e.XOffset > 0 {
+				stroke = svgPaintAttrs("stroke", e.Color) +
+					fmt.Sprintf(` stroke-width="%d" stroke-linejoin="round"`, 2*e.XOffset)
+			}
+			use(0, 0, e.Color, stroke)
+		}
+	}
+	use(0, 0, opt.FontColor, "")
+	return sb.String(), nil
+}
+
+// glyphPath 将文本的字形轮廓转换为 SVG 路径数据，(x, y) 为第一个字形的基线原点
+// 字形间距与 font.Drawer 一致，包括字距调整
+func glyphPath(f *truetype.Font, face font.Face, text string, size float64, x, y int) (string, error) {
+	scale := fixed.Int26_6(0.5 + size*64)
+	var buf truetype.GlyphBuf
+	var sb strings.Builder
+
+	dot := fixed.I(x)
+	prev := rune(-1)
+	for _, r := range text {
+		if prev >= 0 {
+			dot += face.Kern(prev, r)
+		}
+		if err := buf.Load(f, scale, f.Index(r), font.HintingNone); err != nil {
+			return "", fmt.Errorf("%w, error loading glyph %q", err, r)
+		}
+		renderer.AddGlyphOutline(pathWriter{&sb}, &buf, func(p truetype.Point) fixed.Point26_6 {
+			// 字形坐标的 y 轴向上
+			return fixed.Point26_6{X: dot + p.X, Y: fixed.I(y) - p.Y}
+		})
+		advance, _ := face.GlyphAdvance(r)
+		dot += advance
+		prev = r
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// pathWriter 把轮廓写成 SVG 路径数据，实现 rasterx.Adder
+type pathWriter struct {
+	sb *strings.Builder
+}
+
+func (w pathWriter) Start(a fixed.Point26_6) {
+	fmt.Fprintf(w.sb, "M%s ", svgPoint(a))
+}
+
+func (w pathWriter) Line(b fixed.Point26_6) {
+	fmt.Fprintf(w.sb, "L%s ", svgPoint(b))
+}
+
+func (w pathWriter) QuadBezier(b, c fixed.Point26_6) {
+	fmt.Fprintf(w.sb, "Q%s %s ", svgPoint(b), svgPoint(c))
+}
+
+func (w pathWriter) CubeBezier(b, c, d fixed.Point26_6) {
+	fmt.Fprintf(w.sb, "C%s %s %s ", svgPoint(b), svgPoint(c), svgPoint(d))
+}
+
+func (w pathWriter) Stop(closeLoop bool) {
+	if closeLoop {
+		w.sb.WriteString("Z ")
+	}
+}
+
+// image 将图片图层以 data URI 内嵌，适配方式映射为 preserveAspectRatio
+func (sw *svgWriter) image(layer LayerSpec, box image.Rectangle) (string, error) {
+	src, err := sw.im.decodeImage("image layer", layer.Image)
+	if err != nil {
+		return "", err
+	}
+	_, format, err := image.DecodeConfig(bytes.NewReader(layer.Image))
+	if err != nil {
+		return "", fmt.Errorf("%w, error decoding image layer", err)
+	}
+	href := "data:image/" + format + ";base64," + Bytes2Base64(layer.Image)
+
+	switch layer.Fit {
+	case FitCenterCrop:
+		// 不缩放，居中放置并裁剪到图层区域，嵌套 <svg> 的视口负责裁剪
+		sb := src.Bounds()
+		return fmt.Sprintf(`<svg x="%d" y="%d" width="%d" height="%d"><image x="%d" y="%d" width="%d" height="%d" xlink:href="%s"/></svg>`,
+			box.Min.X, box.Min.Y, box.Dx(), box.Dy(),
+			(box.Dx()-sb.Dx())/2, (box.Dy()-sb.Dy())/2, sb.Dx(), sb.Dy(), href), nil
+	case FitContain:
+		return svgImage(box, "xMidYMid meet", href), nil
+	case FitStretch:
+		return svgImage(box, "none", href), nil
+	default:
+		return svgImage(box, "xMidYMid slice", href), nil
+	}
+}
+
+// svgImage 返回填充 box 的 <image> 元素
+func svgImage(box image.Rectangle, aspect, href string) string {
+	return fmt.Sprintf(`<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="%s" xlink:href="%s"/>`,
+		box.Min.X, box.Min.Y, box.Dx(), box.Dy(), aspect, href)
+}
+
+// nestedSVG 将 SVG 文档改写为放置在 box 中的嵌套 <svg> 元素
+// 根元素的位置和尺寸被替换，缺少 viewBox 时由原宽高补全；
+// preserveAspectRatio 与其余属性和内容原样保留，与位图渲染的适配方式一致
+func nestedSVG(data []byte, box image.Rectangle) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *xml.StartElement
+	var start, end int64
+	depth := 0
+	for end == 0 {
+		offset := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return "", fmt.Errorf("SVG has no complete <svg> root element")
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w, error parsing SVG", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root == nil {
+				if t.Name.Local != "svg" {
+					return "", fmt.Errorf("SVG root element is <%s>, expected <svg>", t.Name.Local)
+				}
+				se := t.Copy()
+				root, start = &se, dec.InputOffset()
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if root != nil && depth == 0 {
+				end = offset
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg x="%d" y="%d" width="%d" height="%d"`,
+		box.Min.X, box.Min.Y, box.Dx(), box.Dy())
+	var width, height string
+	hasViewBox := false
+	for _, attr := range root.Attr {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		switch name {
+		case "x", "y":
+			continue
+		case "width":
+			width = attr.Value
+			continue
+		case "height":
+			height = attr.Value
+			continue
+		case "viewBox":
+			hasViewBox = true
+		}
+		fmt.Fprintf(&sb, ` %s="%s"`, name, xmlEscape(attr.Value))
+	}
+	if !hasViewBox {
+		w, errW := strconv.ParseFloat(strings.TrimSuffix(width, "px"), 64)
+		h, errH := strconv.ParseFloat(strings.TrimSuffix(height, "px"), 64)
+		if errW == nil && errH == nil && w > 0 && h > 0 {
+			fmt.Fprintf(&sb, ` viewBox="0 0 %s %s"`, svgNum(w), svgNum(h))
+		}
+	}
+	sb.WriteString(">")
+	sb.Write(data[start:end])
+	sb.WriteString("</svg>")
+	return sb.String(), nil
+}
+
+// svgPaintAttrs 返回 fill、stroke 等颜色属性，半透明时附带对应的不透明度属性；nil 视为黑色
+func svgPaintAttrs(attr string, c color.Color) string {
+	if c == nil {
+		c = color.Black
+	}
+	hex, opacity := utils.SVGPaint(c)
+	out := fmt.Sprintf(` %s="%s"`, attr, hex)
+	if opacity < 1 {
+		out += fmt.Sprintf(` %s-opacity="%s"`, attr, svgNum(opacity))
+	}
+	return out
+}
+
+// svgPoint 格式化 26.6 定点坐标
+func svgPoint(p fixed.Point26_6) string {
+	return svgNum(float64(p.X)/64) + " " + svgNum(float64(p.Y)/64)
+}
+
+// svgNum 格式化数值，最多保留三位小数
+func svgNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// xmlEscape 转义属性值和文本内容
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
@@ -5,7 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
@@ -109,32 +108,6 @@ const iconTemplate = `<?xml version="1.0" encoding="UTF-8"?>
   <circle cx="55" cy="62" r="5" fill="white" stroke="{{.IconColor}}" stroke-width="1"/>
 </svg>`
 
-// 自定义SVG渲染选项，实现renderer.SVGRenderOption接口
-type SVGRenderOpts struct {
-	SVGData  []byte
-	Width    int
-	Height   int
-	Position image.Point
-}
-
-func (o *SVGRenderOpts) GetSVGData() []byte {
-	return o.SVGData
-}
-
-func (o *SVGRenderOpts) GetDimensions() (width, height int) {
-	return o.Width, o.Height
-}
-
-func (o *SVGRenderOpts) ValidateOption() error {
-	if len(o.SVGData) == 0 {
-		return fmt.Errorf("SVG数据为空")
-	}
-	if o.Width <= 0 || o.Height <= 0 {
-		return fmt.Errorf("无效的尺寸: width=%d, height=%d", o.Width, o.Height)
-	}
-	return nil
-}
-
 // 生成徽章
 func generateBadge(style BadgeStyle) error {
 	// 创建输出目录
@@ -159,11 +132,10 @@ func generateBadge(style BadgeStyle) error {
 	draw.Draw(background, background.Bounds(), &image.Uniform{C: bgColor}, image.Point{}, draw.Src)
 
 	// 渲染SVG底图
-	badgeOpts := &SVGRenderOpts{
-		SVGData:  []byte(badgeSvg),
-		Width:    width,
-		Height:   height,
-		Position: image.Point{X: 0, Y: 0},
+	badgeOpts := &renderer.SVGOptions{
+		Data:   []byte(badgeSvg),
+		Width:  width,
+		Height: height,
 	}
 
 	badgeImg, err := svgRenderer.Render(badgeOpts)
@@ -220,11 +192,10 @@ func generateBadge(style BadgeStyle) error {
 	iconPosition := image.Point{X: (width - iconSize) / 2, Y: 400}
 
 	// 渲染SVG图标
-	iconOpts := &SVGRenderOpts{
-		SVGData:  []byte(iconSvg),
-		Width:    iconSize,
-		Height:   iconSize,
-		Position: image.Point{X: 0, Y: 0},
+	iconOpts := &renderer.SVGOptions{
+		Data:   []byte(iconSvg),
+		Width:  iconSize,
+		Height: iconSize,
 	}
 
 	iconImg, err := svgRenderer.Render(iconOpts)
@@ -252,14 +223,8 @@ func generateBadge(style BadgeStyle) error {
 
 // 保存为PNG图像
 func saveAsPNG(img image.Image, filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("创建输出文件失败: %v", err)
-	}
-	defer f.Close()
-
-	if err := png.Encode(f, img); err != nil {
-		return fmt.Errorf("编码PNG失败: %v", err)
+	if err := core.SaveImage(img, filename, core.EncodeOptions{Format: core.FormatPNG}); err != nil {
+		return fmt.Errorf("保存PNG失败: %v", err)
 	}
 	return nil
 }
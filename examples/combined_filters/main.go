@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
-	"image/jpeg"
 	"os"
 	"path/filepath"
 
 	"github.com/bagaking/iconmarker"
+	"github.com/bagaking/iconmarker/core"
 	"github.com/bagaking/iconmarker/filter"
 )
 
@@ -180,11 +180,5 @@ func openImage(filename string) (image.Image, error) {
 
 // 保存图像为JPEG
 func saveImage(img image.Image, filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	return core.SaveImage(img, filename, core.EncodeOptions{Format: core.FormatJPEG})
 }
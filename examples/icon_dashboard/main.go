@@ -5,7 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"os"
 	"path/filepath"
 
@@ -16,32 +15,6 @@ import (
 	"github.com/bagaking/iconmarker/renderer"
 )
 
-// SVGRenderOption 实现接口
-type SVGRenderOpts struct {
-	SVGData  []byte
-	Width    int
-	Height   int
-	Position image.Point
-}
-
-func (o *SVGRenderOpts) GetSVGData() []byte {
-	return o.SVGData
-}
-
-func (o *SVGRenderOpts) GetDimensions() (width, height int) {
-	return o.Width, o.Height
-}
-
-func (o *SVGRenderOpts) ValidateOption() error {
-	if len(o.SVGData) == 0 {
-		return fmt.Errorf("SVG数据为空")
-	}
-	if o.Width <= 0 || o.Height <= 0 {
-		return fmt.Errorf("无效的尺寸: width=%d, height=%d", o.Width, o.Height)
-	}
-	return nil
-}
-
 func main() {
 	// 创建输出目录
 	outputDir := "output"
@@ -79,11 +52,11 @@ func main() {
 	}
 
 	// 渲染第一个SVG图标 - 保持原始颜色
-	icon1Opts := &SVGRenderOpts{
-		SVGData:  icon1Data,
-		Width:    150,
-		Height:   150,
-		Position: image.Point{X: 100, Y: 125},
+	icon1Pos := image.Point{X: 100, Y: 125}
+	icon1Opts := &renderer.SVGOptions{
+		Data:   icon1Data,
+		Width:  150,
+		Height: 150,
 	}
 
 	icon1Img, err := svgRenderer.Render(icon1Opts)
@@ -94,18 +67,18 @@ func main() {
 
 	// 将SVG图像绘制到目标图像上
 	draw.Draw(background, image.Rect(
-		icon1Opts.Position.X,
-		icon1Opts.Position.Y,
-		icon1Opts.Position.X+icon1Opts.Width,
-		icon1Opts.Position.Y+icon1Opts.Height),
+		icon1Pos.X,
+		icon1Pos.Y,
+		icon1Pos.X+icon1Opts.Width,
+		icon1Pos.Y+icon1Opts.Height),
 		icon1Img, image.Point{}, draw.Over)
 
 	// 渲染第二个SVG图标 - 应用颜色滤镜
-	icon2Opts := &SVGRenderOpts{
-		SVGData:  icon2Data,
-		Width:    150,
-		Height:   150,
-		Position: image.Point{X: 550, Y: 125},
+	icon2Pos := image.Point{X: 550, Y: 125}
+	icon2Opts := &renderer.SVGOptions{
+		Data:   icon2Data,
+		Width:  150,
+		Height: 150,
 	}
 
 	icon2Img, err := svgRenderer.Render(icon2Opts)
@@ -125,10 +98,10 @@ func main() {
 
 	// 将滤镜处理后的SVG图像绘制到目标图像上
 	draw.Draw(background, image.Rect(
-		icon2Opts.Position.X,
-		icon2Opts.Position.Y,
-		icon2Opts.Position.X+icon2Opts.Width,
-		icon2Opts.Position.Y+icon2Opts.Height),
+		icon2Pos.X,
+		icon2Pos.Y,
+		icon2Pos.X+icon2Opts.Width,
+		icon2Pos.Y+icon2Opts.Height),
 		filteredIcon2, image.Point{}, draw.Over)
 
 	// 添加中央文本
@@ -177,15 +150,7 @@ func main() {
 
 // 保存为PNG图像
 func saveAsPNG(img image.Image, filename string) {
-	f, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("创建输出文件失败: %v\n", err)
-		return
-	}
-	defer f.Close()
-
-	if err := png.Encode(f, img); err != nil {
-		fmt.Printf("编码PNG失败: %v\n", err)
-		return
+	if err := core.SaveImage(img, filename, core.EncodeOptions{Format: core.FormatPNG}); err != nil {
+		fmt.Printf("保存PNG失败: %v\n", err)
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
-	"image/jpeg"
 	"os"
 	"path/filepath"
 
@@ -14,32 +13,6 @@ import (
 	"github.com/bagaking/iconmarker/renderer"
 )
 
-// 简化版的SVG渲染选项
-type SVGRenderOpts struct {
-	SVGData []byte
-	Width   int
-	Height  int
-}
-
-// 实现renderer.SVGRenderOption接口
-func (o SVGRenderOpts) GetSVGData() []byte {
-	return o.SVGData
-}
-
-func (o SVGRenderOpts) GetDimensions() (int, int) {
-	return o.Width, o.Height
-}
-
-func (o SVGRenderOpts) ValidateOption() error {
-	if len(o.SVGData) == 0 {
-		return fmt.Errorf("SVG数据为空")
-	}
-	if o.Width <= 0 || o.Height <= 0 {
-		return fmt.Errorf("无效的尺寸: %dx%d", o.Width, o.Height)
-	}
-	return nil
-}
-
 func main() {
 	// 打开背景图像
 	bgFile := filepath.Join("..", "assets", "background.jpg")
@@ -85,10 +58,10 @@ func renderOriginalSVG(marker *core.IconMarker, svgData []byte, bgImg image.Imag
 	draw.Draw(img, bounds, bgImg, image.Point{}, draw.Src)
 
 	// 创建SVG渲染选项
-	svgOpts := SVGRenderOpts{
-		SVGData: svgData,
-		Width:   100,
-		Height:  100,
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  100,
+		Height: 100,
 	}
 
 	// 创建SVG渲染器
@@ -125,10 +98,10 @@ func renderResizedSVG(marker *core.IconMarker, svgData []byte, bgImg image.Image
 	draw.Draw(img, bounds, bgImg, image.Point{}, draw.Src)
 
 	// 创建SVG渲染选项 - 更大尺寸
-	svgOpts := SVGRenderOpts{
-		SVGData: svgData,
-		Width:   200,
-		Height:  200,
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  200,
+		Height: 200,
 	}
 
 	// 创建SVG渲染器
@@ -165,10 +138,10 @@ func renderFilteredSVG(marker *core.IconMarker, svgData []byte, bgImg image.Imag
 	draw.Draw(img, bounds, bgImg, image.Point{}, draw.Src)
 
 	// 创建SVG渲染选项
-	svgOpts := SVGRenderOpts{
-		SVGData: svgData,
-		Width:   150,
-		Height:  150,
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  150,
+		Height: 150,
 	}
 
 	// 创建SVG渲染器
@@ -221,11 +194,5 @@ func openImage(filename string) (image.Image, error) {
 
 // 保存图像为JPEG
 func saveImage(img image.Image, filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	return core.SaveImage(img, filename, core.EncodeOptions{Format: core.FormatJPEG})
 }
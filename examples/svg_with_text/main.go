@@ -5,8 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/jpeg"
-	"image/png"
 	"os"
 	"path/filepath"
 
@@ -17,35 +15,6 @@ import (
 	"github.com/bagaking/iconmarker/renderer"
 )
 
-// 自定义SVG渲染选项，实现renderer.SVGRenderOption接口
-type SVGRenderOpts struct {
-	SVGData  []byte
-	Width    int
-	Height   int
-	Position image.Point
-}
-
-// GetSVGData 返回SVG数据
-func (o *SVGRenderOpts) GetSVGData() []byte {
-	return o.SVGData
-}
-
-// GetDimensions 返回SVG尺寸
-func (o *SVGRenderOpts) GetDimensions() (width, height int) {
-	return o.Width, o.Height
-}
-
-// ValidateOption 验证选项
-func (o *SVGRenderOpts) ValidateOption() error {
-	if len(o.SVGData) == 0 {
-		return fmt.Errorf("SVG data is empty")
-	}
-	if o.Width <= 0 || o.Height <= 0 {
-		return fmt.Errorf("invalid dimensions: width=%d, height=%d", o.Width, o.Height)
-	}
-	return nil
-}
-
 func main() {
 	// 打开背景图像
 	bgFile := filepath.Join("..", "assets", "background.jpg")
@@ -105,22 +74,10 @@ func main() {
 }
 
 // 在图像上渲染SVG的辅助函数
-func renderSVGOnImage(svgRenderer *renderer.SVGRenderer, img draw.Image, opts *SVGRenderOpts) error {
-	// 首先使用Render方法获取SVG图像
-	svgImg, err := svgRenderer.Render(opts)
-	if err != nil {
-		return err
-	}
-
-	// 然后将SVG图像绘制到目标图像上的指定位置
-	draw.Draw(img, image.Rect(
-		opts.Position.X,
-		opts.Position.Y,
-		opts.Position.X+opts.Width,
-		opts.Position.Y+opts.Height),
-		svgImg, image.Point{}, draw.Over)
-
-	return nil
+func renderSVGOnImage(svgRenderer *renderer.SVGRenderer, img draw.Image, opts *renderer.SVGOptions, pos image.Point) error {
+	// 直接光栅化到目标图像的指定位置，无需中间图像
+	opts.Placement.X, opts.Placement.Y = float64(pos.X), float64(pos.Y)
+	return svgRenderer.RenderOnImage(img, opts)
 }
 
 // SVG图标在左，文本在右的布局
@@ -136,18 +93,18 @@ func example1_IconLeft(marker *core.IconMarker, bgImg image.Image, svgData []byt
 	centerY := imgHeight / 2
 
 	// 创建SVG渲染选项 - 放在图像左侧
-	svgSize := 150 // SVG图标大小
-	svgOpts := &SVGRenderOpts{
-		SVGData:  svgData,
-		Width:    svgSize,
-		Height:   svgSize,
-		Position: image.Point{X: imgWidth/4 - svgSize/2, Y: centerY - svgSize/2}, // 放在左侧四分之一处
+	svgSize := 150                                                           // SVG图标大小
+	svgPos := image.Point{X: imgWidth/4 - svgSize/2, Y: centerY - svgSize/2} // 放在左侧四分之一处
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  svgSize,
+		Height: svgSize,
 	}
 
 	// 渲染SVG到图像
 	resourceMgr := marker.GetResourceManager()
 	svgRenderer := renderer.NewSVGRenderer(resourceMgr)
-	if err := renderSVGOnImage(svgRenderer, img, svgOpts); err != nil {
+	if err := renderSVGOnImage(svgRenderer, img, svgOpts, svgPos); err != nil {
 		fmt.Printf("渲染SVG失败: %v\n", err)
 		return
 	}
@@ -183,18 +140,18 @@ func example2_IconTop(marker *core.IconMarker, bgImg image.Image, svgData []byte
 	centerX := imgWidth / 2
 
 	// 创建SVG渲染选项 - 放在图像上部
-	svgSize := 180 // SVG图标大小
-	svgOpts := &SVGRenderOpts{
-		SVGData:  svgData,
-		Width:    svgSize,
-		Height:   svgSize,
-		Position: image.Point{X: centerX - svgSize/2, Y: imgHeight/4 - svgSize/2}, // 放在上方四分之一处
+	svgSize := 180                                                            // SVG图标大小
+	svgPos := image.Point{X: centerX - svgSize/2, Y: imgHeight/4 - svgSize/2} // 放在上方四分之一处
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  svgSize,
+		Height: svgSize,
 	}
 
 	// 渲染SVG到图像
 	resourceMgr := marker.GetResourceManager()
 	svgRenderer := renderer.NewSVGRenderer(resourceMgr)
-	if err := renderSVGOnImage(svgRenderer, img, svgOpts); err != nil {
+	if err := renderSVGOnImage(svgRenderer, img, svgOpts, svgPos); err != nil {
 		fmt.Printf("渲染SVG失败: %v\n", err)
 		return
 	}
@@ -231,18 +188,18 @@ func example3_TextAround(marker *core.IconMarker, bgImg image.Image, svgData []b
 	centerY := imgHeight / 2
 
 	// 创建SVG渲染选项 - 放在图像中心位置
-	svgSize := 200 // SVG图标大小
-	svgOpts := &SVGRenderOpts{
-		SVGData:  svgData,
-		Width:    svgSize,
-		Height:   svgSize,
-		Position: image.Point{X: centerX - svgSize/2, Y: centerY - svgSize/2}, // 居中放置
+	svgSize := 200                                                        // SVG图标大小
+	svgPos := image.Point{X: centerX - svgSize/2, Y: centerY - svgSize/2} // 居中放置
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  svgSize,
+		Height: svgSize,
 	}
 
 	// 渲染SVG到图像
 	resourceMgr := marker.GetResourceManager()
 	svgRenderer := renderer.NewSVGRenderer(resourceMgr)
-	if err := renderSVGOnImage(svgRenderer, img, svgOpts); err != nil {
+	if err := renderSVGOnImage(svgRenderer, img, svgOpts, svgPos); err != nil {
 		fmt.Printf("渲染SVG失败: %v\n", err)
 		return
 	}
@@ -335,17 +292,17 @@ func example4_WithFilters(marker *core.IconMarker, bgImg image.Image, svgData []
 
 	// 创建SVG渲染选项 - 放在图像右上角
 	svgSize := 150
-	svgOpts := &SVGRenderOpts{
-		SVGData:  svgData,
-		Width:    svgSize,
-		Height:   svgSize,
-		Position: image.Point{X: imgWidth - svgSize - 50, Y: 50}, // 右上角
+	svgPos := image.Point{X: imgWidth - svgSize - 50, Y: 50} // 右上角
+	svgOpts := &renderer.SVGOptions{
+		Data:   svgData,
+		Width:  svgSize,
+		Height: svgSize,
 	}
 
 	// 渲染SVG到图像
 	resourceMgr := marker.GetResourceManager()
 	svgRenderer := renderer.NewSVGRenderer(resourceMgr)
-	if err := renderSVGOnImage(svgRenderer, resultImg, svgOpts); err != nil {
+	if err := renderSVGOnImage(svgRenderer, resultImg, svgOpts, svgPos); err != nil {
 		fmt.Printf("渲染SVG失败: %v\n", err)
 		return
 	}
@@ -444,15 +401,15 @@ func example5_EmbeddedIcons(marker *core.IconMarker, bgImg image.Image, outputDi
 		y := bounds.Dy()/2 - iconSize/2
 
 		// 设置渲染选项
-		svgOpts := &SVGRenderOpts{
-			SVGData:  iconData,
-			Width:    iconSize,
-			Height:   iconSize,
-			Position: image.Point{X: x, Y: y},
+		svgPos := image.Point{X: x, Y: y}
+		svgOpts := &renderer.SVGOptions{
+			Data:   iconData,
+			Width:  iconSize,
+			Height: iconSize,
 		}
 
 		// 渲染SVG到图像
-		if err := renderSVGOnImage(svgRenderer, img, svgOpts); err != nil {
+		if err := renderSVGOnImage(svgRenderer, img, svgOpts, svgPos); err != nil {
 			fmt.Printf("渲染图标 %s 失败: %v\n", name, err)
 			continue
 		}
@@ -489,32 +446,18 @@ func openImage(filename string) (image.Image, error) {
 
 // 保存为JPEG图像
 func saveAsJPEG(img image.Image, filename string) {
-	f, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("创建输出文件失败: %v\n", err)
-		return
-	}
-	defer f.Close()
-
-	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 90}); err != nil {
-		fmt.Printf("编码JPEG失败: %v\n", err)
-		return
-	}
-
-	fmt.Printf("已保存: %s\n", filename)
+	saveAs(img, filename, core.FormatJPEG)
 }
 
 // 保存为PNG图像
 func saveAsPNG(img image.Image, filename string) {
-	f, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("创建输出文件失败: %v\n", err)
-		return
-	}
-	defer f.Close()
+	saveAs(img, filename, core.FormatPNG)
+}
 
-	if err := png.Encode(f, img); err != nil {
-		fmt.Printf("编码PNG失败: %v\n", err)
+// 按指定格式保存图像
+func saveAs(img image.Image, filename string, format core.ImageFormat) {
+	if err := core.SaveImage(img, filename, core.EncodeOptions{Format: format}); err != nil {
+		fmt.Printf("保存图像失败: %v\n", err)
 		return
 	}
 
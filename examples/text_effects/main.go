@@ -5,7 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/jpeg"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -224,11 +223,5 @@ func openImage(filename string) (image.Image, error) {
 
 // 保存图像为JPEG
 func saveImage(img image.Image, filename string) error {
-	f, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return jpeg.Encode(f, img, &jpeg.Options{Quality: 90})
+	return core.SaveImage(img, filename, core.EncodeOptions{Format: core.FormatJPEG})
 }
@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"context"
 	"image/draw"
 )
 
@@ -38,11 +39,17 @@ func NewCompositeFilter(filters ...Filter) *CompositeFilter {
 
 // Apply applies all filters in sequence
 func (f *CompositeFilter) Apply(img draw.Image, options FilterOption) error {
+	return f.ApplyCtx(context.Background(), img, options)
+}
+
+// ApplyCtx applies all filters in sequence, checking ctx between filters
+// Cancellation always stops the sequence, regardless of StopOnError
+func (f *CompositeFilter) ApplyCtx(ctx context.Context, img draw.Image, options FilterOption) error {
 	opt, ok := options.(CompositeOption)
 	if !ok {
 		// If no options provided, just apply filters with nil options
 		for _, filter := range f.filters {
-			if err := filter.Apply(img, nil); err != nil {
+			if err := applyCtx(ctx, filter, img, nil); err != nil {
 				return err
 			}
 		}
@@ -61,8 +68,8 @@ func (f *CompositeFilter) Apply(img draw.Image, options FilterOption) error {
 			option = opt.Options[i]
 		}
 
-		if err := filter.Apply(img, option); err != nil {
-			if opt.StopOnError {
+		if err := applyCtx(ctx, filter, img, option); err != nil {
+			if opt.StopOnError || ctx.Err() != nil {
 				return err
 			}
 			// Log error but continue if StopOnError is false
@@ -0,0 +1,22 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// rowBand is the number of rows processed between two ctx checks
+const rowBand = 32
+
+// checkRowBand returns ctx.Err() wrapped with the current row when y starts
+// a new row band and ctx is done
+func checkRowBand(ctx context.Context, bounds image.Rectangle, y int) error {
+	if (y-bounds.Min.Y)%rowBand != 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled at row %d of %d: %w", y-bounds.Min.Y, bounds.Dy(), err)
+	}
+	return nil
+}
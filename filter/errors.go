@@ -10,4 +10,5 @@ var (
 	ErrInvalidColor          = errors.New("invalid color")
 	ErrNoFiltersSpecified    = errors.New("no filters specified")
 	ErrFilterOptionsMismatch = errors.New("number of filter options must match number of filters")
+	ErrNoOptionDecoder       = errors.New("no option decoder registered for filter")
 )
@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"context"
 	"image/color"
 	"image/draw"
 )
@@ -15,6 +16,11 @@ func NewGrayscaleFilter() *GrayscaleFilter {
 
 // Apply applies the grayscale filter
 func (f *GrayscaleFilter) Apply(img draw.Image, options FilterOption) error {
+	return f.ApplyCtx(context.Background(), img, options)
+}
+
+// ApplyCtx applies the grayscale filter, checking ctx between row bands
+func (f *GrayscaleFilter) ApplyCtx(ctx context.Context, img draw.Image, options FilterOption) error {
 	// Cast options to GrayscaleOption
 	opt, ok := options.(GrayscaleOption)
 	if !ok {
@@ -26,6 +32,9 @@ func (f *GrayscaleFilter) Apply(img draw.Image, options FilterOption) error {
 
 	bounds := img.Bounds()
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := checkRowBand(ctx, bounds, y); err != nil {
+			return err
+		}
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			c := img.At(x, y)
 			r, g, b, a := c.RGBA()
@@ -2,7 +2,9 @@
 package filter
 
 import (
+	"context"
 	"image/draw"
+	"sort"
 )
 
 // FilterOption defines options for filtering operations
@@ -17,6 +19,15 @@ type Filter interface {
 	Apply(img draw.Image, options FilterOption) error
 }
 
+// ContextFilter is implemented by filters that can be canceled
+// Long pixel loops check ctx between row bands and return ctx.Err()
+// wrapped with the row they stopped at
+type ContextFilter interface {
+	Filter
+	// ApplyCtx applies the filter to the given image, honoring ctx
+	ApplyCtx(ctx context.Context, img draw.Image, options FilterOption) error
+}
+
 // TintOption defines options for tint filter
 type TintOption struct {
 	// Color is the tint color
@@ -36,7 +47,7 @@ func (o TintOption) ValidateOption() error {
 // GrayscaleOption defines options for grayscale filter
 type GrayscaleOption struct {
 	// PreserveAlpha determines whether to preserve the alpha channel
-	PreserveAlpha bool
+	PreserveAlpha bool `json:"preserve_alpha"`
 }
 
 // ValidateOption validates the grayscale options
@@ -47,7 +58,7 @@ func (o GrayscaleOption) ValidateOption() error {
 // OpacityOption defines options for opacity filter
 type OpacityOption struct {
 	// Opacity is between 0 and 1, where 0 means fully transparent and 1 means fully opaque
-	Opacity float64
+	Opacity float64 `json:"opacity"`
 }
 
 // ValidateOption validates the opacity options
@@ -61,7 +72,7 @@ func (o OpacityOption) ValidateOption() error {
 // InvertOption defines options for invert filter
 type InvertOption struct {
 	// InvertAlpha determines whether to invert the alpha channel as well
-	InvertAlpha bool
+	InvertAlpha bool `json:"invert_alpha"`
 }
 
 // ValidateOption validates the invert options
@@ -71,13 +82,15 @@ func (o InvertOption) ValidateOption() error {
 
 // FilterManager manages and applies filters to images
 type FilterManager struct {
-	filters map[string]Filter
+	filters  map[string]Filter
+	decoders map[string]OptionDecoder
 }
 
 // NewFilterManager creates a new filter manager
 func NewFilterManager() *FilterManager {
 	manager := &FilterManager{
-		filters: make(map[string]Filter),
+		filters:  make(map[string]Filter),
+		decoders: make(map[string]OptionDecoder),
 	}
 
 	// Register default filters
@@ -86,6 +99,12 @@ func NewFilterManager() *FilterManager {
 	manager.Register("opacity", NewOpacityFilter())
 	manager.Register("invert", NewInvertFilter())
 
+	// Register option decoders of default filters
+	manager.RegisterOptionDecoder("grayscale", JSONOptionDecoder[GrayscaleOption]())
+	manager.RegisterOptionDecoder("tint", JSONOptionDecoder[TintOption]())
+	manager.RegisterOptionDecoder("opacity", JSONOptionDecoder[OpacityOption]())
+	manager.RegisterOptionDecoder("invert", JSONOptionDecoder[InvertOption]())
+
 	return manager
 }
 
@@ -100,6 +119,23 @@ func (m *FilterManager) Get(name string) (Filter, bool) {
 	return filter, ok
 }
 
+// Names returns the names of all registered filters in sorted order
+func (m *FilterManager) Names() []string {
+	names := make([]string, 0, len(m.filters))
+	for name := range m.filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasOptionDecoder reports whether options of the named filter can be decoded
+// by DecodeOption
+func (m *FilterManager) HasOptionDecoder(name string) bool {
+	_, ok := m.decoders[name]
+	return ok
+}
+
 // Apply applies a named filter to an image
 func (m *FilterManager) Apply(img draw.Image, name string, options FilterOption) error {
 	filter, ok := m.Get(name)
@@ -109,3 +145,26 @@ func (m *FilterManager) Apply(img draw.Image, name string, options FilterOption)
 
 	return filter.Apply(img, options)
 }
+
+// ApplyCtx applies a named filter to an image, honoring ctx
+// Filters that do not implement ContextFilter are only checked before
+// they start
+func (m *FilterManager) ApplyCtx(ctx context.Context, img draw.Image, name string, options FilterOption) error {
+	filter, ok := m.Get(name)
+	if !ok {
+		return ErrFilterNotFound
+	}
+
+	return applyCtx(ctx, filter, img, options)
+}
+
+// applyCtx applies a filter, using ApplyCtx when the filter supports it
+func applyCtx(ctx context.Context, filter Filter, img draw.Image, options FilterOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cf, ok := filter.(ContextFilter); ok {
+		return cf.ApplyCtx(ctx, img, options)
+	}
+	return filter.Apply(img, options)
+}
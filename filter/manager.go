@@ -1,12 +1,21 @@
 package filter
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/draw"
 )
 
 // ApplyFilters applies multiple filters to an image and returns a new image
 func (fm *FilterManager) ApplyFilters(src image.Image, filterNames []string, optionsList []FilterOption) (image.Image, error) {
+	return fm.ApplyFiltersCtx(context.Background(), src, filterNames, optionsList)
+}
+
+// ApplyFiltersCtx applies multiple filters to an image and returns a new image
+// ctx is checked between filters and, for filters implementing ContextFilter,
+// between row bands. Cancellation errors wrap ctx.Err() with the filter name
+func (fm *FilterManager) ApplyFiltersCtx(ctx context.Context, src image.Image, filterNames []string, optionsList []FilterOption) (image.Image, error) {
 	// Create a new RGBA image to work with
 	bounds := src.Bounds()
 	dst := image.NewRGBA(bounds)
@@ -24,7 +33,10 @@ func (fm *FilterManager) ApplyFilters(src image.Image, filterNames []string, opt
 			option = optionsList[i]
 		}
 
-		if err := filter.Apply(dst, option); err != nil {
+		if err := applyCtx(ctx, filter, dst, option); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("filter %q (#%d): %w", name, i, err)
+			}
 			return nil, err
 		}
 	}
@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"context"
 	"image/color"
 	"image/draw"
 )
@@ -15,6 +16,11 @@ func NewOpacityFilter() *OpacityFilter {
 
 // Apply applies the opacity filter
 func (f *OpacityFilter) Apply(img draw.Image, options FilterOption) error {
+	return f.ApplyCtx(context.Background(), img, options)
+}
+
+// ApplyCtx applies the opacity filter, checking ctx between row bands
+func (f *OpacityFilter) ApplyCtx(ctx context.Context, img draw.Image, options FilterOption) error {
 	// Cast options to OpacityOption
 	opt, ok := options.(OpacityOption)
 	if !ok {
@@ -31,6 +37,9 @@ func (f *OpacityFilter) Apply(img draw.Image, options FilterOption) error {
 
 	bounds := img.Bounds()
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := checkRowBand(ctx, bounds, y); err != nil {
+			return err
+		}
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			c := img.At(x, y)
 			r, g, b, a := c.RGBA()
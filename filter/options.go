@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// OptionDecoder decodes the JSON encoded options of a filter
+type OptionDecoder func(data []byte) (FilterOption, error)
+
+// JSONOptionDecoder returns an OptionDecoder that decodes JSON into T,
+// rejecting unknown fields, and validates the result
+func JSONOptionDecoder[T FilterOption]() OptionDecoder {
+	return func(data []byte) (FilterOption, error) {
+		var opt T
+		if err := decodeStrict(data, &opt); err != nil {
+			return nil, err
+		}
+		if err := opt.ValidateOption(); err != nil {
+			return nil, err
+		}
+		return opt, nil
+	}
+}
+
+// RegisterOptionDecoder registers the decoder used by DecodeOption for the
+// options of a named filter
+func (m *FilterManager) RegisterOptionDecoder(name string, decoder OptionDecoder) {
+	m.decoders[name] = decoder
+}
+
+// DecodeOption decodes JSON encoded options for a registered filter
+// Empty data and JSON null decode to nil options
+func (m *FilterManager) DecodeOption(name string, data []byte) (FilterOption, error) {
+	if _, ok := m.Get(name); !ok {
+		return nil, fmt.Errorf("%w: %q", ErrFilterNotFound, name)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+
+	decoder, ok := m.decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoOptionDecoder, name)
+	}
+	return decoder(data)
+}
+
+// tintOptionJSON is the serialized form of TintOption
+type tintOptionJSON struct {
+	Color     string  `json:"color"`
+	Intensity float64 `json:"intensity"`
+}
+
+// MarshalJSON implements json.Marshaler, writing the color as a hex string
+func (o TintOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tintOptionJSON{
+		Color:     utils.ToHexString(color.RGBA{R: o.Color[0], G: o.Color[1], B: o.Color[2], A: 255}),
+		Intensity: o.Intensity,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, rejecting unknown fields
+func (o *TintOption) UnmarshalJSON(data []byte) error {
+	var in tintOptionJSON
+	if err := decodeStrict(data, &in); err != nil {
+		return err
+	}
+	c, err := utils.ParseHexColor(in.Color)
+	if err != nil {
+		return fmt.Errorf("color: %w", err)
+	}
+	*o = TintOption{Color: [3]uint8{c.R, c.G, c.B}, Intensity: in.Intensity}
+	return nil
+}
+
+// decodeStrict decodes a single JSON value, rejecting unknown fields
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVGFilter is implemented by filters that can be expressed as SVG filter
+// primitives, so vector output can keep them instead of rasterizing
+//
+// Primitives read the result of the previous primitive, so the primitives of
+// several filters concatenated in one <filter> element apply them in order.
+// SVG filters work on unpremultiplied colors, which matches Apply exactly on
+// opaque pixels and closely on translucent ones.
+type SVGFilter interface {
+	Filter
+	// SVGPrimitives returns the SVG filter primitives reproducing the filter
+	SVGPrimitives(options FilterOption) (string, error)
+}
+
+// lumRow holds the luminance weights used by grayscale and tint
+var lumRow = [3]float64{0.299, 0.587, 0.114}
+
+// colorMatrix formats an feColorMatrix primitive from a 4x5 matrix in
+// row-major order, offsets are in the 0-1 range. Values are rounded to four
+// decimals, finer than 8-bit channels can tell apart
+func colorMatrix(m [4][5]float64) string {
+	values := make([]string, 0, 20)
+	for _, row := range m {
+		for _, v := range row {
+			values = append(values, strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64))
+		}
+	}
+	return fmt.Sprintf(`<feColorMatrix type="matrix" values="%s"/>`, strings.Join(values, " "))
+}
+
+// SVGPrimitives implements SVGFilter
+func (f *GrayscaleFilter) SVGPrimitives(options FilterOption) (string, error) {
+	opt, ok := options.(GrayscaleOption)
+	if !ok {
+		opt = GrayscaleOption{PreserveAlpha: true}
+	}
+
+	lum := [5]float64{lumRow[0], lumRow[1], lumRow[2], 0, 0}
+	alpha := lum
+	if opt.PreserveAlpha {
+		alpha = [5]float64{0, 0, 0, 1, 0}
+	}
+	return colorMatrix([4][5]float64{lum, lum, lum, alpha}), nil
+}
+
+// SVGPrimitives implements SVGFilter
+func (f *TintFilter) SVGPrimitives(options FilterOption) (string, error) {
+	opt, ok := options.(TintOption)
+	if !ok {
+		return "", ErrInvalidColor
+	}
+	if err := opt.ValidateOption(); err != nil {
+		return "", err
+	}
+
+	// Each channel mixes the luminance with the tint color by intensity
+	keep := 1 - opt.Intensity
+	var m [4][5]float64
+	for i := 0; i < 3; i++ {
+		m[i] = [5]float64{lumRow[0] * keep, lumRow[1] * keep, lumRow[2] * keep, 0,
+			float64(opt.Color[i]) / 255 * opt.Intensity}
+	}
+	m[3] = [5]float64{0, 0, 0, 1, 0}
+	return colorMatrix(m), nil
+}
+
+// SVGPrimitives implements SVGFilter
+func (f *InvertFilter) SVGPrimitives(options FilterOption) (string, error) {
+	opt, _ := options.(InvertOption)
+
+	m := [4][5]float64{
+		{-1, 0, 0, 0, 1},
+		{0, -1, 0, 0, 1},
+		{0, 0, -1, 0, 1},
+		{0, 0, 0, 1, 0},
+	}
+	if opt.InvertAlpha {
+		m[3] = [5]float64{0, 0, 0, -1, 1}
+	}
+	return colorMatrix(m), nil
+}
+
+// SVGPrimitives implements SVGFilter
+func (f *OpacityFilter) SVGPrimitives(options FilterOption) (string, error) {
+	opt, ok := options.(OpacityOption)
+	if !ok {
+		opt = OpacityOption{Opacity: 1.0}
+	}
+	if err := opt.ValidateOption(); err != nil {
+		return "", err
+	}
+
+	return colorMatrix([4][5]float64{
+		{1, 0, 0, 0, 0},
+		{0, 1, 0, 0, 0},
+		{0, 0, 1, 0, 0},
+		{0, 0, 0, opt.Opacity, 0},
+	}), nil
+}
+
+// SVGPrimitives implements SVGFilter when every filter of the composite does
+func (f *CompositeFilter) SVGPrimitives(options FilterOption) (string, error) {
+	filters := f.filters
+	var opts []FilterOption
+	if opt, ok := options.(CompositeOption); ok {
+		if err := opt.ValidateOption(); err != nil {
+			return "", err
+		}
+		filters, opts = opt.Filters, opt.Options
+	}
+
+	var sb strings.Builder
+	for i, filter := range filters {
+		sf, ok := filter.(SVGFilter)
+		if !ok {
+			return "", fmt.Errorf("composite filter %d (%T) has no SVG representation", i, filter)
+		}
+		var option FilterOption
+		if i < len(opts) {
+			option = opts[i]
+		}
+		primitives, err := sf.SVGPrimitives(option)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(primitives)
+	}
+	return sb.String(), nil
+}
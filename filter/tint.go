@@ -1,6 +1,7 @@
 package filter
 
 import (
+	"context"
 	"image/color"
 	"image/draw"
 	"math"
@@ -16,6 +17,11 @@ func NewTintFilter() *TintFilter {
 
 // Apply applies the tint filter
 func (f *TintFilter) Apply(img draw.Image, options FilterOption) error {
+	return f.ApplyCtx(context.Background(), img, options)
+}
+
+// ApplyCtx applies the tint filter, checking ctx between row bands
+func (f *TintFilter) ApplyCtx(ctx context.Context, img draw.Image, options FilterOption) error {
 	// Cast options to TintOption
 	opt, ok := options.(TintOption)
 	if !ok {
@@ -33,6 +39,9 @@ func (f *TintFilter) Apply(img draw.Image, options FilterOption) error {
 
 	bounds := img.Bounds()
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		if err := checkRowBand(ctx, bounds, y); err != nil {
+			return err
+		}
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			c := img.At(x, y)
 			r, g, b, a := c.RGBA()
@@ -7,11 +7,20 @@ import (
 )
 
 // ParseHexColor 将十六进制颜色字符串解析为 color.RGBA
-// 支持格式: #RGB, #RGBA, RGB, RGBA
+// 支持格式: #RRGGBB, #RRGGBBAA 及简写 #RGB, #RGBA，# 可省略
 func ParseHexColor(colorStr string) (color.RGBA, error) {
 	// 移除#号
 	colorStr = strings.TrimPrefix(colorStr, "#")
 
+	// 简写形式每位重复一次，例如 #35A 等价于 #3355AA
+	if len(colorStr) == 3 || len(colorStr) == 4 {
+		expanded := make([]byte, 0, len(colorStr)*2)
+		for i := 0; i < len(colorStr); i++ {
+			expanded = append(expanded, colorStr[i], colorStr[i])
+		}
+		colorStr = string(expanded)
+	}
+
 	// 确保字符串长度为6或8
 	if len(colorStr) != 6 && len(colorStr) != 8 {
 		return color.RGBA{}, fmt.Errorf("invalid color format: %s", colorStr)
@@ -114,3 +123,30 @@ func ToHexString(c color.RGBA) string {
 	}
 	return fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
 }
+
+// FormatColor 将任意颜色格式化为非预乘的十六进制字符串
+// 与 ParseColor 配合可在 JSON 等文本格式中无损往返
+func FormatColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return ToHexString(color.RGBA{R: n.R, G: n.G, B: n.B, A: n.A})
+}
+
+// ParseColor 解析 FormatColor 输出的十六进制字符串
+// 不透明颜色返回 color.RGBA，半透明颜色按非预乘语义返回 color.NRGBA
+func ParseColor(colorStr string) (color.Color, error) {
+	c, err := ParseHexColor(colorStr)
+	if err != nil {
+		return nil, err
+	}
+	if c.A == 255 {
+		return c, nil
+	}
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}, nil
+}
+
+// SVGPaint 将任意颜色转换为 SVG 颜色 #RRGGBB 和 0-1 的不透明度
+// SVG 1.1 不支持 #RRGGBBAA，透明度需要单独写入 fill-opacity 等属性
+func SVGPaint(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B), float64(n.A) / 255
+}
@@ -0,0 +1,91 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bagaking/iconmarker/core"
+)
+
+// errNotAcceptable 表示 Accept 请求头不接受任何支持的格式
+var errNotAcceptable = errors.New("no acceptable image format")
+
+// formatPreference 是 Accept 中权重相同或使用通配符时选择格式的顺序
+var formatPreference = []core.ImageFormat{core.FormatPNG, core.FormatJPEG, core.FormatGIF, core.FormatTIFF, core.FormatBMP}
+
+// negotiateFormat 选择输出格式
+// format 查询参数优先；否则按 Accept 中的权重选择，Accept 缺省时为 PNG
+func negotiateFormat(param, accept string) (core.ImageFormat, error) {
+	if param != "" {
+		format, err := core.ParseImageFormat(param)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", errBadRequest, err)
+		}
+		return format, nil
+	}
+	if strings.TrimSpace(accept) == "" {
+		return core.FormatPNG, nil
+	}
+
+	best, bestQ := core.ImageFormat(""), 0.0
+	for _, format := range formatPreference {
+		if q := acceptQuality(accept, format.ContentType()); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	if bestQ == 0 {
+		return "", errNotAcceptable
+	}
+	return best, nil
+}
+
+// acceptQuality 返回 Accept 请求头给媒体类型的权重
+// 精确匹配优先于 image/*，image/* 优先于 */*
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		rng := strings.ToLower(strings.TrimSpace(fields[0]))
+
+		var s int
+		switch rng {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality
+}
+
+// etagMatch 按弱比较判断 If-None-Match 是否匹配 etag
+func etagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"net/url"
+	"strconv"
+
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/filter/utils"
+)
+
+// errBadRequest 表示无效的查询参数
+var errBadRequest = errors.New("bad request")
+
+// queryParams 列出 GET 请求支持的查询参数，出现其他参数时返回 400，以免拼写错误被静默忽略
+var queryParams = map[string]bool{
+	"text":       true,
+	"icon":       true,
+	"icon_color": true,
+	"bg":         true,
+	"text_color": true,
+	"size":       true,
+	"format":     true,
+}
+
+// specFromQuery 将查询参数转换为 IconSpec
+// 颜色为 #RGB、#RRGGBB 或带透明度的十六进制字符串，bg 缺省时使用默认背景色
+func (s *Server) specFromQuery(q url.Values) (*core.IconSpec, error) {
+	for name, values := range q {
+		if !queryParams[name] {
+			return nil, fmt.Errorf("%w: unknown query parameter %q", errBadRequest, name)
+		}
+		if len(values) > 1 {
+			return nil, fmt.Errorf("%w: query parameter %q given more than once", errBadRequest, name)
+		}
+	}
+
+	params := core.IconParams{
+		Text:       q.Get("text"),
+		Icon:       q.Get("icon"),
+		Background: defaultBackground,
+		Size:       s.opts.DefaultSize,
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid size %q", errBadRequest, v)
+		}
+		params.Size = size
+	}
+
+	var err error
+	if params.IconColor, err = queryColor(q, "icon_color"); err != nil {
+		return nil, err
+	}
+	if params.TextColor, err = queryColor(q, "text_color"); err != nil {
+		return nil, err
+	}
+	if bg, err := queryColor(q, "bg"); err != nil {
+		return nil, err
+	} else if bg != nil {
+		params.Background = bg
+	}
+	return params.Spec()
+}
+
+// defaultBackground 是查询参数未指定 bg 时的背景色
+var defaultBackground = color.RGBA{R: 0x33, G: 0x66, B: 0x99, A: 0xff}
+
+// queryColor 解析颜色参数，参数缺省时返回 nil
+func queryColor(q url.Values, name string) (color.Color, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	c, err := utils.ParseColor(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s: %v", errBadRequest, name, err)
+	}
+	return c, nil
+}
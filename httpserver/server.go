@@ -0,0 +1,190 @@
+// Package httpserver serves rendered icons over HTTP, mapping URL query
+// parameters or a posted IconSpec document to renders
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+// 默认配置
+const (
+	DefaultMaxAge       = 24 * time.Hour
+	DefaultIconSize     = 128
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+// Options 配置 Server，零值字段使用默认值
+type Options struct {
+	MaxAge       time.Duration // Cache-Control 的 max-age，为 0 时使用 DefaultMaxAge，小于 0 时禁止缓存
+	DefaultSize  int           // 查询参数未指定 size 时的边长，为 0 时使用 DefaultIconSize
+	MaxBodyBytes int64         // POST 请求体的最大字节数，为 0 时使用 DefaultMaxBodyBytes
+	ErrorLog     *log.Logger   // 记录渲染失败等服务端错误，为 nil 时不记录
+}
+
+// Server 是渲染图标的 http.Handler
+//
+//	GET  /icon?text=Team+A&icon=robot&bg=%23335&size=128
+//	POST /icon  (请求体为 JSON 或 YAML 格式的 IconSpec)
+//
+// 输出格式由 format 查询参数或 Accept 请求头决定；响应带有由渲染参数哈希得到的 ETag，
+// 支持 If-None-Match 条件请求；资源限制沿用 IconMarker 上配置的 Limits
+type Server struct {
+	marker *core.IconMarker
+	opts   Options
+	mux    *http.ServeMux
+}
+
+// NewServer 创建 Server，marker 为 nil 时创建启用默认资源限制的 IconMarker
+func NewServer(marker *core.IconMarker, opts Options) *Server {
+	if marker == nil {
+		marker = core.NewIconMarker()
+		marker.SetLimits(limits.Default())
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.DefaultSize <= 0 {
+		opts.DefaultSize = DefaultIconSize
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	s := &Server{marker: marker, opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/icon", s.handleIcon)
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleIcon 处理 /icon 请求
+func (s *Server) handleIcon(w http.ResponseWriter, r *http.Request) {
+	var spec *core.IconSpec
+	var err error
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		spec, err = s.specFromQuery(r.URL.Query())
+	case http.MethodPost:
+		spec, err = s.specFromBody(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err == nil {
+		// 提前解析滤镜并检查画布和背景尺寸，确保无效或超限的文档
+		// 不会得到 ETag 和 304，也不会在渲染时分配过多内存
+		err = s.marker.ValidateSpec(spec)
+	}
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	format, err := negotiateFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	key, err := core.SpecCacheKey(spec, format)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	etag := `"` + key[:32] + `"`
+
+	if r.Method != http.MethodPost && etagMatch(r.Header.Get("If-None-Match"), etag) {
+		s.setCacheHeaders(w.Header(), etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	img, err := s.marker.RenderSpecCtx(r.Context(), spec)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	data, err := core.EncodeToBytes(img, core.EncodeOptions{Format: format})
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if err = s.marker.Limits().CheckOutputBytes(len(data)); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	// 缓存头只随成功的响应发送，避免代理缓存错误响应
+	h := w.Header()
+	s.setCacheHeaders(h, etag)
+	h.Set("Content-Type", format.ContentType())
+	h.Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
+
+// setCacheHeaders 设置 200 和 304 响应的缓存头
+func (s *Server) setCacheHeaders(h http.Header, etag string) {
+	h.Set("ETag", etag)
+	h.Set("Vary", "Accept")
+	if s.opts.MaxAge > 0 {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.opts.MaxAge/time.Second)))
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+}
+
+// specFromBody 读取并解析 POST 请求体中的 IconSpec
+func (s *Server) specFromBody(w http.ResponseWriter, r *http.Request) (*core.IconSpec, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	return core.ParseIconSpec(data)
+}
+
+// fail 按错误类型返回对应的状态码
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	code := statusCode(err)
+	if code >= http.StatusInternalServerError && s.opts.ErrorLog != nil {
+		s.opts.ErrorLog.Printf("httpserver: %s %s: %v", r.Method, r.URL, err)
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// statusCode 将错误映射为 HTTP 状态码
+// 无效的参数或文档为 400，过大的输入为 413，超出其余资源限制为 422
+func statusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, core.ErrInvalidSpec), errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, errNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.As(err, &maxBytesErr), errors.Is(err, limits.ErrInputTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, limits.ErrImageTooLarge), errors.Is(err, limits.ErrSVGTooComplex),
+		errors.Is(err, limits.ErrOutputTooLarge):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
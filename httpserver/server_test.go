@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post sends a spec document to a server with the default limits
+func post(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/icon", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPostRejectsOversizedSpecs(t *testing.T) {
+	s := NewServer(nil, Options{})
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "oversized background",
+			body: `{"version":1,"canvas":{"width":64,"height":64},"background":{"kind":"solid","color":"#112233","width":10000,"height":10000}}`,
+			code: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "background above the hard maximum",
+			body: `{"version":1,"canvas":{"width":64,"height":64},"background":{"kind":"solid","color":"#112233","width":40000,"height":40000}}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "oversized canvas",
+			body: `{"version":1,"canvas":{"width":40000,"height":40000}}`,
+			code: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "tiny noise cells",
+			body: `{"version":1,"canvas":{"width":64,"height":64},"background":{"kind":"noise","cell_size":0.0001}}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "oversized body",
+			body: `{"version":1,"canvas":{"width":64,"height":64},"font":"` + strings.Repeat("A", DefaultMaxBodyBytes) + `"}`,
+			code: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(t, s, tt.body)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			for _, name := range []string{"ETag", "Cache-Control"} {
+				if v := rec.Header().Get(name); v != "" {
+					t.Errorf("error response has %s %q", name, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCacheHeaders(t *testing.T) {
+	s := NewServer(nil, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/icon?text=A&size=32", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || !strings.HasPrefix(rec.Header().Get("Cache-Control"), "public") {
+		t.Fatalf("missing cache headers: %v", rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/icon?text=A&size=32", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != etag {
+		t.Fatalf("status %d, ETag %q, want 304 with %q", rec.Code, rec.Header().Get("ETag"), etag)
+	}
+}
@@ -0,0 +1,172 @@
+// Package limits defines resource limits that protect rendering from
+// hostile input, such as decompression bombs and overly complex SVGs
+package limits
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits bounds the resources a single render may consume
+// A zero field means the corresponding resource is not limited
+type Limits struct {
+	MaxInputBytes   int64 // Maximum size of a single input (background, SVG, font) in bytes
+	MaxInputWidth   int   // Maximum decoded width of an input image in pixels
+	MaxInputHeight  int   // Maximum decoded height of an input image in pixels
+	MaxInputPixels  int64 // Maximum decoded width*height of an input image
+	MaxSVGElements  int   // Maximum number of elements in an SVG document
+	MaxSVGPaths     int   // Maximum number of drawable paths in a parsed SVG
+	MaxOutputWidth  int   // Maximum output width in pixels
+	MaxOutputHeight int   // Maximum output height in pixels
+	MaxOutputBytes  int64 // Maximum size of an encoded output in bytes
+}
+
+// Default returns limits suitable for rendering untrusted user uploads
+func Default() Limits {
+	return Limits{
+		MaxInputBytes:   10 << 20,
+		MaxInputWidth:   8192,
+		MaxInputHeight:  8192,
+		MaxInputPixels:  4096 * 4096,
+		MaxSVGElements:  10000,
+		MaxSVGPaths:     5000,
+		MaxOutputWidth:  4096,
+		MaxOutputHeight: 4096,
+		MaxOutputBytes:  20 << 20,
+	}
+}
+
+// Kind identifies which limit was violated
+type Kind string
+
+// Limit kinds
+const (
+	KindInputBytes       Kind = "input_bytes"
+	KindInputDimensions  Kind = "input_dimensions"
+	KindInputPixels      Kind = "input_pixels"
+	KindSVGElements      Kind = "svg_elements"
+	KindSVGPaths         Kind = "svg_paths"
+	KindOutputDimensions Kind = "output_dimensions"
+	KindOutputBytes      Kind = "output_bytes"
+)
+
+// Sentinel errors, a *Error matches the one of its category with errors.Is
+var (
+	ErrInputTooLarge  = errors.New("input too large")
+	ErrImageTooLarge  = errors.New("image dimensions too large")
+	ErrSVGTooComplex  = errors.New("svg too complex")
+	ErrOutputTooLarge = errors.New("output too large")
+)
+
+// Error reports a violated limit
+type Error struct {
+	Kind    Kind   // Which limit was violated
+	Subject string // What was checked, e.g. "background" or "svg"
+	Limit   int64  // The configured limit
+	Actual  int64  // The observed value, a lower bound when counting stopped early
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s %d exceeds limit %d", e.Subject, e.Kind, e.Actual, e.Limit)
+}
+
+// Is matches the sentinel error of the limit's category
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInputTooLarge:
+		return e.Kind == KindInputBytes
+	case ErrImageTooLarge:
+		return e.Kind == KindInputDimensions || e.Kind == KindInputPixels
+	case ErrSVGTooComplex:
+		return e.Kind == KindSVGElements || e.Kind == KindSVGPaths
+	case ErrOutputTooLarge:
+		return e.Kind == KindOutputDimensions || e.Kind == KindOutputBytes
+	}
+	return false
+}
+
+// CheckInputBytes checks the size of an input
+func (l Limits) CheckInputBytes(subject string, size int) error {
+	if l.MaxInputBytes > 0 && int64(size) > l.MaxInputBytes {
+		return &Error{Kind: KindInputBytes, Subject: subject, Limit: l.MaxInputBytes, Actual: int64(size)}
+	}
+	return nil
+}
+
+// CheckImageSize checks the decoded dimensions of an input image, typically
+// obtained from image.DecodeConfig before the full decode
+func (l Limits) CheckImageSize(subject string, width, height int) error {
+	if l.MaxInputWidth > 0 && width > l.MaxInputWidth {
+		return &Error{Kind: KindInputDimensions, Subject: subject + " width", Limit: int64(l.MaxInputWidth), Actual: int64(width)}
+	}
+	if l.MaxInputHeight > 0 && height > l.MaxInputHeight {
+		return &Error{Kind: KindInputDimensions, Subject: subject + " height", Limit: int64(l.MaxInputHeight), Actual: int64(height)}
+	}
+	if pixels := int64(width) * int64(height); l.MaxInputPixels > 0 && pixels > l.MaxInputPixels {
+		return &Error{Kind: KindInputPixels, Subject: subject, Limit: l.MaxInputPixels, Actual: pixels}
+	}
+	return nil
+}
+
+// CheckOutputSize checks the dimensions of an output image
+func (l Limits) CheckOutputSize(width, height int) error {
+	if l.MaxOutputWidth > 0 && width > l.MaxOutputWidth {
+		return &Error{Kind: KindOutputDimensions, Subject: "output width", Limit: int64(l.MaxOutputWidth), Actual: int64(width)}
+	}
+	if l.MaxOutputHeight > 0 && height > l.MaxOutputHeight {
+		return &Error{Kind: KindOutputDimensions, Subject: "output height", Limit: int64(l.MaxOutputHeight), Actual: int64(height)}
+	}
+	return nil
+}
+
+// CheckOutputBytes checks the size of an encoded output
+func (l Limits) CheckOutputBytes(size int) error {
+	if l.MaxOutputBytes > 0 && int64(size) > l.MaxOutputBytes {
+		return &Error{Kind: KindOutputBytes, Subject: "output", Limit: l.MaxOutputBytes, Actual: int64(size)}
+	}
+	return nil
+}
+
+// CheckSVG checks the size and element count of an SVG document
+// Elements are counted with a streaming tokenizer that stops as soon as
+// the limit is exceeded, so hostile documents are never fully parsed
+func (l Limits) CheckSVG(data []byte) error {
+	if err := l.CheckInputBytes("svg", len(data)); err != nil {
+		return err
+	}
+	if l.MaxSVGElements <= 0 {
+		return nil
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	count := 0
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// Malformed documents are rejected later by the SVG parser
+			return nil
+		}
+		if _, ok := tok.(xml.StartElement); !ok {
+			continue
+		}
+		if count++; count > l.MaxSVGElements {
+			return &Error{Kind: KindSVGElements, Subject: "svg", Limit: int64(l.MaxSVGElements), Actual: int64(count)}
+		}
+	}
+}
+
+// CheckSVGPaths checks the number of drawable paths of a parsed SVG
+func (l Limits) CheckSVGPaths(count int) error {
+	if l.MaxSVGPaths > 0 && count > l.MaxSVGPaths {
+		return &Error{Kind: KindSVGPaths, Subject: "svg", Limit: int64(l.MaxSVGPaths), Actual: int64(count)}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChecksReturnTypedErrors(t *testing.T) {
+	l := Limits{
+		MaxInputBytes:   100,
+		MaxInputWidth:   50,
+		MaxInputHeight:  40,
+		MaxInputPixels:  1000,
+		MaxSVGElements:  3,
+		MaxSVGPaths:     2,
+		MaxOutputWidth:  64,
+		MaxOutputHeight: 32,
+		MaxOutputBytes:  200,
+	}
+	tests := []struct {
+		name     string
+		err      error
+		want     Error
+		sentinel error
+	}{
+		{"input bytes", l.CheckInputBytes("font", 101),
+			Error{Kind: KindInputBytes, Subject: "font", Limit: 100, Actual: 101}, ErrInputTooLarge},
+		{"input width", l.CheckImageSize("background", 51, 10),
+			Error{Kind: KindInputDimensions, Subject: "background width", Limit: 50, Actual: 51}, ErrImageTooLarge},
+		{"input height", l.CheckImageSize("background", 10, 41),
+			Error{Kind: KindInputDimensions, Subject: "background height", Limit: 40, Actual: 41}, ErrImageTooLarge},
+		{"input pixels", l.CheckImageSize("background", 50, 40),
+			Error{Kind: KindInputPixels, Subject: "background", Limit: 1000, Actual: 2000}, ErrImageTooLarge},
+		{"svg bytes", l.CheckSVG([]byte(strings.Repeat(" ", 101))),
+			Error{Kind: KindInputBytes, Subject: "svg", Limit: 100, Actual: 101}, ErrInputTooLarge},
+		{"svg elements", l.CheckSVG([]byte("<svg><g><g><path/></g></g></svg>")),
+			Error{Kind: KindSVGElements, Subject: "svg", Limit: 3, Actual: 4}, ErrSVGTooComplex},
+		{"svg paths", l.CheckSVGPaths(3),
+			Error{Kind: KindSVGPaths, Subject: "svg", Limit: 2, Actual: 3}, ErrSVGTooComplex},
+		{"output width", l.CheckOutputSize(65, 10),
+			Error{Kind: KindOutputDimensions, Subject: "output width", Limit: 64, Actual: 65}, ErrOutputTooLarge},
+		{"output height", l.CheckOutputSize(10, 33),
+			Error{Kind: KindOutputDimensions, Subject: "output height", Limit: 32, Actual: 33}, ErrOutputTooLarge},
+		{"output bytes", l.CheckOutputBytes(201),
+			Error{Kind: KindOutputBytes, Subject: "output", Limit: 200, Actual: 201}, ErrOutputTooLarge},
+	}
+	sentinels := []error{ErrInputTooLarge, ErrImageTooLarge, ErrSVGTooComplex, ErrOutputTooLarge}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var limitErr *Error
+			if !errors.As(tt.err, &limitErr) {
+				t.Fatalf("got %v, want *Error", tt.err)
+			}
+			if *limitErr != tt.want {
+				t.Errorf("got %+v, want %+v", *limitErr, tt.want)
+			}
+
+			// Wrapping keeps the sentinel, and only the matching one matches
+			wrapped := fmt.Errorf("rendering: %w", tt.err)
+			for _, sentinel := range sentinels {
+				if got := errors.Is(wrapped, sentinel); got != (sentinel == tt.sentinel) {
+					t.Errorf("errors.Is(%v) = %v", sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestChecksWithinLimits(t *testing.T) {
+	l := Default()
+	checks := []struct {
+		name string
+		err  error
+	}{
+		{"input bytes", l.CheckInputBytes("font", int(l.MaxInputBytes))},
+		{"image size", l.CheckImageSize("background", 4096, 4096)},
+		{"svg", l.CheckSVG([]byte("<svg><path/></svg>"))},
+		{"malformed svg", l.CheckSVG([]byte("<svg><path"))},
+		{"svg paths", l.CheckSVGPaths(l.MaxSVGPaths)},
+		{"output size", l.CheckOutputSize(l.MaxOutputWidth, l.MaxOutputHeight)},
+		{"output bytes", l.CheckOutputBytes(int(l.MaxOutputBytes))},
+	}
+	for _, tt := range checks {
+		if tt.err != nil {
+			t.Errorf("%s: %v", tt.name, tt.err)
+		}
+	}
+}
+
+func TestZeroLimitsAllowEverything(t *testing.T) {
+	var l Limits
+	huge := 1 << 30
+	checks := []error{
+		l.CheckInputBytes("font", huge),
+		l.CheckImageSize("background", huge, huge),
+		l.CheckSVG([]byte(strings.Repeat("<g>", 1000))),
+		l.CheckSVGPaths(huge),
+		l.CheckOutputSize(huge, huge),
+		l.CheckOutputBytes(huge),
+	}
+	for i, err := range checks {
+		if err != nil {
+			t.Errorf("check %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/freetype"
+)
+
+// FontRegistry maps CSS font-family names to TrueType font data for SVG
+// <text> rendering. Names are matched case-insensitively, the first
+// registered family of a font-family list wins and text without a match
+// uses the embedded default font. Generic families such as sans-serif can
+// be registered like any other name. It is safe for concurrent use
+type FontRegistry struct {
+	mu    sync.RWMutex
+	fonts map[string][]byte
+}
+
+// NewFontRegistry creates an empty font registry
+func NewFontRegistry() *FontRegistry {
+	return &FontRegistry{fonts: make(map[string][]byte)}
+}
+
+// Register maps a font family to font data, replacing an earlier mapping
+// The data is parsed once to reject invalid fonts early
+func (f *FontRegistry) Register(family string, fontData []byte) error {
+	name := normalizeFamily(family)
+	if name == "" {
+		return fmt.Errorf("font family is empty")
+	}
+	if _, err := freetype.ParseFont(fontData); err != nil {
+		return fmt.Errorf("error parsing font for family %q: %w", family, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fonts[name] = fontData
+	return nil
+}
+
+// Families returns the registered family names in lower case, sorted
+func (f *FontRegistry) Families() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.fonts))
+	for name := range f.fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the font data of the first registered family in a CSS
+// font-family list, nil when none is registered
+func (f *FontRegistry) lookup(fontFamily string) []byte {
+	if f == nil || fontFamily == "" {
+		return nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, family := range strings.Split(fontFamily, ",") {
+		if data, ok := f.fonts[normalizeFamily(family)]; ok {
+			return data
+		}
+	}
+	return nil
+}
+
+// normalizeFamily trims whitespace and quotes and lower-cases a family name
+func normalizeFamily(family string) string {
+	family = strings.TrimSpace(family)
+	family = strings.Trim(family, `"'`)
+	return strings.ToLower(strings.TrimSpace(family))
+}
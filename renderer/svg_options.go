@@ -0,0 +1,212 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// AspectRatio controls how the viewBox of an SVG is fitted into the output,
+// following SVG's preserveAspectRatio with xMidYMid alignment
+type AspectRatio string
+
+// Supported aspect ratio modes
+const (
+	AspectMeet  AspectRatio = "meet"  // scale to fit inside the output, centered
+	AspectSlice AspectRatio = "slice" // scale to cover the output, centered, overflow is clipped
+	AspectNone  AspectRatio = "none"  // stretch to fill the output
+)
+
+// SVGLayoutOption can be implemented by an SVGRenderOption to control the
+// layout of the output. Options that do not implement it follow the
+// preserveAspectRatio of the document without padding or background
+type SVGLayoutOption interface {
+	// GetAspectRatio returns the aspect ratio mode, empty follows the document
+	GetAspectRatio() AspectRatio
+	// GetPadding returns the transparent margin on each side in pixels
+	GetPadding() int
+	// GetBackground returns the color filling the whole output, nil for none
+	GetBackground() color.Color
+}
+
+// SVGOptions is the built-in SVGRenderOption
+//
+// When Width and Height are both 0 the content is rendered at the intrinsic
+// size of the viewBox and the padding is added around it. When only one is 0
+// it is derived from the viewBox aspect ratio. Otherwise Width and Height
+// are the output size and the padding is taken from it. Placement is only
+// used by RenderOnImage
+type SVGOptions struct {
+	Data        []byte
+	Width       int
+	Height      int
+	AspectRatio AspectRatio  // empty follows the preserveAspectRatio of the document, meet by default
+	Padding     int          // margin on each side in pixels
+	Background  color.Color  // fills the whole output including the padding, nil keeps it transparent
+	Colors      *SVGColorMap // recolors the SVG before rasterizing, see SVGColorMap
+	Placement   SVGPlacement // position, transform, opacity and clip on the destination
+}
+
+// GetSVGData returns the SVG data
+func (o *SVGOptions) GetSVGData() []byte {
+	return o.Data
+}
+
+// GetDimensions returns the requested output size, 0 means intrinsic
+func (o *SVGOptions) GetDimensions() (width, height int) {
+	return o.Width, o.Height
+}
+
+// GetAspectRatio returns the aspect ratio mode
+func (o *SVGOptions) GetAspectRatio() AspectRatio {
+	return o.AspectRatio
+}
+
+// GetPadding returns the padding in pixels
+func (o *SVGOptions) GetPadding() int {
+	return o.Padding
+}
+
+// GetBackground returns the background color
+func (o *SVGOptions) GetBackground() color.Color {
+	return o.Background
+}
+
+// GetColorMap returns the color mapping
+func (o *SVGOptions) GetColorMap() *SVGColorMap {
+	return o.Colors
+}
+
+// GetPlacement returns the placement on the destination
+func (o *SVGOptions) GetPlacement() SVGPlacement {
+	return o.Placement
+}
+
+// ValidateOption validates the options
+func (o *SVGOptions) ValidateOption() error {
+	if len(o.Data) == 0 {
+		return fmt.Errorf("SVG data is empty")
+	}
+	if o.Width < 0 || o.Height < 0 {
+		return fmt.Errorf("invalid dimensions: width=%d, height=%d", o.Width, o.Height)
+	}
+	if o.Padding < 0 {
+		return fmt.Errorf("invalid padding: %d", o.Padding)
+	}
+	if err := o.AspectRatio.validate(); err != nil {
+		return err
+	}
+	if err := o.Placement.validate(); err != nil {
+		return err
+	}
+	return o.Colors.Validate()
+}
+
+// validate checks the mode, empty is valid
+func (a AspectRatio) validate() error {
+	switch a {
+	case "", AspectMeet, AspectSlice, AspectNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid aspect ratio %q, expected meet, slice or none", string(a))
+	}
+}
+
+// svgLayout is the resolved layout of one render
+type svgLayout struct {
+	width, height int
+	padding       int
+	aspect        AspectRatio
+	background    color.Color
+}
+
+// layoutFromOptions reads the layout from the options, width and height may
+// still be 0 until the viewBox is known
+func layoutFromOptions(options SVGRenderOption) (svgLayout, error) {
+	var l svgLayout
+	l.width, l.height = options.GetDimensions()
+	if l.width < 0 || l.height < 0 {
+		return l, fmt.Errorf("invalid dimensions: width=%d, height=%d", l.width, l.height)
+	}
+	if lo, ok := options.(SVGLayoutOption); ok {
+		l.aspect, l.padding, l.background = lo.GetAspectRatio(), lo.GetPadding(), lo.GetBackground()
+		if err := l.aspect.validate(); err != nil {
+			return l, err
+		}
+		if l.padding < 0 {
+			return l, fmt.Errorf("invalid padding: %d", l.padding)
+		}
+	}
+	return l, nil
+}
+
+// resolve fills in the intrinsic size and returns the content rectangle
+// inside the padding; the viewBox is vw×vh
+func (l *svgLayout) resolve(vw, vh float64) (image.Rectangle, error) {
+	if l.width == 0 || l.height == 0 {
+		if vw <= 0 || vh <= 0 {
+			return image.Rectangle{}, fmt.Errorf("SVG has no viewBox or size, output dimensions are required")
+		}
+		switch {
+		case l.width == 0 && l.height == 0:
+			l.width = int(math.Ceil(vw)) + 2*l.padding
+			l.height = int(math.Ceil(vh)) + 2*l.padding
+		case l.width == 0:
+			l.width = int(math.Round(float64(l.height-2*l.padding)*vw/vh)) + 2*l.padding
+		default:
+			l.height = int(math.Round(float64(l.width-2*l.padding)*vh/vw)) + 2*l.padding
+		}
+	}
+	content := image.Rect(l.padding, l.padding, l.width-l.padding, l.height-l.padding)
+	if content.Empty() {
+		return image.Rectangle{}, fmt.Errorf("padding %d leaves no room in %dx%d", l.padding, l.width, l.height)
+	}
+	return content, nil
+}
+
+// target returns the rectangle the viewBox maps to, relative to a content
+// area of cw×ch
+func (l *svgLayout) target(vw, vh float64, cw, ch int) (x, y, w, h float64) {
+	w, h = float64(cw), float64(ch)
+	if l.aspect == AspectNone || vw <= 0 || vh <= 0 {
+		return 0, 0, w, h
+	}
+	scale := math.Min(w/vw, h/vh)
+	if l.aspect == AspectSlice {
+		scale = math.Max(w/vw, h/vh)
+	}
+	return (w - vw*scale) / 2, (h - vh*scale) / 2, vw * scale, vh * scale
+}
+
+// documentAspectRatio returns the mode given by the preserveAspectRatio of
+// the root element; alignments other than xMidYMid are treated as centered
+func documentAspectRatio(data []byte) AspectRatio {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return AspectMeet
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range se.Attr {
+			if attr.Name.Local != "preserveAspectRatio" {
+				continue
+			}
+			fields := strings.Fields(attr.Value)
+			switch {
+			case len(fields) > 0 && fields[0] == "none":
+				return AspectNone
+			case len(fields) > 1 && fields[1] == "slice":
+				return AspectSlice
+			}
+		}
+		return AspectMeet
+	}
+}
@@ -0,0 +1,100 @@
+package renderer
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/srwiley/rasterx"
+)
+
+// SVGPlacement positions an SVG on the destination of RenderOnImage
+//
+// The SVG is laid out in a box of the output size exactly as Render would
+// lay it out, the box is then scaled and rotated about its center and its
+// top-left corner is moved to (X, Y) on the destination
+type SVGPlacement struct {
+	X, Y     float64         // top-left corner of the unrotated box in destination coordinates
+	Scale    float64         // uniform scale about the box center, 0 means 1
+	Rotation float64         // clockwise rotation about the box center in degrees
+	Opacity  *float64        // 0 to 1, nil draws fully opaque
+	Clip     image.Rectangle // limits drawing to this rectangle of the destination, empty does not clip
+}
+
+// SVGPlacementOption can be implemented by an SVGRenderOption to position
+// the SVG in RenderOnImage. Options that do not implement it are drawn at
+// the origin of the destination coordinate space, untransformed and opaque
+type SVGPlacementOption interface {
+	// GetPlacement returns the placement on the destination
+	GetPlacement() SVGPlacement
+}
+
+// validate checks the scale and the opacity
+func (p SVGPlacement) validate() error {
+	if p.Scale < 0 || math.IsNaN(p.Scale) || math.IsInf(p.Scale, 0) {
+		return fmt.Errorf("invalid placement scale: %v", p.Scale)
+	}
+	if math.IsNaN(p.X) || math.IsInf(p.X, 0) || math.IsNaN(p.Y) || math.IsInf(p.Y, 0) {
+		return fmt.Errorf("invalid placement position: %v,%v", p.X, p.Y)
+	}
+	if math.IsNaN(p.Rotation) || math.IsInf(p.Rotation, 0) {
+		return fmt.Errorf("invalid placement rotation: %v", p.Rotation)
+	}
+	if p.Opacity != nil && (*p.Opacity < 0 || *p.Opacity > 1 || math.IsNaN(*p.Opacity)) {
+		return fmt.Errorf("invalid placement opacity: %v, expected 0 to 1", *p.Opacity)
+	}
+	return nil
+}
+
+// opacity returns the opacity, 1 when unset
+func (p SVGPlacement) opacity() float64 {
+	if p.Opacity == nil {
+		return 1
+	}
+	return *p.Opacity
+}
+
+// matrix maps box coordinates of a width×height box to the destination
+func (p SVGPlacement) matrix(width, height int) rasterx.Matrix2D {
+	scale := p.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	cx, cy := float64(width)/2, float64(height)/2
+	return rasterx.Identity.
+		Translate(p.X+cx, p.Y+cy).
+		Rotate(p.Rotation*math.Pi/180).
+		Scale(scale, scale).
+		Translate(-cx, -cy)
+}
+
+// viewBoxMatrix maps viewBox coordinates into the content rectangle of the
+// layout, unlike SvgIcon.SetTarget it honors a viewBox origin other than 0,0
+func viewBoxMatrix(vx, vy, vw, vh float64, layout svgLayout, content image.Rectangle) rasterx.Matrix2D {
+	x, y, w, h := layout.target(vw, vh, content.Dx(), content.Dy())
+	sx, sy := 1.0, 1.0
+	if vw > 0 && vh > 0 {
+		sx, sy = w/vw, h/vh
+	}
+	return rasterx.Identity.
+		Translate(float64(content.Min.X)+x, float64(content.Min.Y)+y).
+		Scale(sx, sy).
+		Translate(-vx, -vy)
+}
+
+// deviceBounds returns the pixel rectangle covering r transformed by m
+func deviceBounds(m rasterx.Matrix2D, r image.Rectangle) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range [4][2]float64{
+		{float64(r.Min.X), float64(r.Min.Y)},
+		{float64(r.Max.X), float64(r.Min.Y)},
+		{float64(r.Max.X), float64(r.Max.Y)},
+		{float64(r.Min.X), float64(r.Max.Y)},
+	} {
+		x, y := m.Transform(c[0], c[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
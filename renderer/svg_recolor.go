@@ -0,0 +1,563 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+)
+
+// SVGColorMap describes how the colors of an SVG are replaced before it is
+// rasterized. Unlike tinting the rendered image, it keeps the shading of
+// icons drawn with several colors.
+//
+// The fill and stroke overrides win over Colors; Colors also applies to
+// stop-color and color, so it recolors gradients and currentColor users.
+type SVGColorMap struct {
+	// Colors maps an exact color to its replacement. Keys are compared by
+	// value, so "#D9D9D9", "#d9d9d9" and "rgb(217,217,217)" are the same key
+	Colors map[string]string `json:"colors,omitempty"`
+	// Fill replaces every fill except none and url(...) references
+	Fill string `json:"fill,omitempty"`
+	// Stroke replaces every stroke except none and url(...) references
+	Stroke string `json:"stroke,omitempty"`
+	// CurrentColor is the value of currentColor. When empty, currentColor
+	// resolves to the inherited color property, or black without one
+	CurrentColor string `json:"current_color,omitempty"`
+	// Vars sets CSS custom properties such as "--primary", overriding the
+	// declarations found in the document
+	Vars map[string]string `json:"vars,omitempty"`
+}
+
+// SVGColorMapper can be implemented by an SVGRenderOption to recolor the
+// SVG before rendering. The mapping is part of the parsed-SVG cache key
+type SVGColorMapper interface {
+	// GetColorMap returns the color mapping, nil leaves colors unchanged
+	GetColorMap() *SVGColorMap
+}
+
+// IsEmpty reports whether the mapping changes nothing
+func (m *SVGColorMap) IsEmpty() bool {
+	return m == nil || (len(m.Colors) == 0 && m.Fill == "" && m.Stroke == "" &&
+		m.CurrentColor == "" && len(m.Vars) == 0)
+}
+
+// Validate checks that every color parses and every variable name starts with "--"
+func (m *SVGColorMap) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for from, to := range m.Colors {
+		if c, err := oksvg.ParseSVGColor(from); err != nil || c == nil {
+			return fmt.Errorf("invalid color map key %q", from)
+		}
+		if _, err := oksvg.ParseSVGColor(to); err != nil {
+			return fmt.Errorf("invalid replacement color %q for %q: %w", to, from, err)
+		}
+	}
+	for name, value := range map[string]string{"fill": m.Fill, "stroke": m.Stroke, "current color": m.CurrentColor} {
+		if value == "" {
+			continue
+		}
+		if _, err := oksvg.ParseSVGColor(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	for name, value := range m.Vars {
+		if !strings.HasPrefix(name, "--") || len(name) == 2 {
+			return fmt.Errorf("invalid CSS variable name %q, expected --name", name)
+		}
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("CSS variable %q has an empty value", name)
+		}
+	}
+	return nil
+}
+
+// CacheKey returns a canonical string of the mapping, equal mappings give
+// equal keys regardless of map iteration order
+func (m *SVGColorMap) CacheKey() string {
+	if m.IsEmpty() {
+		return ""
+	}
+	var sb strings.Builder
+	writeMap := func(name string, values map[string]string) {
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString(name)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "%q=%q,", strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(values[k]))
+		}
+		sb.WriteByte(';')
+	}
+	writeMap("colors:", m.Colors)
+	fmt.Fprintf(&sb, "fill:%q;stroke:%q;current:%q;", m.Fill, m.Stroke, m.CurrentColor)
+	writeMap("vars:", m.Vars)
+	return sb.String()
+}
+
+// needsRecolor reports whether data must pass through RecolorSVG before
+// oksvg can parse it: a mapping is set, or the document uses currentColor or
+// CSS variables, which oksvg does not understand
+func needsRecolor(data []byte, m *SVGColorMap) bool {
+	return !m.IsEmpty() || bytes.Contains(data, []byte("currentColor")) || bytes.Contains(data, []byte("var("))
+}
+
+// colorProperties are the properties whose values are colors
+var colorProperties = map[string]bool{"fill": true, "stroke": true, "stop-color": true, "color": true}
+
+// cssDeclRe matches one declaration inside a <style> rule
+var cssDeclRe = regexp.MustCompile(`([A-Za-z-][\w-]*)(\s*:\s*)([^;{}]*?)(\s*[;}])`)
+
+// emptyRuleRe matches a rule without declarations, which the rasterizer rejects
+var emptyRuleRe = regexp.MustCompile(`[^{};]*\{[\s;]*\}`)
+
+// maxVarDepth bounds nested and self-referencing var() lookups
+const maxVarDepth = 16
+
+// RecolorSVG applies the color mapping to an SVG document and returns the
+// rewritten document. It also resolves currentColor and var() references,
+// which the rasterizer does not support, so a nil mapping is valid.
+// Only start tags and <style> contents that change are rewritten, the rest
+// of the document is copied byte for byte
+func RecolorSVG(data []byte, m *SVGColorMap) ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	rc := &recolorer{m: m, colors: map[color.NRGBA]string{}, docVars: map[string]string{}}
+	if m != nil {
+		for from, to := range m.Colors {
+			c, _ := oksvg.ParseSVGColor(from)
+			rc.colors[toNRGBA(c)] = to
+		}
+	}
+	if err := rc.collectStyleVars(data); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	scopes := []recolorScope{{}}
+	inStyle, rootSeen := false, false
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, error parsing SVG", err)
+		}
+		raw := data[offset:dec.InputOffset()]
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := scopes[len(scopes)-1]
+			attrs, changed := rc.element(t.Attr, &scope)
+			if !rootSeen {
+				rootSeen = true
+				attrs, changed = rc.rootDefaults(attrs, changed)
+			}
+			scopes = append(scopes, scope)
+			inStyle = t.Name.Local == "style"
+			if !changed {
+				out.Write(raw)
+				continue
+			}
+			writeStartTag(&out, t.Name, attrs, bytes.HasSuffix(raw, []byte("/>")))
+		case xml.EndElement:
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+			inStyle = false
+			out.Write(raw)
+		case xml.CharData:
+			if !inStyle {
+				out.Write(raw)
+				continue
+			}
+			css := string(t)
+			if rewritten := rc.styleSheet(css); rewritten != css {
+				xml.EscapeText(&out, []byte(rewritten))
+				continue
+			}
+			out.Write(raw)
+		default:
+			out.Write(raw)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// recolorer holds the state of one RecolorSVG call
+type recolorer struct {
+	m       *SVGColorMap
+	colors  map[color.NRGBA]string // Colors keyed by parsed value
+	docVars map[string]string      // custom properties declared in <style> elements
+}
+
+// recolorScope is the inherited state of an element
+type recolorScope struct {
+	vars  map[string]string // custom properties declared on ancestors, shared until written
+	color string            // computed color property, empty when not set
+}
+
+// collectStyleVars records the custom properties declared in <style>
+// elements, they apply to the whole document
+func (rc *recolorer) collectStyleVars(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	inStyle := false
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w, error parsing SVG", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			inStyle = t.Name.Local == "style"
+		case xml.EndElement:
+			inStyle = false
+		case xml.CharData:
+			if !inStyle {
+				continue
+			}
+			for _, match := range cssDeclRe.FindAllStringSubmatch(string(t), -1) {
+				if strings.HasPrefix(match[1], "--") {
+					rc.docVars[match[1]] = strings.TrimSpace(match[3])
+				}
+			}
+		}
+	}
+}
+
+// element rewrites the presentation attributes and the style attribute of an
+// element, updating scope with the custom properties and color it declares
+func (rc *recolorer) element(attrs []xml.Attr, scope *recolorScope) ([]xml.Attr, bool) {
+	styleIdx := -1
+	var decls [][2]string
+	for i, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "style" {
+			styleIdx = i
+			decls = parseDeclarations(attr.Value)
+		}
+	}
+
+	// 自定义属性先于其他声明生效，与声明顺序无关
+	for _, d := range decls {
+		if !strings.HasPrefix(d[0], "--") {
+			continue
+		}
+		if value, ok := rc.resolveVars(d[1], scope, 0); ok {
+			vars := make(map[string]string, len(scope.vars)+1)
+			for k, v := range scope.vars {
+				vars[k] = v
+			}
+			vars[d[0]] = value
+			scope.vars = vars
+		}
+	}
+	// color 在 fill、stroke 之前计算，currentColor 取本元素的 color
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "color" {
+			if value, ok := rc.property("color", attr.Value, scope); ok {
+				scope.color = value
+			}
+		}
+	}
+	for _, d := range decls {
+		if d[0] == "color" {
+			if value, ok := rc.property("color", d[1], scope); ok {
+				scope.color = value
+			}
+		}
+	}
+
+	out := make([]xml.Attr, 0, len(attrs))
+	changed := false
+	for i, attr := range attrs {
+		if i == styleIdx {
+			style, ok := rc.declarations(decls, scope)
+			if style != attr.Value {
+				changed = true
+			}
+			if ok {
+				attr.Value = style
+				out = append(out, attr)
+			}
+			continue
+		}
+		if attr.Name.Space != "" || attr.Name.Local == "xmlns" {
+			out = append(out, attr)
+			continue
+		}
+		value, ok := rc.property(attr.Name.Local, attr.Value, scope)
+		if !ok || value != attr.Value {
+			changed = true
+		}
+		if ok {
+			attr.Value = value
+			out = append(out, attr)
+		}
+	}
+	return out, changed
+}
+
+// rootDefaults adds the fill override to the root element when it does not
+// set a fill, so elements relying on the initial black fill are recolored
+func (rc *recolorer) rootDefaults(attrs []xml.Attr, changed bool) ([]xml.Attr, bool) {
+	if rc.m == nil || rc.m.Fill == "" {
+		return attrs, changed
+	}
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "fill" {
+			return attrs, changed
+		}
+		if attr.Name.Space == "" && attr.Name.Local == "style" {
+			for _, d := range parseDeclarations(attr.Value) {
+				if d[0] == "fill" {
+					return attrs, changed
+				}
+			}
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: "fill"}, Value: rc.m.Fill}), true
+}
+
+// declarations rewrites the declarations of a style attribute, dropping
+// custom properties, which are already applied to the scope
+// ok is false when no declaration remains
+func (rc *recolorer) declarations(decls [][2]string, scope *recolorScope) (string, bool) {
+	var parts []string
+	for _, d := range decls {
+		if strings.HasPrefix(d[0], "--") {
+			continue
+		}
+		if value, ok := rc.property(d[0], d[1], scope); ok {
+			parts = append(parts, d[0]+":"+value)
+		}
+	}
+	return strings.Join(parts, ";"), len(parts) > 0
+}
+
+// styleSheet rewrites the declarations of a <style> element
+// Custom properties are kept, the rasterizer ignores unknown properties and
+// removing them could leave an empty rule it cannot parse
+func (rc *recolorer) styleSheet(css string) string {
+	dropped := false
+	css = cssDeclRe.ReplaceAllStringFunc(css, func(decl string) string {
+		match := cssDeclRe.FindStringSubmatch(decl)
+		name := strings.ToLower(match[1])
+		if strings.HasPrefix(name, "--") {
+			return decl
+		}
+		value, ok := rc.property(name, match[3], &recolorScope{})
+		if !ok {
+			// 无法解析的声明整体移除，保留结束符
+			dropped = true
+			return strings.TrimSpace(match[4])
+		}
+		return match[1] + match[2] + value + match[4]
+	})
+	if dropped {
+		css = emptyRuleRe.ReplaceAllString(css, "")
+	}
+	return css
+}
+
+// property returns the rewritten value of a property, ok is false when the
+// value references an undefined variable and the property must be dropped
+func (rc *recolorer) property(name, value string, scope *recolorScope) (string, bool) {
+	resolved, ok := rc.resolveVars(value, scope, 0)
+	if !ok {
+		return "", false
+	}
+	if colorProperties[strings.ToLower(name)] {
+		resolved = rc.paint(strings.ToLower(name), resolved, scope)
+	}
+	if resolved == value {
+		return value, true
+	}
+	return resolved, true
+}
+
+// paint applies the overrides, currentColor and the color map to a color value
+func (rc *recolorer) paint(name, value string, scope *recolorScope) string {
+	v := strings.TrimSpace(value)
+	lower := strings.ToLower(v)
+	if lower == "none" || lower == "inherit" || strings.HasPrefix(lower, "url(") {
+		return value
+	}
+
+	if rc.m != nil {
+		switch {
+		case name == "fill" && rc.m.Fill != "":
+			return rc.m.Fill
+		case name == "stroke" && rc.m.Stroke != "":
+			return rc.m.Stroke
+		}
+	}
+	if lower == "currentcolor" {
+		switch {
+		case name == "color":
+			// color: currentColor 等同于继承
+			if scope.color != "" {
+				return scope.color
+			}
+			return "black"
+		case rc.m != nil && rc.m.CurrentColor != "":
+			return rc.m.CurrentColor
+		case scope.color != "":
+			// scope.color 已经过颜色映射
+			return scope.color
+		default:
+			return "black"
+		}
+	}
+
+	c, err := oksvg.ParseSVGColor(v)
+	if err != nil || c == nil {
+		return value
+	}
+	if to, ok := rc.colors[toNRGBA(c)]; ok {
+		return to
+	}
+	return value
+}
+
+// resolveVars substitutes var(--name, fallback) references
+// Lookup order is SVGColorMap.Vars, declarations on ancestors, then <style>
+// declarations; ok is false when a variable is undefined and has no fallback
+func (rc *recolorer) resolveVars(value string, scope *recolorScope, depth int) (string, bool) {
+	if !strings.Contains(value, "var(") {
+		return value, true
+	}
+	if depth >= maxVarDepth {
+		return "", false
+	}
+
+	var sb strings.Builder
+	rest := value
+	for {
+		i := strings.Index(rest, "var(")
+		if i < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		sb.WriteString(rest[:i])
+		args, end, ok := matchParen(rest[i+len("var("):])
+		if !ok {
+			return "", false
+		}
+		rest = rest[i+len("var(")+end+1:]
+
+		name, fallback, hasFallback := strings.Cut(args, ",")
+		name = strings.TrimSpace(name)
+		v, found := rc.lookupVar(name, scope)
+		if !found {
+			if !hasFallback {
+				return "", false
+			}
+			v = strings.TrimSpace(fallback)
+		}
+		resolved, ok := rc.resolveVars(v, scope, depth+1)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(resolved)
+	}
+	return sb.String(), true
+}
+
+// lookupVar finds the value of a custom property
+func (rc *recolorer) lookupVar(name string, scope *recolorScope) (string, bool) {
+	if rc.m != nil {
+		if v, ok := rc.m.Vars[name]; ok {
+			return v, true
+		}
+	}
+	if v, ok := scope.vars[name]; ok {
+		return v, true
+	}
+	v, ok := rc.docVars[name]
+	return v, ok
+}
+
+// matchParen returns the text before the parenthesis closing an already
+// opened one and its index in s
+func matchParen(s string) (string, int, bool) {
+	depth := 1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[:i], i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// parseDeclarations splits a style attribute into name and value pairs
+func parseDeclarations(style string) [][2]string {
+	var decls [][2]string
+	for _, part := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(part, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, "--") {
+			name = strings.ToLower(name)
+		}
+		decls = append(decls, [2]string{name, strings.TrimSpace(value)})
+	}
+	return decls
+}
+
+// writeStartTag writes a start tag from raw token parts, prefixes are kept as written
+func writeStartTag(w *bytes.Buffer, name xml.Name, attrs []xml.Attr, selfClosing bool) {
+	w.WriteByte('<')
+	writeName(w, name)
+	for _, attr := range attrs {
+		w.WriteByte(' ')
+		writeName(w, attr.Name)
+		w.WriteString(`="`)
+		xml.EscapeText(w, []byte(attr.Value))
+		w.WriteByte('"')
+	}
+	if selfClosing {
+		w.WriteString("/>")
+		return
+	}
+	w.WriteByte('>')
+}
+
+// writeName writes a possibly prefixed XML name
+func writeName(w *bytes.Buffer, name xml.Name) {
+	if name.Space != "" {
+		w.WriteString(name.Space)
+		w.WriteByte(':')
+	}
+	w.WriteString(name.Local)
+}
+
+// toNRGBA converts a parsed color to the comparable NRGBA form
+func toNRGBA(c color.Color) color.NRGBA {
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
@@ -2,155 +2,381 @@ package renderer
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
-	"sync"
+	"image/color"
+	"image/draw"
+	"sync/atomic"
 
 	"github.com/bagaking/iconmarker/cache"
+	"github.com/bagaking/iconmarker/limits"
+	"github.com/bagaking/iconmarker/workpool"
 	"github.com/srwiley/oksvg"
 	"github.com/srwiley/rasterx"
+	"golang.org/x/image/math/fixed"
 )
 
-// SVGResource represents a cacheable SVG resource
-// 存储原始 SVG 数据而不是已解析的图标，这样我们可以避免并发问题
-type SVGResource struct {
-	svgData []byte // 存储原始SVG数据，而不是解析后的图标
-}
+// SVGResource is an alias of cache.SVGResource, kept for compatibility
+type SVGResource = cache.SVGResource
 
-// Size implements cache.CacheItem
-// 返回 SVG 数据的实际大小
-func (r *SVGResource) Size() int {
-	// 返回实际SVG数据的大小
-	return len(r.svgData)
+// SVGRenderer implements the Renderer interface for SVG rendering
+type SVGRenderer struct {
+	resourceManager *cache.ResourceManager
+	pool            atomic.Pointer[workpool.Pool] // swapped by SetPool while batches may run
+	limits          atomic.Pointer[limits.Limits] // swapped by SetLimits while renders may run
+	text            *TextRenderer                 // loads the fonts of <text> elements
+	fonts           atomic.Pointer[FontRegistry]  // swapped by SetFontRegistry while renders may run
 }
 
-// Clone implements cache.Resource
-// 深度复制 SVG 数据以避免并发修改问题
-func (r *SVGResource) Clone() cache.Resource {
-	if r.svgData == nil || len(r.svgData) == 0 {
-		return &SVGResource{}
+// NewSVGRenderer creates a new SVG renderer
+// Batch rendering runs on a pool of GOMAXPROCS workers, see SetPool
+func NewSVGRenderer(resourceManager *cache.ResourceManager) *SVGRenderer {
+	r := &SVGRenderer{
+		resourceManager: resourceManager,
+		text:            NewTextRenderer(resourceManager),
 	}
+	r.pool.Store(workpool.NewPool(0))
+	r.fonts.Store(NewFontRegistry())
+	return r
+}
 
-	// 深度复制数据
-	dataCopy := make([]byte, len(r.svgData))
-	copy(dataCopy, r.svgData)
+// Fonts returns the registry mapping the font-family of <text> elements to
+// fonts, families that are not registered use the embedded default font
+func (r *SVGRenderer) Fonts() *FontRegistry {
+	return r.fonts.Load()
+}
 
-	return &SVGResource{
-		svgData: dataCopy,
-	}
+// SetFontRegistry replaces the font registry, sharing a registry between
+// renderers keeps their font mappings in sync. It is safe to call while
+// renders run
+func (r *SVGRenderer) SetFontRegistry(fonts *FontRegistry) {
+	r.fonts.Store(fonts)
 }
 
-// SVGRenderer implements the Renderer interface for SVG rendering
-type SVGRenderer struct {
-	resourceManager *cache.ResourceManager
+// SetLimits sets the resource limits applied to every render
+// SVG size and element count are checked before parsing, the number of
+// paths right after parsing and the output dimensions before rasterizing
+// It is safe to call while renders run, each render uses the limits that
+// were set when it started
+func (r *SVGRenderer) SetLimits(l limits.Limits) {
+	r.limits.Store(&l)
 }
 
-// NewSVGRenderer creates a new SVG renderer
-func NewSVGRenderer(resourceManager *cache.ResourceManager) *SVGRenderer {
-	return &SVGRenderer{
-		resourceManager: resourceManager,
+// Limits returns the resource limits applied to every render
+func (r *SVGRenderer) Limits() limits.Limits {
+	if l := r.limits.Load(); l != nil {
+		return *l
 	}
+	return limits.Limits{}
+}
+
+// SetPool sets the worker pool used by RenderMultiple, sharing a pool
+// between renderers bounds their combined parallelism. Batches already
+// running finish on the pool they started with
+func (r *SVGRenderer) SetPool(pool *workpool.Pool) {
+	r.pool.Store(pool)
 }
 
 // Render renders an SVG to an image
 func (r *SVGRenderer) Render(options RenderOption) (image.Image, error) {
-	// Cast options to SVGRenderOption
-	svgOptions, ok := options.(SVGRenderOption)
-	if !ok {
-		return nil, fmt.Errorf("options is not SVGRenderOption")
+	return r.RenderCtx(context.Background(), options)
+}
+
+// RenderCtx renders an SVG to an image, honoring ctx
+// ctx is checked before parsing, before rasterizing and between paths,
+// cancellation returns ctx.Err() wrapped with the stage it stopped at
+func (r *SVGRenderer) RenderCtx(ctx context.Context, options RenderOption) (image.Image, error) {
+	svg, err := r.prepare(ctx, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate options
-	if err := svgOptions.ValidateOption(); err != nil {
+	// Create output image, the placement is the identity
+	img := image.NewRGBA(image.Rect(0, 0, svg.layout.width, svg.layout.height))
+	if err = r.drawSVG(ctx, img, svg, SVGPlacement{}); err != nil {
 		return nil, err
 	}
 
-	// Get dimensions
-	width, height := svgOptions.GetDimensions()
-	if width <= 0 || height <= 0 {
-		return nil, fmt.Errorf("invalid dimensions: width=%d, height=%d", width, height)
+	return img, nil
+}
+
+// RenderOnImage renders an SVG directly onto an existing image
+func (r *SVGRenderer) RenderOnImage(img draw.Image, options RenderOption) error {
+	return r.RenderOnImageCtx(context.Background(), img, options)
+}
+
+// RenderOnImageCtx renders an SVG directly onto an existing image, honoring ctx
+// The SVG is laid out as by Render and composited over img at the placement
+// given by an SVGPlacementOption, without an intermediate image when img
+// supports SubImage. Opacity is applied to each path, so overlapping shapes
+// of a translucent SVG show through each other
+func (r *SVGRenderer) RenderOnImageCtx(ctx context.Context, img draw.Image, options RenderOption) error {
+	var placement SVGPlacement
+	if po, ok := options.(SVGPlacementOption); ok {
+		placement = po.GetPlacement()
+		if err := placement.validate(); err != nil {
+			return err
+		}
 	}
 
-	// Parse and render SVG
-	svgData := svgOptions.GetSVGData()
-	img, err := r.renderSVG(svgData, width, height)
+	svg, err := r.prepare(ctx, options)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return img, nil
+	return r.drawSVG(ctx, img, svg, placement)
 }
 
 // RenderMultiple renders multiple SVGs in parallel
+// It returns the error of the first failed SVG by index, use
+// RenderMultipleCtx for per-item errors and cancellation
 func (r *SVGRenderer) RenderMultiple(options []SVGRenderOption) ([]image.Image, error) {
-	results := make([]image.Image, len(options))
-	errors := make([]error, len(options))
+	results := r.RenderMultipleCtx(context.Background(), options)
 
-	var wg sync.WaitGroup
-	for i, opt := range options {
-		wg.Add(1)
-		go func(idx int, opt SVGRenderOption) {
-			defer wg.Done()
-			img, err := r.Render(opt)
-			results[idx] = img
-			errors[idx] = err
-		}(i, opt)
+	// Check for errors
+	for i, res := range results {
+		if res.Err != nil {
+			return nil, fmt.Errorf("error rendering SVG %d: %w", i, res.Err)
+		}
 	}
 
-	wg.Wait()
+	return workpool.Values(results), nil
+}
+
+// RenderMultipleCtx renders multiple SVGs on the renderer's worker pool
+// Results are returned in input order with one error per item, items not
+// started before ctx is done fail with ctx.Err()
+func (r *SVGRenderer) RenderMultipleCtx(ctx context.Context, options []SVGRenderOption) []workpool.Result[image.Image] {
+	return workpool.Map(ctx, r.pool.Load(), options, func(ctx context.Context, _ int, opt SVGRenderOption) (image.Image, error) {
+		return r.RenderCtx(ctx, opt)
+	})
+}
 
-	// Check for errors
-	for i, err := range errors {
-		if err != nil {
-			return nil, fmt.Errorf("error rendering SVG %d: %w", i, err)
+// preparedSVG is a parsed SVG with its resolved layout
+type preparedSVG struct {
+	icon    *oksvg.SvgIcon
+	texts   []svgText // <text> elements in document order
+	layout  svgLayout
+	content image.Rectangle // content rectangle of the layout, inside the padding
+}
+
+// prepare validates the options, then loads the SVG and resolves its layout
+func (r *SVGRenderer) prepare(ctx context.Context, options RenderOption) (*preparedSVG, error) {
+	// Cast options to SVGRenderOption
+	svgOptions, ok := options.(SVGRenderOption)
+	if !ok {
+		return nil, fmt.Errorf("options is not SVGRenderOption")
+	}
+
+	// Validate options
+	if err := svgOptions.ValidateOption(); err != nil {
+		return nil, err
+	}
+
+	// Get dimensions, 0 is resolved from the viewBox after parsing
+	lim := r.Limits()
+	layout, err := layoutFromOptions(svgOptions)
+	if err != nil {
+		return nil, err
+	}
+	if layout.width > 0 && layout.height > 0 {
+		if err = lim.CheckOutputSize(layout.width, layout.height); err != nil {
+			return nil, err
+		}
+	}
+
+	var colors *SVGColorMap
+	if mapper, ok := options.(SVGColorMapper); ok {
+		colors = mapper.GetColorMap()
+		if err = colors.Validate(); err != nil {
+			return nil, err
 		}
 	}
 
-	return results, nil
+	// Parse SVG
+	icon, texts, data, err := r.loadIcon(ctx, lim, svgOptions.GetSVGData(), colors)
+	if err != nil {
+		return nil, err
+	}
+
+	vb := icon.ViewBox
+	content, err := layout.resolve(vb.W, vb.H)
+	if err != nil {
+		return nil, err
+	}
+	if err = lim.CheckOutputSize(layout.width, layout.height); err != nil {
+		return nil, err
+	}
+	if layout.aspect == "" {
+		layout.aspect = documentAspectRatio(data)
+	}
+
+	return &preparedSVG{icon: icon, texts: texts, layout: layout, content: content}, nil
 }
 
-// renderSVG renders an SVG to an RGBA image
+// loadIcon parses an SVG within lim, recoloring it with colors first
+// It returns the icon, its <text> elements and the data it was parsed from
 // 每次渲染时都重新解析 SVG 数据以避免并发问题
-func (r *SVGRenderer) renderSVG(svgData []byte, width, height int) (*image.RGBA, error) {
-	// Generate key for cache
+func (r *SVGRenderer) loadIcon(ctx context.Context, lim limits.Limits, svgData []byte, colors *SVGColorMap) (*oksvg.SvgIcon, []svgText, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("svg render canceled before parsing: %w", err)
+	}
+	if err := lim.CheckSVG(svgData); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Generate key for cache, the cached data is already recolored
 	key := r.resourceManager.GenerateKeyFromData(svgData)
+	if !colors.IsEmpty() {
+		key += ":" + r.resourceManager.GenerateKeyFromData([]byte(colors.CacheKey()))
+	}
 
 	// Try to get from cache
-	var svgResource *SVGResource
-	item, found := r.resourceManager.GetResource("svg", key, r.resourceManager.GetSVGCache())
-	if found {
-		if res, ok := item.(*SVGResource); ok {
-			svgResource = res
-		}
-	}
+	svgs := r.resourceManager.SVGs()
+	svgResource, found := svgs.Get(key)
 
 	// 确保我们有SVG数据
-	if svgResource == nil {
+	if !found {
+		data := svgData
+		if needsRecolor(svgData, colors) {
+			var err error
+			if data, err = RecolorSVG(svgData, colors); err != nil {
+				return nil, nil, nil, err
+			}
+		}
 		// 缓存SVG数据
-		svgResource = &SVGResource{
-			svgData: svgData,
+		svgResource = cache.NewSVGResource(data)
+		svgs.Put(key, svgResource)
+	}
+
+	// 光栅化器不支持 <text>，解析前替换为标记路径
+	data := svgResource.Data()
+	parseData, texts := data, []svgText(nil)
+	if bytes.Contains(data, []byte("<text")) {
+		var err error
+		if parseData, texts, err = extractSVGText(data); err != nil {
+			return nil, nil, nil, err
 		}
-		r.resourceManager.PutResource("svg", key, r.resourceManager.GetSVGCache(), svgResource)
 	}
 
 	// 每次都从数据创建新的SvgIcon，避免并发修改问题
-	svgIcon, err := oksvg.ReadIconStream(bytes.NewReader(svgResource.svgData))
+	svgIcon, err := oksvg.ReadIconStream(bytes.NewReader(parseData))
 	if err != nil {
-		return nil, fmt.Errorf("error parsing SVG: %w", err)
+		return nil, nil, nil, fmt.Errorf("error parsing SVG: %w", err)
+	}
+	if len(texts) > 0 {
+		if texts, err = bindTextMarkers(svgIcon, texts); err != nil {
+			return nil, nil, nil, err
+		}
 	}
+	if err = lim.CheckSVGPaths(len(svgIcon.SVGPaths)); err != nil {
+		return nil, nil, nil, err
+	}
+	return svgIcon, texts, data, nil
+}
 
-	// Set dimensions
-	svgIcon.SetTarget(0, 0, float64(width), float64(height))
+// drawSVG composites the background and the paths of a prepared SVG over dst
+// Only the pixels covered by the transformed box and the clip rectangle are
+// rasterized; slice overflow is clipped to the bounding box of the
+// transformed content area, which is exact unless the SVG is rotated
+func (r *SVGRenderer) drawSVG(ctx context.Context, dst draw.Image, svg *preparedSVG, placement SVGPlacement) error {
+	layout := svg.layout
+	place := placement.matrix(layout.width, layout.height)
+	limit := dst.Bounds()
+	if !placement.Clip.Empty() {
+		limit = limit.Intersect(placement.Clip)
+	}
+	opacity := placement.opacity()
 
-	// Create output image
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if layout.background != nil {
+		box := image.Rect(0, 0, layout.width, layout.height)
+		area := deviceBounds(place, box).Intersect(limit)
+		if !area.Empty() {
+			rasterizeInto(dst, area, func(target draw.Image) {
+				fillBox(target, box, place, area.Min, layout.background, opacity)
+			})
+		}
+	}
+
+	area := deviceBounds(place, svg.content).Intersect(limit)
+	if area.Empty() {
+		return nil
+	}
 
-	// Use high-quality rendering
-	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
-	raster := rasterx.NewDasher(width, height, scanner)
+	// 视图框先映射到布局中的内容区域，再按放置变换到目标图像，最后平移到光栅化区域
+	vb := svg.icon.ViewBox
+	svg.icon.Transform = rasterx.Identity.
+		Translate(-float64(area.Min.X), -float64(area.Min.Y)).
+		Mult(place).
+		Mult(viewBoxMatrix(vb.X, vb.Y, vb.W, vb.H, layout, svg.content))
 
-	// Draw SVG
-	svgIcon.Draw(raster, 1.0)
+	var err error
+	rasterizeInto(dst, area, func(target draw.Image) {
+		// Use high-quality rendering
+		scanner := rasterx.NewScannerGV(area.Dx(), area.Dy(), target, target.Bounds())
+		raster := rasterx.NewDasher(area.Dx(), area.Dy(), scanner)
 
-	return img, nil
+		// Draw SVG path by path so that long renders can be canceled
+		err = r.drawIconCtx(ctx, svg, raster, opacity)
+	})
+	return err
+}
+
+// rasterizeInto calls draw with an image covering area of dst, which is a
+// sub-image of dst when dst supports it and a scratch image composited over
+// dst afterwards otherwise. The origin of the rasterizer is area.Min
+func rasterizeInto(dst draw.Image, area image.Rectangle, drawFn func(target draw.Image)) {
+	if sub, ok := dst.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		if target, ok := sub.SubImage(area).(draw.Image); ok {
+			drawFn(target)
+			return
+		}
+	}
+
+	scratch := image.NewRGBA(image.Rectangle{Max: area.Size()})
+	drawFn(scratch)
+	draw.Draw(dst, area, scratch, image.Point{}, draw.Over)
+}
+
+// fillBox fills box transformed by m with c, relative to origin on the destination
+func fillBox(target draw.Image, box image.Rectangle, m rasterx.Matrix2D, origin image.Point, c color.Color, opacity float64) {
+	m = rasterx.Identity.Translate(-float64(origin.X), -float64(origin.Y)).Mult(m)
+	size := target.Bounds().Size()
+	scanner := rasterx.NewScannerGV(size.X, size.Y, target, target.Bounds())
+	filler := rasterx.NewFiller(size.X, size.Y, scanner)
+	filler.SetColor(rasterx.ApplyOpacity(c, opacity))
+	corner := func(x, y int) fixed.Point26_6 {
+		return rasterx.ToFixedP(m.Transform(float64(x), float64(y)))
+	}
+	filler.Start(corner(box.Min.X, box.Min.Y))
+	filler.Line(corner(box.Max.X, box.Min.Y))
+	filler.Line(corner(box.Max.X, box.Max.Y))
+	filler.Line(corner(box.Min.X, box.Max.Y))
+	filler.Stop(true)
+	filler.Draw()
+}
+
+// drawIconCtx draws all paths and texts of an icon in document order,
+// checking ctx between paths
+func (r *SVGRenderer) drawIconCtx(ctx context.Context, svg *preparedSVG, raster *rasterx.Dasher, opacity float64) error {
+	icon, texts := svg.icon, svg.texts
+	t := icon.Transform
+	for i := 0; i <= len(icon.SVGPaths); i++ {
+		for len(texts) > 0 && texts[0].at == i {
+			if err := r.drawText(&texts[0], t, raster, opacity); err != nil {
+				return err
+			}
+			texts = texts[1:]
+		}
+		if i == len(icon.SVGPaths) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("svg render canceled at path %d of %d: %w", i, len(icon.SVGPaths), err)
+		}
+		icon.SVGPaths[i].DrawTransformed(raster, opacity, t)
+	}
+	return nil
 }
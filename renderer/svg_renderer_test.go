@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"testing"
+
+	"github.com/bagaking/iconmarker/cache"
+	"github.com/bagaking/iconmarker/limits"
+)
+
+func TestSetLimitsDuringRender(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">` +
+		`<path d="M0 0H5V5Z"/><path d="M5 5H10V10Z"/></svg>`
+	r := NewSVGRenderer(cache.NewResourceManager(10, 10, 10))
+	tight := limits.Default()
+	tight.MaxSVGPaths = 1
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				r.SetLimits(tight)
+			} else {
+				r.SetLimits(limits.Default())
+			}
+		}
+	}()
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, err := r.Render(&SVGOptions{Data: []byte(doc), Width: 20, Height: 20})
+				if err != nil && !errors.Is(err, limits.ErrSVGTooComplex) {
+					t.Errorf("render failed with %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	r.SetLimits(tight)
+	if _, err := r.Render(&SVGOptions{Data: []byte(doc), Width: 20, Height: 20}); !errors.Is(err, limits.ErrSVGTooComplex) {
+		t.Errorf("render under tight limits returned %v", err)
+	}
+	if got := r.Limits(); got != tight {
+		t.Errorf("Limits() = %+v, want %+v", got, tight)
+	}
+}
+
+func TestStrokeWidthIsInOutputPixels(t *testing.T) {
+	// A 4 unit stroke in a 10 unit viewBox rendered at 100px stays 4px wide,
+	// the templates in assets rely on this
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">` +
+		`<path d="M0 5H10" stroke="#000" stroke-width="4"/></svg>`
+	r := NewSVGRenderer(cache.NewResourceManager(10, 10, 10))
+	img, err := r.Render(&SVGOptions{Data: []byte(doc), Width: 100, Height: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rgba := img.(*image.RGBA)
+	covered := 0
+	for y := 0; y < 100; y++ {
+		if rgba.RGBAAt(50, y).A > 128 {
+			covered++
+		}
+	}
+	if covered != 4 {
+		t.Errorf("stroke covers %d rows, want 4", covered)
+	}
+}
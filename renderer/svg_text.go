@@ -0,0 +1,557 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// The rasterizer ignores <text>, so text elements are taken out of the
+// document before parsing and each is replaced by a marker path. The marker
+// keeps the position of the text among the paths, so text is composited in
+// document order, and it inherits the transforms of its ancestors, so
+// drawing it through a recording scanner yields the transform of the text
+
+// textMarkerSize is the length of the marker edges in user units, long
+// edges keep the recovered transform precise in fixed point
+const textMarkerSize = 1000
+
+// textMarker replaces a <text> element, the %d pair is a vertex drawn at
+// random once per process, which tells markers apart from authored paths,
+// %s is the transform attribute of the text
+const textMarker = `<path d="M0 0L1000 0L0 1000L%d %dZ" style="fill:#000;stroke:none;opacity:1"%s/>`
+
+// markerNonceRange bounds the coordinates of the random marker vertex, so
+// it stays exact in fixed point
+const markerNonceRange = 1 << 20
+
+// markerNonce returns the random vertex of the markers
+var markerNonce = sync.OnceValue(func() [2]int {
+	return [2]int{rand.IntN(markerNonceRange), rand.IntN(markerNonceRange)}
+})
+
+// markerElement returns the marker replacing a text with the given
+// transform attribute, already escaped
+func markerElement(transform string) string {
+	nonce := markerNonce()
+	return fmt.Sprintf(textMarker, nonce[0], nonce[1], transform)
+}
+
+// markerPath returns the parsed geometry of a marker
+var markerPath = sync.OnceValues(func() (rasterx.Path, error) {
+	doc := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1 1">` + markerElement("") + `</svg>`
+	icon, err := oksvg.ReadIconStream(strings.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing text marker: %w", err)
+	}
+	if len(icon.SVGPaths) != 1 {
+		return nil, fmt.Errorf("text marker parsed into %d paths, want 1", len(icon.SVGPaths))
+	}
+	return icon.SVGPaths[0].Path, nil
+})
+
+// defaultFontSize is the initial font-size of SVG
+const defaultFontSize = 16
+
+// hiddenElements are containers whose text is never drawn directly
+var hiddenElements = map[string]bool{
+	"defs": true, "symbol": true, "clipPath": true, "mask": true, "pattern": true,
+	"marker": true, "title": true, "desc": true, "metadata": true, "style": true,
+}
+
+// svgText is a <text> element taken out of the document
+type svgText struct {
+	runs   []svgTextRun
+	at     int           // index of the first path drawn after the text
+	marker oksvg.SvgPath // marker path carrying the transform of the text
+}
+
+// svgTextRun is a span of characters sharing a style, in document order
+type svgTextRun struct {
+	text   string
+	x, y   *float64 // absolute position, nil continues after the previous run
+	dx, dy float64  // relative shift before the run
+	style  svgTextStyle
+}
+
+// svgTextStyle is the computed style of a text run
+type svgTextStyle struct {
+	fill        string
+	fillOpacity float64
+	opacity     float64 // product of the opacity of the element and its ancestors
+	fontSize    float64
+	fontFamily  string
+	anchor      string
+	baseline    string
+	hidden      bool
+}
+
+// textPosition holds position attributes until the next characters
+type textPosition struct {
+	x, y   *float64
+	dx, dy float64
+}
+
+// extractSVGText replaces the <text> elements of an SVG with marker paths
+// and returns the rewritten document with the extracted text in document
+// order. Text in containers that are not rendered is left in place
+func extractSVGText(data []byte) ([]byte, []svgText, error) {
+	var out bytes.Buffer
+	var texts []svgText
+	var text *svgText
+	var pending textPosition
+	var textStart int64
+	var textTransform string
+	copied := int64(0)
+	textDepth, hidden := 0, 0
+	styles := []svgTextStyle{{fill: "black", fillOpacity: 1, opacity: 1, fontSize: defaultFontSize}}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w, error parsing SVG", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			style := styles[len(styles)-1].inherit(t.Attr)
+			styles = append(styles, style)
+			if hidden > 0 || hiddenElements[t.Name.Local] || style.hidden {
+				hidden++
+				continue
+			}
+			if text != nil {
+				textDepth++
+				pending.read(t.Attr)
+				continue
+			}
+			if t.Name.Local == "text" {
+				text, textDepth, textStart = &svgText{}, 1, offset
+				pending = textPosition{}
+				pending.read(t.Attr)
+				textTransform = ""
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "transform" {
+						textTransform = attr.Value
+					}
+				}
+			}
+
+		case xml.EndElement:
+			if len(styles) > 1 {
+				styles = styles[:len(styles)-1]
+			}
+			if hidden > 0 {
+				hidden--
+				continue
+			}
+			if text == nil {
+				continue
+			}
+			if textDepth--; textDepth > 0 {
+				continue
+			}
+			// 没有文字的元素直接移除，每个标记都对应一个 svgText
+			out.Write(data[copied:textStart])
+			copied = dec.InputOffset()
+			text.runs = collapseWhitespace(text.runs)
+			if len(text.runs) > 0 {
+				transform := ""
+				if textTransform != "" {
+					var buf bytes.Buffer
+					_ = xml.EscapeText(&buf, []byte(textTransform))
+					transform = ` transform="` + buf.String() + `"`
+				}
+				out.WriteString(markerElement(transform))
+				texts = append(texts, *text)
+			}
+			text = nil
+
+		case xml.CharData:
+			if text == nil || hidden > 0 || len(t) == 0 {
+				continue
+			}
+			text.runs = append(text.runs, svgTextRun{
+				text:  string(t),
+				x:     pending.x,
+				y:     pending.y,
+				dx:    pending.dx,
+				dy:    pending.dy,
+				style: styles[len(styles)-1],
+			})
+			pending = textPosition{}
+		}
+	}
+	if len(texts) == 0 {
+		return data, nil, nil
+	}
+	out.Write(data[copied:])
+	return out.Bytes(), texts, nil
+}
+
+// inherit returns the style of a child element with the given attributes
+// Style declarations take precedence over presentation attributes
+func (s svgTextStyle) inherit(attrs []xml.Attr) svgTextStyle {
+	parentSize := s.fontSize
+	s.hidden = false
+	apply := func(name, value string) {
+		value = strings.TrimSpace(value)
+		switch name {
+		case "fill":
+			s.fill = value
+		case "fill-opacity":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				s.fillOpacity = clamp01(v)
+			}
+		case "opacity":
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				s.opacity *= clamp01(v)
+			}
+		case "font-size":
+			if v, ok := parseFontSize(value, parentSize); ok {
+				s.fontSize = v
+			}
+		case "font-family":
+			s.fontFamily = value
+		case "text-anchor":
+			s.anchor = value
+		case "dominant-baseline":
+			s.baseline = value
+		case "display":
+			s.hidden = value == "none"
+		}
+	}
+	var style string
+	for _, attr := range attrs {
+		if attr.Name.Local == "style" {
+			style = attr.Value
+			continue
+		}
+		apply(attr.Name.Local, attr.Value)
+	}
+	for _, decl := range parseDeclarations(style) {
+		apply(decl[0], decl[1])
+	}
+	return s
+}
+
+// read takes the first value of the position attributes of an element
+func (p *textPosition) read(attrs []xml.Attr) {
+	for _, attr := range attrs {
+		v, ok := firstLength(attr.Value)
+		if !ok {
+			continue
+		}
+		switch attr.Name.Local {
+		case "x":
+			p.x = &v
+		case "y":
+			p.y = &v
+		case "dx":
+			p.dx += v
+		case "dy":
+			p.dy += v
+		}
+	}
+}
+
+// firstLength parses the first number of a length list, px units are allowed
+func firstLength(s string) (float64, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "px"), 64)
+	return v, err == nil
+}
+
+// parseFontSize parses a font-size in user units, em and % are relative to parent
+func parseFontSize(s string, parent float64) (float64, bool) {
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "px"):
+		s = strings.TrimSuffix(s, "px")
+	case strings.HasSuffix(s, "pt"):
+		s, scale = strings.TrimSuffix(s, "pt"), 4.0/3
+	case strings.HasSuffix(s, "em"):
+		s, scale = strings.TrimSuffix(s, "em"), parent
+	case strings.HasSuffix(s, "%"):
+		s, scale = strings.TrimSuffix(s, "%"), parent/100
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || !(v > 0) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v * scale, true
+}
+
+// clamp01 clamps v to [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// collapseWhitespace applies the default xml:space handling across runs:
+// newlines are removed, tabs become spaces, consecutive spaces collapse and
+// leading and trailing spaces of the element are dropped
+func collapseWhitespace(runs []svgTextRun) []svgTextRun {
+	var out []svgTextRun
+	space := true // drops leading spaces
+	for _, run := range runs {
+		var sb strings.Builder
+		for _, r := range run.text {
+			switch r {
+			case '\n', '\r':
+				continue
+			case '\t', ' ':
+				if space {
+					continue
+				}
+				space = true
+				sb.WriteByte(' ')
+			default:
+				space = false
+				sb.WriteRune(r)
+			}
+		}
+		run.text = sb.String()
+		if run.text != "" || run.x != nil || run.y != nil {
+			out = append(out, run)
+		}
+	}
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i].text = strings.TrimRight(out[i].text, " ")
+		if out[i].text != "" {
+			break
+		}
+	}
+	return out
+}
+
+// bindTextMarkers removes the marker paths from a parsed icon and records
+// where each text is drawn. Texts whose marker was not parsed are dropped
+func bindTextMarkers(icon *oksvg.SvgIcon, texts []svgText) ([]svgText, error) {
+	marker, err := markerPath()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]oksvg.SvgPath, 0, len(icon.SVGPaths))
+	n := 0
+	for _, p := range icon.SVGPaths {
+		if n < len(texts) && slices.Equal(p.Path, marker) {
+			texts[n].at, texts[n].marker = len(kept), p
+			n++
+			continue
+		}
+		kept = append(kept, p)
+	}
+	icon.SVGPaths = kept
+	return texts[:n], nil
+}
+
+// pointRecorder is a rasterx.Scanner that records path points instead of drawing
+type pointRecorder struct {
+	points []fixed.Point26_6
+}
+
+func (p *pointRecorder) Start(a fixed.Point26_6)            { p.points = append(p.points, a) }
+func (p *pointRecorder) Line(b fixed.Point26_6)             { p.points = append(p.points, b) }
+func (p *pointRecorder) Draw()                              {}
+func (p *pointRecorder) GetPathExtent() fixed.Rectangle26_6 { return fixed.Rectangle26_6{} }
+func (p *pointRecorder) SetBounds(int, int)                 {}
+func (p *pointRecorder) SetColor(interface{})               {}
+func (p *pointRecorder) SetWinding(bool)                    {}
+func (p *pointRecorder) Clear()                             { p.points = p.points[:0] }
+func (p *pointRecorder) SetClip(image.Rectangle)            {}
+
+// matrix returns the transform from text user space to the output, t is the
+// transform of the icon; it falls back to t when the marker draws nothing
+func (s *svgText) matrix(t rasterx.Matrix2D) rasterx.Matrix2D {
+	rec := &pointRecorder{}
+	marker := s.marker
+	marker.DrawTransformed(rasterx.NewDasher(1, 1, rec), 1, t)
+	if len(rec.points) < 3 {
+		return t
+	}
+	f := func(v fixed.Int26_6) float64 { return float64(v) / 64 }
+	p0, p1, p2 := rec.points[0], rec.points[1], rec.points[2]
+	return rasterx.Matrix2D{
+		A: (f(p1.X) - f(p0.X)) / textMarkerSize,
+		B: (f(p1.Y) - f(p0.Y)) / textMarkerSize,
+		C: (f(p2.X) - f(p0.X)) / textMarkerSize,
+		D: (f(p2.Y) - f(p0.Y)) / textMarkerSize,
+		E: f(p0.X),
+		F: f(p0.Y),
+	}
+}
+
+// placedRun is a text run with its font and position resolved
+type placedRun struct {
+	run      *svgTextRun
+	font     *truetype.Font
+	face     font.Face
+	x, y     float64 // baseline origin in user units
+	advance  float64
+	newChunk bool
+}
+
+// drawText lays out a text element and fills its glyph outlines
+// Glyphs are loaded at the output size and mapped through the text
+// transform, so they stay sharp when scaled or rotated
+func (r *SVGRenderer) drawText(text *svgText, t rasterx.Matrix2D, raster *rasterx.Dasher, opacity float64) error {
+	m := text.matrix(t)
+	scale := math.Sqrt(math.Abs(m.A*m.D - m.B*m.C))
+	if scale == 0 {
+		return nil
+	}
+
+	// 先排版所有片段，锚点需要整段文本的宽度
+	fonts := r.Fonts()
+	placed := make([]placedRun, 0, len(text.runs))
+	var x, y float64
+	for i := range text.runs {
+		run := &text.runs[i]
+		f, err := r.text.LoadFont(fonts.lookup(run.style.fontFamily))
+		if err != nil {
+			return err
+		}
+		size := run.style.fontSize * scale
+		if limit := maxGlyphPixelSize(f); !(size <= limit) {
+			return fmt.Errorf("font-size %g renders at %gpx, above the %.0fpx limit of the font", run.style.fontSize, size, limit)
+		}
+		face := truetype.NewFace(f, &truetype.Options{Size: size, DPI: 72, Hinting: font.HintingNone})
+
+		p := placedRun{run: run, font: f, face: face, newChunk: i == 0 || run.x != nil}
+		if run.x != nil {
+			x = *run.x
+		}
+		if run.y != nil {
+			y = *run.y
+		}
+		x, y = x+run.dx, y+run.dy
+		p.x, p.y = x, y+baselineShift(face, run.style.baseline)/scale
+		p.advance = float64(font.MeasureString(face, run.text)) / 64 / scale
+		x += p.advance
+		placed = append(placed, p)
+	}
+	for start := 0; start < len(placed); {
+		end := start + 1
+		for end < len(placed) && !placed[end].newChunk {
+			end++
+		}
+		width := placed[end-1].x + placed[end-1].advance - placed[start].x
+		shift := 0.0
+		switch placed[start].run.style.anchor {
+		case "middle":
+			shift = -width / 2
+		case "end":
+			shift = -width
+		}
+		for i := start; i < end; i++ {
+			placed[i].x += shift
+		}
+		start = end
+	}
+
+	for _, p := range placed {
+		if p.run.text == "" {
+			continue
+		}
+		fill, err := oksvg.ParseSVGColor(p.run.style.fill)
+		if err != nil {
+			fill, err = oksvg.ParseSVGColor("black")
+		}
+		if err != nil || fill == nil {
+			continue
+		}
+
+		raster.Clear()
+		rf := &raster.Filler
+		rf.SetWinding(true)
+		if err = addGlyphs(rf, p, m, scale); err != nil {
+			return err
+		}
+		rf.SetColor(rasterx.ApplyOpacity(fill, p.run.style.fillOpacity*p.run.style.opacity*opacity))
+		rf.Draw()
+	}
+	return nil
+}
+
+// maxGlyphPixelSize returns the largest font size in pixels whose scaled
+// glyph coordinates fit in fixed.Int26_6, truetype scales them in 32 bits
+// and larger sizes silently wrap around into garbage outlines
+func maxGlyphPixelSize(f *truetype.Font) float64 {
+	upem := fixed.Int26_6(f.FUnitsPerEm())
+	b := f.Bounds(upem) // scaled to one unit per font unit
+	units := upem
+	for _, v := range [4]fixed.Int26_6{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y} {
+		units = max(units, v, -v)
+	}
+	return float64(math.MaxInt32-upem) / 64 / float64(units)
+}
+
+// baselineShift returns how far the alphabetic baseline lies below the
+// dominant-baseline position, in output pixels
+func baselineShift(face font.Face, baseline string) float64 {
+	metrics := face.Metrics()
+	ascent, descent := float64(metrics.Ascent)/64, float64(metrics.Descent)/64
+	switch baseline {
+	case "central":
+		return (ascent - descent) / 2
+	case "middle":
+		if bounds, _, ok := face.GlyphBounds('x'); ok && bounds.Min.Y < 0 {
+			return float64(-bounds.Min.Y) / 64 / 2
+		}
+		return (ascent - descent) / 2
+	case "hanging", "text-before-edge", "text-top":
+		return ascent
+	case "text-after-edge", "text-bottom", "ideographic":
+		return -descent
+	default:
+		return 0
+	}
+}
+
+// addGlyphs adds the outlines of a run to the filler, mapped through m
+func addGlyphs(rf *rasterx.Filler, p placedRun, m rasterx.Matrix2D, scale float64) error {
+	var buf truetype.GlyphBuf
+	size := fixed.Int26_6(0.5 + p.run.style.fontSize*scale*64)
+	dot := p.x
+	prev := rune(-1)
+	for _, c := range p.run.text {
+		if prev >= 0 {
+			dot += float64(p.face.Kern(prev, c)) / 64 / scale
+		}
+		if err := buf.Load(p.font, size, p.font.Index(c), font.HintingNone); err != nil {
+			return fmt.Errorf("%w, error loading glyph %q", err, c)
+		}
+
+		AddGlyphOutline(rf, &buf, func(q truetype.Point) fixed.Point26_6 {
+			// 字形坐标的 y 轴向上
+			return rasterx.ToFixedP(m.Transform(dot+float64(q.X)/64/scale, p.y-float64(q.Y)/64/scale))
+		})
+
+		advance, _ := p.face.GlyphAdvance(c)
+		dot += float64(advance) / 64 / scale
+		prev = c
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package renderer
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bagaking/iconmarker/cache"
+)
+
+// renderSVG renders an SVG document at 100x100
+func renderSVG(t *testing.T, doc string) (*image.RGBA, error) {
+	t.Helper()
+	r := NewSVGRenderer(cache.NewResourceManager(10, 10, 10))
+	img, err := r.Render(&SVGOptions{Data: []byte(doc), Width: 100, Height: 100})
+	if err != nil {
+		return nil, err
+	}
+	return img.(*image.RGBA), nil
+}
+
+func TestSVGTextFontSizeLimit(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">` +
+		`<text x="10" y="80" font-size="%s" fill="#f00">A</text></svg>`
+
+	for _, size := range []string{"1e6", "1000000px", "100000em"} {
+		if _, err := renderSVG(t, fmt.Sprintf(doc, size)); err == nil || !strings.Contains(err.Error(), "font-size") {
+			t.Errorf("font-size %s: got %v, want a font-size error", size, err)
+		}
+	}
+
+	img, err := renderSVG(t, fmt.Sprintf(doc, "60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] > 128 && img.Pix[i+3] > 128 {
+			red++
+		}
+	}
+	if red == 0 {
+		t.Fatal("text at font-size 60 drew nothing")
+	}
+}
+
+func TestSVGTextMarkerDoesNotMatchAuthoredPaths(t *testing.T) {
+	// The authored triangle has the geometry and style markers used to
+	// have, it must still be drawn while the text is drawn as text
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1000 1000">` +
+		`<path d="M0 0L1000 0L0 1000Z" style="fill:#000;stroke:none;opacity:1"/>` +
+		`<text x="900" y="900" font-size="200" text-anchor="end" fill="#f00">A</text></svg>`
+
+	img, err := renderSVG(t, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.RGBAAt(10, 10); c.A != 255 || c.R != 0 {
+		t.Errorf("authored path not drawn, pixel (10,10) = %v", c)
+	}
+	red := false
+	for y := 70; y < 90 && !red; y++ {
+		for x := 70; x < 90 && !red; x++ {
+			c := img.RGBAAt(x, y)
+			red = c.R > 128 && c.G < 64 && c.A > 128
+		}
+	}
+	if !red {
+		t.Error("text not drawn")
+	}
+}
+
+func TestSetFontRegistryDuringRender(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">` +
+		`<text x="10" y="80" font-size="60" font-family="Custom" fill="#f00">A</text></svg>`
+	r := NewSVGRenderer(cache.NewResourceManager(10, 10, 10))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			r.SetFontRegistry(NewFontRegistry())
+		}
+	}()
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, err := r.Render(&SVGOptions{Data: []byte(doc), Width: 50, Height: 50}); err != nil {
+					t.Errorf("render failed with %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	fonts := NewFontRegistry()
+	r.SetFontRegistry(fonts)
+	if r.Fonts() != fonts {
+		t.Errorf("Fonts() does not return the registry set last")
+	}
+}
@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
@@ -10,26 +11,13 @@ import (
 	"github.com/bagaking/iconmarker/cache"
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
+	"github.com/srwiley/rasterx"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
-// FontResource represents a cacheable font resource
-type FontResource struct {
-	font *truetype.Font
-}
-
-// Size implements cache.CacheItem
-func (r *FontResource) Size() int {
-	// Approximation of font size in memory
-	return 100 * 1024 // 100KB is a reasonable approximation
-}
-
-// Clone implements cache.Resource
-func (r *FontResource) Clone() cache.Resource {
-	// Fonts are immutable, so we can return the same instance
-	return r
-}
+// FontResource is an alias of cache.FontResource, kept for compatibility
+type FontResource = cache.FontResource
 
 // TextRenderer implements the Renderer interface for text rendering
 type TextRenderer struct {
@@ -45,6 +33,16 @@ func NewTextRenderer(resourceManager *cache.ResourceManager) *TextRenderer {
 
 // Render renders text on an image
 func (r *TextRenderer) Render(options RenderOption) (image.Image, error) {
+	return r.RenderCtx(context.Background(), options)
+}
+
+// RenderCtx renders text on an image, honoring ctx
+// ctx is checked before loading the font and between row bands while drawing
+func (r *TextRenderer) RenderCtx(ctx context.Context, options RenderOption) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("text render canceled before loading font: %w", err)
+	}
+
 	// Cast options to TextRenderOption
 	textOptions, ok := options.(TextRenderOption)
 	if !ok {
@@ -113,12 +111,37 @@ func (r *TextRenderer) Render(options RenderOption) (image.Image, error) {
 	y := (height+txtHeight)/2 - drawer.Face.Metrics().Descent.Round() + yOffset
 
 	// Draw text
-	drawer.Dot = fixed.P(x, y)
-	drawer.DrawString(textOptions.GetText())
+	if err = drawStringBands(ctx, drawer, img, textOptions.GetText(), fixed.P(x, y)); err != nil {
+		return nil, err
+	}
 
 	return img, nil
 }
 
+// textRowBand is the number of rows drawn between two ctx checks
+const textRowBand = 32
+
+// drawStringBands draws text at dot one row band of dst at a time, checking
+// ctx before each band. Only the bands covered by the text are drawn
+func drawStringBands(ctx context.Context, drawer *font.Drawer, dst *image.RGBA, text string, dot fixed.Point26_6) error {
+	drawer.Dot = dot
+	textBounds, _ := drawer.BoundString(text)
+	area := dst.Bounds().Intersect(image.Rect(
+		textBounds.Min.X.Floor(), textBounds.Min.Y.Floor(),
+		textBounds.Max.X.Ceil(), textBounds.Max.Y.Ceil(),
+	))
+
+	for y := area.Min.Y; y < area.Max.Y; y += textRowBand {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("text render canceled at row %d of %d: %w", y-area.Min.Y, area.Dy(), err)
+		}
+		drawer.Dst = dst.SubImage(image.Rect(area.Min.X, y, area.Max.X, min(y+textRowBand, area.Max.Y))).(*image.RGBA)
+		drawer.Dot = dot
+		drawer.DrawString(text)
+	}
+	return nil
+}
+
 // RenderOnImage renders text on an existing image
 func (r *TextRenderer) RenderOnImage(img draw.Image, options RenderOption) error {
 	// Cast options to TextRenderOption
@@ -203,17 +226,28 @@ func (r *TextRenderer) RenderOnImage(img draw.Image, options RenderOption) error
 	return nil
 }
 
+// LoadFont loads a font through the font cache
+// Empty fontData loads the embedded default font
+func (r *TextRenderer) LoadFont(fontData []byte) (*truetype.Font, error) {
+	if len(fontData) == 0 {
+		var err error
+		fontData, err = assets.GetDefaultFont()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default font: %w", err)
+		}
+	}
+	return r.getFont(fontData)
+}
+
 // getFont loads a font from cache or parses it
 func (r *TextRenderer) getFont(fontData []byte) (*truetype.Font, error) {
 	// Generate key for font cache
 	key := r.resourceManager.GenerateKeyFromData(fontData)
 
 	// Try to get from cache
-	item, found := r.resourceManager.GetResource("font", key, r.resourceManager.GetFontCache())
-	if found {
-		if fontResource, ok := item.(*FontResource); ok {
-			return fontResource.font, nil
-		}
+	fonts := r.resourceManager.Fonts()
+	if fontResource, found := fonts.Get(key); found {
+		return fontResource.Font(), nil
 	}
 
 	// Parse font
@@ -223,7 +257,7 @@ func (r *TextRenderer) getFont(fontData []byte) (*truetype.Font, error) {
 	}
 
 	// Cache font
-	r.resourceManager.PutResource("font", key, r.resourceManager.GetFontCache(), &FontResource{font: font})
+	fonts.Put(key, cache.NewFontResource(font))
 
 	return font, nil
 }
@@ -285,3 +319,57 @@ func (r *TextRenderer) adaptFontSize(f *truetype.Font, text string, maxW, maxH i
 
 	return fontSize
 }
+
+// AddGlyphOutline adds the closed contours of a loaded glyph to dst, mapping
+// every point through pt. It is shared by everything that turns glyphs into
+// paths, e.g. rasterizing SVG <text> or writing text layers as SVG paths
+//
+// Glyph outlines are quadratic, two adjacent off-curve points imply an
+// on-curve point halfway between them
+func AddGlyphOutline(dst rasterx.Adder, buf *truetype.GlyphBuf, pt func(truetype.Point) fixed.Point26_6) {
+	e0 := 0
+	for _, e1 := range buf.Ends {
+		addContour(dst, buf.Points[e0:e1], pt)
+		e0 = e1
+	}
+}
+
+// addContour adds one closed TrueType contour
+func addContour(dst rasterx.Adder, ps []truetype.Point, pt func(truetype.Point) fixed.Point26_6) {
+	if len(ps) == 0 {
+		return
+	}
+	mid := func(a, b fixed.Point26_6) fixed.Point26_6 {
+		return fixed.Point26_6{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	}
+	onCurve := func(q truetype.Point) bool { return q.Flags&0x01 != 0 }
+
+	start, others := pt(ps[0]), ps[1:]
+	if !onCurve(ps[0]) {
+		last := ps[len(ps)-1]
+		if onCurve(last) {
+			start, others = pt(last), ps[:len(ps)-1]
+		} else {
+			start, others = mid(start, pt(last)), ps
+		}
+	}
+
+	dst.Start(start)
+	q0, on0 := start, true
+	for _, q := range others {
+		cur, on := pt(q), onCurve(q)
+		switch {
+		case on && on0:
+			dst.Line(cur)
+		case on:
+			dst.QuadBezier(q0, cur)
+		case !on0:
+			dst.QuadBezier(q0, mid(q0, cur))
+		}
+		q0, on0 = cur, on
+	}
+	if !on0 {
+		dst.QuadBezier(q0, start)
+	}
+	dst.Stop(true)
+}
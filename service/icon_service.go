@@ -0,0 +1,273 @@
+// Package service provides the high level icon service described in the PRD,
+// wiring the SVG renderer, text drawing and filters together
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/core"
+	"github.com/bagaking/iconmarker/filter"
+	"github.com/bagaking/iconmarker/renderer"
+	"github.com/bagaking/iconmarker/workpool"
+)
+
+// 服务错误
+var (
+	ErrEmptyName        = errors.New("group name is empty")
+	ErrUnknownGroupType = errors.New("unknown group type")
+	ErrInvalidSize      = errors.New("icon size must be positive")
+)
+
+// IconRequest 描述批量创建中的一个群组图标
+type IconRequest struct {
+	Name      string `json:"name"`
+	GroupType string `json:"group_type"`
+	Size      int    `json:"size"`
+}
+
+// IconService 整合渲染器与滤镜，按群组名称和类型生成图标
+type IconService struct {
+	marker     *core.IconMarker
+	groupTypes map[string]groupType
+	mu         sync.RWMutex
+}
+
+// NewIconService 创建图标服务，marker 为 nil 时创建新的 IconMarker
+func NewIconService(marker *core.IconMarker) *IconService {
+	if marker == nil {
+		marker = core.NewIconMarker()
+	}
+	return &IconService{
+		marker:     marker,
+		groupTypes: defaultGroupTypes(),
+	}
+}
+
+// RegisterGroupType 注册或覆盖一个群组类型
+func (s *IconService) RegisterGroupType(name string, icon assets.IconType, preset StylePreset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groupTypes[name] = groupType{icon: icon, preset: preset}
+}
+
+// GroupTypes 返回所有已注册的群组类型名称
+func (s *IconService) GroupTypes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.groupTypes))
+	for name := range s.groupTypes {
+		names = append(names, name)
+	}
+	return names
+}
+
+// CreateGroupIcon 创建群组图标
+// groupType 可以是已注册的群组类型，也可以直接是内嵌图标名称（使用默认样式）
+func (s *IconService) CreateGroupIcon(name, groupType string, size int) (image.Image, error) {
+	return s.CreateGroupIconCtx(context.Background(), name, groupType, size)
+}
+
+// CreateGroupIconCtx 创建群组图标，支持通过 ctx 取消
+// 在渲染图标、着色和绘制每个标签图层之前检查 ctx，
+// 取消时返回包装了阶段信息的 context.Canceled 或 context.DeadlineExceeded
+func (s *IconService) CreateGroupIconCtx(ctx context.Context, name, groupType string, size int) (image.Image, error) {
+	if err := checkCtx(ctx, "start"); err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSize, size)
+	}
+	if err := s.marker.Limits().CheckOutputSize(size, size); err != nil {
+		return nil, err
+	}
+
+	label := DeriveLabel(name)
+	if label == "" {
+		return nil, ErrEmptyName
+	}
+
+	gt, err := s.resolveGroupType(groupType)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(ctx, label, gt, size)
+}
+
+// BatchCreateIcons 并行创建多个群组图标
+// 返回的图像与请求顺序一致，失败项为 nil；存在失败项时返回 *workpool.BatchError
+func (s *IconService) BatchCreateIcons(requests []IconRequest) ([]image.Image, error) {
+	results := s.BatchCreateIconsCtx(context.Background(), requests)
+	return workpool.Values(results), workpool.Errors(results)
+}
+
+// BatchCreateIconsCtx 并行创建多个群组图标，每个请求单独返回结果和错误
+// ctx 结束后尚未开始的请求返回 ctx.Err()，正在渲染的请求在下一个阶段之间停止
+func (s *IconService) BatchCreateIconsCtx(ctx context.Context, requests []IconRequest) []workpool.Result[image.Image] {
+	return workpool.Map(ctx, s.marker.GetPool(), requests, func(ctx context.Context, i int, req IconRequest) (image.Image, error) {
+		if err := checkCtx(ctx, "request %d", i); err != nil {
+			return nil, err
+		}
+		return s.CreateGroupIconCtx(ctx, req.Name, req.GroupType, req.Size)
+	})
+}
+
+// resolveGroupType 查找群组类型，未注册时尝试作为内嵌图标名称解析
+func (s *IconService) resolveGroupType(name string) (groupType, error) {
+	s.mu.RLock()
+	gt, ok := s.groupTypes[name]
+	s.mu.RUnlock()
+	if ok {
+		return gt, nil
+	}
+
+	icon, err := assets.ParseIconType(name)
+	if err != nil {
+		return groupType{}, fmt.Errorf("%w: %q", ErrUnknownGroupType, name)
+	}
+	return groupType{icon: icon, preset: DefaultPreset}, nil
+}
+
+// checkCtx 检查 ctx，已结束时返回包装了阶段信息的 ctx.Err()
+func checkCtx(ctx context.Context, stage string, args ...any) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+	if len(args) > 0 {
+		stage = fmt.Sprintf(stage, args...)
+	}
+	return fmt.Errorf("group icon canceled at %s: %w", stage, err)
+}
+
+// render 绘制背景、图标和标签
+func (s *IconService) render(ctx context.Context, label string, gt groupType, size int) (*image.RGBA, error) {
+	preset := gt.preset
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(preset.Background), image.Point{}, draw.Src)
+
+	// 图标位于画布上半部分
+	iconSize := int(float64(size) * preset.IconScale)
+	if iconSize > 0 {
+		svgData, err := gt.icon.Load()
+		if err != nil {
+			return nil, fmt.Errorf("error loading icon %s: %w", gt.icon, err)
+		}
+
+		iconImg, err := s.marker.GetSVGRenderer().RenderCtx(ctx, &renderer.SVGOptions{Data: svgData, Width: iconSize, Height: iconSize, Colors: preset.IconColors})
+		if err != nil {
+			return nil, fmt.Errorf("error rendering icon %s: %w", gt.icon, err)
+		}
+
+		if preset.TintIntensity > 0 {
+			tint := filter.TintOption{Color: preset.IconTint, Intensity: preset.TintIntensity}
+			iconImg, err = s.marker.GetFilterManager().ApplyFiltersCtx(ctx, iconImg, []string{"tint"}, []filter.FilterOption{tint})
+			if err != nil {
+				return nil, fmt.Errorf("error tinting icon: %w", err)
+			}
+		}
+
+		x := (size - iconSize) / 2
+		y := size / 10
+		draw.Draw(canvas, image.Rect(x, y, x+iconSize, y+iconSize), iconImg, image.Point{}, draw.Over)
+	}
+
+	// 标签位于画布下半部分
+	if err := checkCtx(ctx, "loading label font"); err != nil {
+		return nil, err
+	}
+	font, err := s.marker.GetTextRenderer().LoadFont(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	labelHeight := int(float64(size) * preset.LabelScale)
+	opt := core.DrawTextOption{
+		FontColor: preset.TextColor,
+		Text:      label,
+	}.SetAdaptedSize(size*4/5, labelHeight).MoveOffset(0, size*3/10)
+	if preset.OutlineWidth > 0 {
+		width := preset.OutlineWidth * size / 128
+		if width < 1 {
+			width = 1
+		}
+		opt = opt.AddOutline(preset.OutlineColor, width)
+	}
+
+	for j, eop := range opt.ToEffectGroup() {
+		if err = checkCtx(ctx, "label effect layer %d", j); err != nil {
+			return nil, err
+		}
+		if err = core.DrawCenteredFont(font, canvas, eop); err != nil {
+			return nil, fmt.Errorf("%w, error drawing label", err)
+		}
+	}
+	if err = checkCtx(ctx, "label"); err != nil {
+		return nil, err
+	}
+	if err = core.DrawCenteredFont(font, canvas, opt); err != nil {
+		return nil, fmt.Errorf("%w, error drawing label", err)
+	}
+
+	return canvas, nil
+}
+
+// DeriveLabel 从群组名称生成图标标签
+// 以中日韩文字开头的名称取前两个字，其他名称取前两个单词的首字母，
+// 单个单词取前两个字母
+func DeriveLabel(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+
+	runes := []rune(name)
+	if core.IsCJK(runes[0]) {
+		n := 0
+		var b strings.Builder
+		for _, r := range runes {
+			if unicode.IsSpace(r) || unicode.IsPunct(r) {
+				continue
+			}
+			b.WriteRune(r)
+			if n++; n == 2 {
+				break
+			}
+		}
+		return b.String()
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_'
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) >= 2 {
+		return strings.ToUpper(string([]rune{firstRune(words[0]), firstRune(words[1])}))
+	}
+
+	word := []rune(words[0])
+	if len(word) > 2 {
+		word = word[:2]
+	}
+	word[0] = unicode.ToUpper(word[0])
+	return string(word)
+}
+
+// firstRune 返回字符串的第一个字符
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"image/color"
+
+	"github.com/bagaking/iconmarker/assets"
+	"github.com/bagaking/iconmarker/renderer"
+)
+
+// StylePreset 描述群组图标的样式
+type StylePreset struct {
+	Background    color.RGBA            // 背景色
+	IconTint      [3]uint8              // 图标着色
+	TintIntensity float64               // 着色强度，0 表示保留图标原色
+	IconColors    *renderer.SVGColorMap // 栅格化前替换图标颜色，保留明暗层次，通常与 TintIntensity 0 搭配
+	TextColor     color.RGBA            // 标签文字颜色
+	OutlineColor  color.RGBA            // 标签描边颜色
+	OutlineWidth  int                   // 标签描边宽度（相对 128px 图标），0 表示不描边
+	IconScale     float64               // 图标边长占画布边长的比例
+	LabelScale    float64               // 标签高度占画布边长的比例
+}
+
+// DefaultPreset 是未注册样式的群组类型使用的样式
+var DefaultPreset = StylePreset{
+	Background:    color.RGBA{R: 100, G: 116, B: 139, A: 255},
+	IconTint:      [3]uint8{255, 255, 255},
+	TintIntensity: 0.8,
+	TextColor:     color.RGBA{R: 255, G: 255, B: 255, A: 255},
+	OutlineColor:  color.RGBA{R: 0, G: 0, B: 0, A: 96},
+	OutlineWidth:  2,
+	IconScale:     0.5,
+	LabelScale:    0.24,
+}
+
+// groupType 描述一个预定义的群组类型
+type groupType struct {
+	icon   assets.IconType
+	preset StylePreset
+}
+
+// presetWithBackground 基于默认样式替换背景色
+func presetWithBackground(r, g, b uint8) StylePreset {
+	p := DefaultPreset
+	p.Background = color.RGBA{R: r, G: g, B: b, A: 255}
+	return p
+}
+
+// 预定义群组类型
+const (
+	GroupProject    = "project"    // 项目组
+	GroupDiscussion = "discussion" // 讨论组
+	GroupInterest   = "interest"   // 兴趣小组
+	GroupTeam       = "team"       // 团队
+	GroupNotice     = "notice"     // 通知群
+	GroupTech       = "tech"       // 技术交流
+	GroupEvent      = "event"      // 活动
+	GroupLocal      = "local"      // 同城
+	GroupGoal       = "goal"       // 目标管理
+	GroupInnovation = "innovation" // 创新孵化
+)
+
+// defaultGroupTypes 将预定义群组类型映射到内嵌图标和样式
+func defaultGroupTypes() map[string]groupType {
+	return map[string]groupType{
+		GroupProject:    {assets.IconTodo, presetWithBackground(37, 99, 235)},
+		GroupDiscussion: {assets.IconPaperPlane, presetWithBackground(14, 165, 233)},
+		GroupInterest:   {assets.IconHeart, presetWithBackground(219, 39, 119)},
+		GroupTeam:       {assets.IconTeam, presetWithBackground(5, 150, 105)},
+		GroupNotice:     {assets.IconAlert, presetWithBackground(234, 88, 12)},
+		GroupTech:       {assets.IconRobot, presetWithBackground(79, 70, 229)},
+		GroupEvent:      {assets.IconAlarmClock, presetWithBackground(202, 138, 4)},
+		GroupLocal:      {assets.IconLocationPin, presetWithBackground(220, 38, 38)},
+		GroupGoal:       {assets.IconTarget, presetWithBackground(13, 148, 136)},
+		GroupInnovation: {assets.IconLightning, presetWithBackground(124, 58, 237)},
+	}
+}
@@ -0,0 +1,160 @@
+// Package workpool provides a bounded worker pool for parallel rendering
+package workpool
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Pool bounds the number of tasks running at the same time
+//
+// A Pool can be shared by several batches, the limit then applies to all of
+// them together. Submitting blocks while all slots are busy, which gives
+// callers natural backpressure.
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool creates a pool running at most size tasks concurrently
+// If size <= 0, runtime.GOMAXPROCS(0) is used
+func NewPool(size int) *Pool {
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+	return &Pool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// Size returns the concurrency limit of the pool
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+// Go runs task in a new goroutine once a slot is free
+// It blocks until a slot is acquired or ctx is done, in which case the
+// task is not run and ctx.Err() is returned
+func (p *Pool) Go(ctx context.Context, task func(ctx context.Context)) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	go func() {
+		defer func() { <-p.slots }()
+		task(ctx)
+	}()
+	return nil
+}
+
+// Result holds the outcome of one task of a batch
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Map runs fn for every input on the pool and returns the results in input
+// order. Inputs that were not started because ctx was done get ctx.Err()
+// as their error. A nil pool uses a new pool of GOMAXPROCS workers
+func Map[In, Out any](ctx context.Context, p *Pool, inputs []In,
+	fn func(ctx context.Context, index int, input In) (Out, error)) []Result[Out] {
+
+	if p == nil {
+		p = NewPool(0)
+	}
+	results := make([]Result[Out], len(inputs))
+
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		wg.Add(1)
+		idx, in := i, input
+		err := p.Go(ctx, func(ctx context.Context) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				results[idx].Err = err
+				return
+			}
+			results[idx].Value, results[idx].Err = fn(ctx, idx, in)
+		})
+		if err != nil {
+			wg.Done()
+			results[i].Err = err
+		}
+	}
+	wg.Wait()
+
+	return results
+}
+
+// TaskError is the error of a single task in a batch
+type TaskError struct {
+	Index int
+	Err   error
+}
+
+// Error implements error
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError collects the errors of all failed tasks in a batch, ordered
+// by task index
+type BatchError struct {
+	Errors []*TaskError
+}
+
+// Error implements error
+func (e *BatchError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%d tasks failed, first: %v", len(e.Errors), e.Errors[0])
+}
+
+// Unwrap returns the task errors, so errors.Is and errors.As look into
+// every failed task
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, te := range e.Errors {
+		errs[i] = te
+	}
+	return errs
+}
+
+// Errors returns a *BatchError describing every failed result, or nil if
+// all tasks succeeded
+func Errors[T any](results []Result[T]) error {
+	var batchErr BatchError
+	for i, r := range results {
+		if r.Err != nil {
+			batchErr.Errors = append(batchErr.Errors, &TaskError{Index: i, Err: r.Err})
+		}
+	}
+	if len(batchErr.Errors) == 0 {
+		return nil
+	}
+	return &batchErr
+}
+
+// Values returns the values of all results, failed results yield the zero
+// value of T
+func Values[T any](results []Result[T]) []T {
+	values := make([]T, len(results))
+	for i, r := range results {
+		values[i] = r.Value
+	}
+	return values
+}
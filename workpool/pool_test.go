@@ -0,0 +1,238 @@
+package workpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// track counts a running task and records the peak, call the returned
+// function when the task ends
+func track(running, peak *atomic.Int64) func() {
+	n := running.Add(1)
+	for {
+		old := peak.Load()
+		if n <= old || peak.CompareAndSwap(old, n) {
+			break
+		}
+	}
+	return func() { running.Add(-1) }
+}
+
+func TestMapKeepsInputOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		poolSize int
+		inputs   int
+	}{
+		{"empty", 2, 0},
+		{"single worker", 1, 20},
+		{"fewer inputs than workers", 8, 3},
+		{"more inputs than workers", 3, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := make([]int, tt.inputs)
+			for i := range inputs {
+				inputs[i] = i
+			}
+
+			// Later inputs finish first, so completion order is reversed
+			results := Map(context.Background(), NewPool(tt.poolSize), inputs,
+				func(_ context.Context, index, input int) (string, error) {
+					time.Sleep(time.Duration(tt.inputs-index) * 100 * time.Microsecond)
+					if input%7 == 3 {
+						return "", fmt.Errorf("input %d", input)
+					}
+					return fmt.Sprint(input * input), nil
+				})
+
+			if len(results) != tt.inputs {
+				t.Fatalf("got %d results, want %d", len(results), tt.inputs)
+			}
+			for i, r := range results {
+				if i%7 == 3 {
+					if r.Err == nil || r.Err.Error() != fmt.Sprintf("input %d", i) {
+						t.Errorf("result %d error = %v", i, r.Err)
+					}
+					continue
+				}
+				if r.Err != nil || r.Value != fmt.Sprint(i*i) {
+					t.Errorf("result %d = %q, %v, want %q", i, r.Value, r.Err, fmt.Sprint(i*i))
+				}
+			}
+		})
+	}
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	tests := []struct {
+		name     string
+		poolSize int
+		pools    int // batches sharing the pool
+	}{
+		{"one batch", 2, 1},
+		{"shared by batches", 3, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool(tt.poolSize)
+			var running, peak atomic.Int64
+			task := func(context.Context, int, int) (int, error) {
+				defer track(&running, &peak)()
+				time.Sleep(time.Millisecond)
+				return 0, nil
+			}
+
+			var wg sync.WaitGroup
+			for b := 0; b < tt.pools; b++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					Map(context.Background(), p, make([]int, 20), task)
+				}()
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got > int64(tt.poolSize) {
+				t.Errorf("%d tasks ran at once, pool size %d", got, tt.poolSize)
+			}
+		})
+	}
+}
+
+func TestPoolGoBlocksWhileFull(t *testing.T) {
+	p := NewPool(1)
+	release := make(chan struct{})
+	if err := p.Go(context.Background(), func(context.Context) { <-release }); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := p.Go(ctx, func(context.Context) { ran = true })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Go on a full pool returned %v, want DeadlineExceeded", err)
+	}
+	if ran {
+		t.Errorf("task ran although no slot was free")
+	}
+
+	close(release)
+	done := make(chan struct{})
+	if err = p.Go(context.Background(), func(context.Context) { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestMapCancellation(t *testing.T) {
+	tests := []struct {
+		name     string
+		cancelAt int // cancel when this input starts, -1 cancels before Map
+	}{
+		{"canceled before start", -1},
+		{"canceled mid batch", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAt < 0 {
+				cancel()
+			}
+
+			var started atomic.Int64
+			results := Map(ctx, NewPool(1), make([]int, 10), func(ctx context.Context, index, _ int) (int, error) {
+				started.Add(1)
+				if index == tt.cancelAt {
+					cancel()
+				}
+				return index, nil
+			})
+
+			if tt.cancelAt < 0 && started.Load() != 0 {
+				t.Errorf("%d tasks ran on a canceled context", started.Load())
+			}
+			if int(started.Load()) > tt.cancelAt+2 {
+				t.Errorf("%d tasks ran after cancel at input %d", started.Load(), tt.cancelAt)
+			}
+
+			err := Errors(results)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Errors() = %v, want context.Canceled", err)
+			}
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("Errors() = %T, want *BatchError", err)
+			}
+			if last := batchErr.Errors[len(batchErr.Errors)-1]; last.Index != 9 {
+				t.Errorf("last failed task = %d, want 9", last.Index)
+			}
+			for i, r := range results[:tt.cancelAt+1] {
+				if r.Err != nil || r.Value != i {
+					t.Errorf("result %d before cancel = %d, %v", i, r.Value, r.Err)
+				}
+			}
+		})
+	}
+}
+
+// TestPoolSwap mirrors how IconMarker and SVGRenderer replace their pool
+// while batches run: a batch keeps the pool it loaded, new batches use the
+// new one
+func TestPoolSwap(t *testing.T) {
+	var current atomic.Pointer[Pool]
+	current.Store(NewPool(1))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 20; i++ {
+				current.Store(NewPool(i % 4))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				p := current.Load()
+				var running, peak atomic.Int64
+				Map(context.Background(), p, make([]int, 8), func(context.Context, int, int) (int, error) {
+					defer track(&running, &peak)()
+					return 0, nil
+				})
+				if peak.Load() > int64(p.Size()) {
+					t.Errorf("batch ran %d tasks at once on a pool of %d", peak.Load(), p.Size())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestErrorsAndValues(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	results := []Result[int]{{Value: 1}, {Err: sentinel}, {Value: 3}}
+
+	if got := Values(results); fmt.Sprint(got) != "[1 0 3]" {
+		t.Errorf("Values() = %v", got)
+	}
+	err := Errors(results)
+	if !errors.Is(err, sentinel) {
+		t.Errorf("Errors() = %v, want it to wrap the task error", err)
+	}
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 1 {
+		t.Errorf("Errors() task error = %+v, want index 1", taskErr)
+	}
+	if Errors(results[:1]) != nil {
+		t.Errorf("Errors() of successful results is not nil")
+	}
+}